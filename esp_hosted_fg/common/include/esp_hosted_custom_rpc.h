@@ -8,6 +8,9 @@
 /* This file contains the custom RPC message IDs shared between the ESP Firmware and the Host side Protobuf Application.
 
  * These IDs are just demonstration purpose. You can customize them as per your application requirements.
+ *
+ * ESP handles requests in its Rx thread, so handlers must not block. Requests needing
+ * slow work are acked with empty response and answered later with an event.
  */
 
 
@@ -15,6 +18,53 @@ enum custom_rpc_req_id {
 	CUSTOM_RPC_REQ_ID__ONLY_ACK                          = 1,
 	CUSTOM_RPC_REQ_ID__ECHO_BACK_RESPONSE                = 2,
 	CUSTOM_RPC_REQ_ID__ECHO_BACK_AS_EVENT                = 3,
+	/* Response carries custom_rpc_softap_sta_list_t */
+	CUSTOM_RPC_REQ_ID__SOFTAP_GET_STA_DETAILS            = 4,
+	/* Request carries the 6 byte MAC of station to deauthenticate */
+	CUSTOM_RPC_REQ_ID__SOFTAP_KICK_STA                   = 5,
+	/* Request carries 1 byte iface, response carries custom_rpc_wifi_protocol_t */
+	CUSTOM_RPC_REQ_ID__GET_WIFI_PROTOCOL                 = 6,
+	/* Request carries custom_rpc_wifi_protocol_t */
+	CUSTOM_RPC_REQ_ID__SET_WIFI_PROTOCOL                 = 7,
+	/* Request carries 1 byte iface, response carries custom_rpc_wifi_bandwidth_t */
+	CUSTOM_RPC_REQ_ID__GET_WIFI_BANDWIDTH                = 8,
+	/* Request carries custom_rpc_wifi_bandwidth_t */
+	CUSTOM_RPC_REQ_ID__SET_WIFI_BANDWIDTH                = 9,
+	/* Request carries custom_rpc_vendor_ie_monitor_t */
+	CUSTOM_RPC_REQ_ID__VENDOR_IE_MONITOR                 = 10,
+	/* Request carries custom_rpc_probe_req_monitor_t */
+	CUSTOM_RPC_REQ_ID__PROBE_REQ_MONITOR                 = 11,
+	/* Request carries 1 byte iface, response carries custom_rpc_pmf_config_t */
+	CUSTOM_RPC_REQ_ID__GET_PMF_CONFIG                    = 12,
+	/* Request carries custom_rpc_pmf_config_t */
+	CUSTOM_RPC_REQ_ID__SET_PMF_CONFIG                    = 13,
+	/* Response carries custom_rpc_partition_table_t */
+	CUSTOM_RPC_REQ_ID__GET_PARTITION_TABLE               = 14,
+	/* Request carries custom_rpc_flash_read_t, empty response acks it.
+	 * Data follows in CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA */
+	CUSTOM_RPC_REQ_ID__READ_FLASH                        = 15,
+	/* Request carries custom_rpc_log_level_t */
+	CUSTOM_RPC_REQ_ID__SET_LOG_LEVEL                     = 16,
+	/* Request carries custom_rpc_adc_read_t, empty response acks it.
+	 * Reading follows in CUSTOM_RPC_EVENT_ID__ADC_READING */
+	CUSTOM_RPC_REQ_ID__READ_ADC                          = 17,
+	/* Empty response acks request, reading follows in CUSTOM_RPC_EVENT_ID__CHIP_TEMP */
+	CUSTOM_RPC_REQ_ID__READ_CHIP_TEMP                    = 18,
+	/* Request carries custom_rpc_traffic_filter_t */
+	CUSTOM_RPC_REQ_ID__SET_TRAFFIC_FILTER                = 19,
+	/* Response carries custom_rpc_traffic_filter_t */
+	CUSTOM_RPC_REQ_ID__GET_TRAFFIC_FILTER                = 20,
+	/* Response carries custom_rpc_event_log_t, kept in ESP flash across reboots */
+	CUSTOM_RPC_REQ_ID__GET_EVENT_LOG                     = 21,
+	CUSTOM_RPC_REQ_ID__CLEAR_EVENT_LOG                   = 22,
+	/* Request carries custom_rpc_scan_config_t */
+	CUSTOM_RPC_REQ_ID__SET_SCAN_CONFIG                   = 23,
+	/* Response carries custom_rpc_scan_config_t */
+	CUSTOM_RPC_REQ_ID__GET_SCAN_CONFIG                   = 24,
+	/* Request carries custom_rpc_softap_acl_t */
+	CUSTOM_RPC_REQ_ID__SET_SOFTAP_ACL                    = 25,
+	/* Response carries custom_rpc_softap_acl_t */
+	CUSTOM_RPC_REQ_ID__GET_SOFTAP_ACL                    = 26,
 	/* Add more request IDs as needed */
 };
 
@@ -22,7 +72,307 @@ enum custom_rpc_event_id {
 	/* Slave use this event to send back data received in CUSTOM_RPC_REQ_ID__ECHO_BACK_AS_EVENT */
 	CUSTOM_RPC_EVENT_ID__DEMO_ECHO_BACK_REQUEST          = 100,
 	CUSTOM_RPC_EVENT_ID__DEMO_SIMPLE_EVENT               = 101,
+	/* Event carries custom_rpc_vendor_ie_event_t */
+	CUSTOM_RPC_EVENT_ID__VENDOR_IE_RECEIVED              = 102,
+	/* Event carries custom_rpc_probe_req_report_t */
+	CUSTOM_RPC_EVENT_ID__PROBE_REQ_REPORT                = 103,
+	/* Event carries custom_rpc_flash_read_data_t */
+	CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA                 = 104,
+	/* Event carries custom_rpc_adc_reading_t */
+	CUSTOM_RPC_EVENT_ID__ADC_READING                     = 105,
+	/* Event carries custom_rpc_chip_temp_t */
+	CUSTOM_RPC_EVENT_ID__CHIP_TEMP                       = 106,
 	/* Add more event IDs as needed */
 };
 
+#define CUSTOM_RPC_MAC_LEN                                   6
+#define CUSTOM_RPC_SOFTAP_MAX_STA                            16
+
+/* Same values as wifi_interface_t in ESP-IDF */
+#define CUSTOM_RPC_WIFI_IF_STA                               0
+#define CUSTOM_RPC_WIFI_IF_AP                                1
+
+/* Same values as WIFI_PROTOCOL_* bitmap in ESP-IDF */
+#define CUSTOM_RPC_WIFI_PROTOCOL_11B                         0x1
+#define CUSTOM_RPC_WIFI_PROTOCOL_11G                         0x2
+#define CUSTOM_RPC_WIFI_PROTOCOL_11N                         0x4
+/* Espressif proprietary long range mode, works only between ESP devices */
+#define CUSTOM_RPC_WIFI_PROTOCOL_LR                          0x8
+
+/* Same values as wifi_bandwidth_t in ESP-IDF */
+#define CUSTOM_RPC_WIFI_BW_HT20                              1
+#define CUSTOM_RPC_WIFI_BW_HT40                              2
+
+#define CUSTOM_RPC_VENDOR_OUI_LEN                            3
+
+/* Probe request senders are reported by truncated salted SHA-256 of MAC,
+ * salt is regenerated on every enable */
+#define CUSTOM_RPC_PROBE_REQ_HASH_LEN                        8
+#define CUSTOM_RPC_PROBE_REQ_MAX_ENTRIES                     32
+
+/* Same values as wifi_vendor_ie_type_t in ESP-IDF */
+#define CUSTOM_RPC_VND_IE_TYPE_BEACON                        0
+#define CUSTOM_RPC_VND_IE_TYPE_PROBE_REQ                     1
+#define CUSTOM_RPC_VND_IE_TYPE_PROBE_RESP                    2
+#define CUSTOM_RPC_VND_IE_TYPE_ASSOC_REQ                     3
+#define CUSTOM_RPC_VND_IE_TYPE_ASSOC_RESP                    4
+
+/* Same as partition label size in ESP-IDF, including NUL */
+#define CUSTOM_RPC_PARTITION_LABEL_LEN                       17
+#define CUSTOM_RPC_MAX_PARTITIONS                            32
+/* Max bytes returned by one CUSTOM_RPC_REQ_ID__READ_FLASH */
+#define CUSTOM_RPC_FLASH_READ_MAX_LEN                        2048
+
+/* Same values as esp_log_level_t in ESP-IDF */
+#define CUSTOM_RPC_LOG_NONE                                  0
+#define CUSTOM_RPC_LOG_ERROR                                 1
+#define CUSTOM_RPC_LOG_WARN                                  2
+#define CUSTOM_RPC_LOG_INFO                                  3
+#define CUSTOM_RPC_LOG_DEBUG                                 4
+#define CUSTOM_RPC_LOG_VERBOSE                               5
+
+/* Log tag length, including NUL */
+#define CUSTOM_RPC_LOG_TAG_LEN                               32
+
+/* Same values as adc_atten_t in ESP-IDF */
+#define CUSTOM_RPC_ADC_ATTEN_DB_0                            0
+#define CUSTOM_RPC_ADC_ATTEN_DB_2_5                          1
+#define CUSTOM_RPC_ADC_ATTEN_DB_6                            2
+#define CUSTOM_RPC_ADC_ATTEN_DB_12                           3
+/* Set in custom_rpc_adc_reading_t when ESP has no ADC calibration data */
+#define CUSTOM_RPC_ADC_MV_UNKNOWN                            0xFFFF
+
+/* Verdict of traffic filter rule for frame received by ESP station */
+#define CUSTOM_RPC_FILTER_ACTION_FORWARD                     0
+#define CUSTOM_RPC_FILTER_ACTION_DROP                        1
+/* Forward, and wake host if sleeping */
+#define CUSTOM_RPC_FILTER_ACTION_WAKE                        2
+
+/* IP protocol numbers, 0 matches any frame, including non IP */
+#define CUSTOM_RPC_FILTER_PROTO_ANY                          0
+#define CUSTOM_RPC_FILTER_PROTO_ICMP                         1
+#define CUSTOM_RPC_FILTER_PROTO_TCP                          6
+#define CUSTOM_RPC_FILTER_PROTO_UDP                          17
+#define CUSTOM_RPC_FILTER_PROTO_ICMPV6                       58
+
+#define CUSTOM_RPC_FILTER_MAX_RULES                          16
+
+/* Firmware goes back to home channel between scanned channels for
+ * home_dwell_ms. Without it, home_dwell_ms is ignored */
+#define CUSTOM_RPC_SCAN_CAP_HOME_DWELL                       0x1
+#define CUSTOM_RPC_SCAN_DWELL_MIN_MS                         10
+#define CUSTOM_RPC_SCAN_DWELL_MAX_MS                         120
+#define CUSTOM_RPC_SCAN_HOME_DWELL_MIN_MS                    30
+#define CUSTOM_RPC_SCAN_HOME_DWELL_MAX_MS                    150
+
+/* Which stations may join SoftAP */
+#define CUSTOM_RPC_SOFTAP_ACL_OFF                            0
+/* Only listed MACs */
+#define CUSTOM_RPC_SOFTAP_ACL_ALLOW                          1
+/* All but listed MACs */
+#define CUSTOM_RPC_SOFTAP_ACL_DENY                           2
+#define CUSTOM_RPC_SOFTAP_ACL_MAX_MACS                       16
+
+#define CUSTOM_RPC_EVENT_LOG_MAX_ENTRIES                     32
+
+/* Event log entry types */
+/* ESP boot, reason is esp_reset_reason_t of ESP-IDF, e.g. 9 for brownout */
+#define CUSTOM_RPC_EVENT_LOG_RESET                           0
+/* Connect attempt failed, reason is wifi_err_reason_t of ESP-IDF */
+#define CUSTOM_RPC_EVENT_LOG_ASSOC_FAIL                      1
+/* Connected station lost AP, reason is wifi_err_reason_t of ESP-IDF */
+#define CUSTOM_RPC_EVENT_LOG_DISCONNECT                      2
+
+/* Payload structures below are packed and little endian on the wire */
+
+typedef struct __attribute__((packed)) {
+	uint8_t mac[CUSTOM_RPC_MAC_LEN];
+	int8_t rssi;
+	uint32_t connected_secs;
+	uint64_t rx_bytes;
+	uint64_t tx_bytes;
+} custom_rpc_softap_sta_info_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t num;
+	custom_rpc_softap_sta_info_t sta[];
+} custom_rpc_softap_sta_list_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t iface;
+	uint8_t protocol;
+} custom_rpc_wifi_protocol_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t iface;
+	uint8_t bandwidth;
+} custom_rpc_wifi_bandwidth_t;
+
+/* Protected Management Frames setting, applied on next connect (station)
+ * or start (softAP). required implies capable */
+typedef struct __attribute__((packed)) {
+	uint8_t iface;
+	uint8_t capable;
+	uint8_t required;
+} custom_rpc_pmf_config_t;
+
+/* Report vendor IEs matching oui seen in received management frames */
+typedef struct __attribute__((packed)) {
+	uint8_t enable;
+	uint8_t oui[CUSTOM_RPC_VENDOR_OUI_LEN];
+} custom_rpc_vendor_ie_monitor_t;
+
+/* ie holds the complete element, starting at element ID 0xDD */
+typedef struct __attribute__((packed)) {
+	uint8_t type;
+	uint8_t sa[CUSTOM_RPC_MAC_LEN];
+	int8_t rssi;
+	uint8_t ie_len;
+	uint8_t ie[];
+} custom_rpc_vendor_ie_event_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t enable;
+	/* Seconds between reports, 0 selects default */
+	uint8_t report_interval_sec;
+} custom_rpc_probe_req_monitor_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t mac_hash[CUSTOM_RPC_PROBE_REQ_HASH_LEN];
+	/* Strongest RSSI seen in this report interval */
+	int8_t rssi;
+	uint8_t channel;
+	uint16_t count;
+	/* Milliseconds since last probe request, relative to report time */
+	uint32_t age_ms;
+} custom_rpc_probe_req_entry_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t num;
+	custom_rpc_probe_req_entry_t entry[];
+} custom_rpc_probe_req_report_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t type;
+	uint8_t subtype;
+	uint32_t address;
+	uint32_t size;
+	uint8_t encrypted;
+	char label[CUSTOM_RPC_PARTITION_LABEL_LEN];
+} custom_rpc_partition_entry_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t num;
+	custom_rpc_partition_entry_t entry[];
+} custom_rpc_partition_table_t;
+
+/* With label set, offset is relative to start of that partition.
+ * With empty label, offset is absolute flash address, e.g. 0x8000 for
+ * partition table itself. Data of encrypted partitions is returned as is */
+typedef struct __attribute__((packed)) {
+	char label[CUSTOM_RPC_PARTITION_LABEL_LEN];
+	uint32_t offset;
+	uint32_t len;
+} custom_rpc_flash_read_t;
+
+/* Result of one CUSTOM_RPC_REQ_ID__READ_FLASH, offset as in request */
+typedef struct __attribute__((packed)) {
+	uint32_t offset;
+	int32_t status;    /* esp_err_t of flash read */
+	uint32_t len;      /* Bytes in data, 0 if read failed */
+	uint8_t data[];
+} custom_rpc_flash_read_data_t;
+
+/* Empty tag or "*" sets level of all tags */
+typedef struct __attribute__((packed)) {
+	uint8_t level;
+	char tag[CUSTOM_RPC_LOG_TAG_LEN];
+} custom_rpc_log_level_t;
+
+/* Only ADC1 is used, as ADC2 is shared with Wi-Fi */
+typedef struct __attribute__((packed)) {
+	uint8_t channel;
+	uint8_t atten;
+} custom_rpc_adc_read_t;
+
+typedef struct __attribute__((packed)) {
+	int32_t status;    /* esp_err_t of read */
+	uint8_t channel;
+	uint16_t raw;
+	/* Calibrated voltage, or CUSTOM_RPC_ADC_MV_UNKNOWN */
+	uint16_t mv;
+} custom_rpc_adc_reading_t;
+
+typedef struct __attribute__((packed)) {
+	int32_t status;    /* esp_err_t of read */
+	/* Internal sensor temperature in 0.1 degree Celsius */
+	int16_t deci_celsius;
+} custom_rpc_chip_temp_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t action;
+	uint8_t proto;
+	/* 1: only multicast/broadcast destination, 0: any destination */
+	uint8_t multicast;
+	/* TCP/UDP destination port, 0 for any */
+	uint16_t port;
+} custom_rpc_filter_rule_t;
+
+/* Rules are checked in order, first match decides. Frames matching no
+ * rule get default_action. No rules with default forward disables filter */
+typedef struct __attribute__((packed)) {
+	uint8_t default_action;
+	/* Frames dropped since filter was set, ignored in set request */
+	uint32_t dropped;
+	uint8_t num;
+	custom_rpc_filter_rule_t rule[];
+} custom_rpc_traffic_filter_t;
+
+typedef struct __attribute__((packed)) {
+	uint8_t type;
+	uint16_t reason;
+	/* Same type and reason in a row, within one boot, share an entry */
+	uint16_t count;
+	/* Boot of last occurrence, counted as in custom_rpc_event_log_t */
+	uint32_t boot;
+	/* Seconds since that boot */
+	uint32_t uptime_sec;
+	/* ESP wall clock, 0 if ESP clock was not set */
+	uint32_t unix_time;
+} custom_rpc_event_log_entry_t;
+
+typedef struct __attribute__((packed)) {
+	/* Boots since log was created or cleared, current one included */
+	uint32_t boot_count;
+	uint8_t num;
+	/* Oldest first */
+	custom_rpc_event_log_entry_t entry[];
+} custom_rpc_event_log_t;
+
+/* Scan while station is connected. With background set, scans are done
+ * with short dwell per channel, so data path of station only pauses
+ * briefly per channel, instead of for whole scan */
+typedef struct __attribute__((packed)) {
+	uint8_t background;
+	/* Max dwell per channel in background scan, 0 for default */
+	uint16_t dwell_ms;
+	/* Time on home channel between scanned channels, 0 for default */
+	uint8_t home_dwell_ms;
+	/* CUSTOM_RPC_SCAN_CAP_* of firmware, ignored in set request */
+	uint8_t caps;
+} custom_rpc_scan_config_t;
+
+/* SoftAP access control, enforced by ESP. Stations not permitted are
+ * deauthenticated on join, and their frames are not passed to host.
+ * With isolate set, frames from a station to another station of SoftAP
+ * are dropped, so stations only reach host */
+typedef struct __attribute__((packed)) {
+	uint8_t mode;
+	uint8_t isolate;
+	/* Joins refused and frames dropped since set, ignored in set request */
+	uint32_t rejected;
+	uint8_t num;
+	uint8_t mac[][CUSTOM_RPC_MAC_LEN];
+} custom_rpc_softap_acl_t;
+
 #endif /* __ESP_HOSTED_RPC_H__ */
@@ -0,0 +1,260 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include "freertos/FreeRTOS.h"
+#include "freertos/queue.h"
+#include "freertos/task.h"
+#include "esp_log.h"
+#include "esp_idf_version.h"
+#include "soc/soc_caps.h"
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 0, 0)
+#include "esp_adc/adc_oneshot.h"
+#include "esp_adc/adc_cali.h"
+#include "esp_adc/adc_cali_scheme.h"
+#if SOC_TEMP_SENSOR_SUPPORTED
+#include "driver/temperature_sensor.h"
+#endif
+#endif
+#include "adc_sensor.h"
+#include "esp_hosted_coprocessor.h"
+#include "esp_hosted_custom_rpc.h"
+
+/* Readings averaged per request, to smooth ADC noise */
+#define ADC_SAMPLES                  8
+#define SENSOR_QUEUE_LEN             4
+
+static const char *TAG = "adc_sensor";
+
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 0, 0)
+typedef struct {
+	uint32_t req_id;
+	uint8_t channel;
+	uint8_t atten;
+} sensor_job_t;
+
+static QueueHandle_t sensor_queue;
+static adc_oneshot_unit_handle_t adc1_handle;
+/* Created once per attenuation, NULL where ESP has no calibration data */
+static adc_cali_handle_t adc1_cali[CUSTOM_RPC_ADC_ATTEN_DB_12 + 1];
+#if SOC_TEMP_SENSOR_SUPPORTED
+static temperature_sensor_handle_t temp_handle;
+#endif
+
+static void adc_cali_create(adc_atten_t atten, adc_cali_handle_t *handle)
+{
+	esp_err_t ret = ESP_ERR_NOT_SUPPORTED;
+
+#if ADC_CALI_SCHEME_CURVE_FITTING_SUPPORTED
+	adc_cali_curve_fitting_config_t curve_cfg = {
+		.unit_id = ADC_UNIT_1,
+		.atten = atten,
+		.bitwidth = ADC_BITWIDTH_DEFAULT,
+	};
+	ret = adc_cali_create_scheme_curve_fitting(&curve_cfg, handle);
+#elif ADC_CALI_SCHEME_LINE_FITTING_SUPPORTED
+	adc_cali_line_fitting_config_t line_cfg = {
+		.unit_id = ADC_UNIT_1,
+		.atten = atten,
+		.bitwidth = ADC_BITWIDTH_DEFAULT,
+	};
+	ret = adc_cali_create_scheme_line_fitting(&line_cfg, handle);
+#endif
+
+	if (ret) {
+		ESP_LOGW(TAG, "No ADC calibration for atten %d (%d), only raw value reported", atten, ret);
+		*handle = NULL;
+	}
+}
+
+static void read_adc(const sensor_job_t *job, custom_rpc_adc_reading_t *reading)
+{
+	adc_oneshot_chan_cfg_t chan_cfg = { .bitwidth = ADC_BITWIDTH_DEFAULT };
+	int raw = 0, sum = 0, mv = 0;
+	esp_err_t ret = ESP_OK;
+
+	reading->channel = job->channel;
+	reading->mv = CUSTOM_RPC_ADC_MV_UNKNOWN;
+
+	chan_cfg.atten = job->atten;
+	ret = adc_oneshot_config_channel(adc1_handle, job->channel, &chan_cfg);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to config ADC1 channel %u: %d", job->channel, ret);
+		reading->status = ret;
+		return;
+	}
+
+	for (int i = 0; i < ADC_SAMPLES; i++) {
+		ret = adc_oneshot_read(adc1_handle, job->channel, &raw);
+		if (ret) {
+			ESP_LOGE(TAG, "ADC1 channel %u read failed: %d", job->channel, ret);
+			reading->status = ret;
+			return;
+		}
+		sum += raw;
+	}
+
+	reading->raw = sum / ADC_SAMPLES;
+	if (adc1_cali[job->atten] &&
+	    adc_cali_raw_to_voltage(adc1_cali[job->atten], reading->raw, &mv) == ESP_OK)
+		reading->mv = mv;
+}
+
+static void read_chip_temp(custom_rpc_chip_temp_t *temp)
+{
+#if SOC_TEMP_SENSOR_SUPPORTED
+	float celsius = 0;
+	esp_err_t ret = ESP_OK;
+
+	ret = temperature_sensor_enable(temp_handle);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to enable temperature sensor: %d", ret);
+		temp->status = ret;
+		return;
+	}
+	ret = temperature_sensor_get_celsius(temp_handle, &celsius);
+	temperature_sensor_disable(temp_handle);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to read temperature sensor: %d", ret);
+		temp->status = ret;
+		return;
+	}
+
+	temp->deci_celsius = (int16_t)(celsius * 10);
+#endif
+}
+
+/* Sampling takes a while, so done here instead of Rx callback */
+static void sensor_task(void *arg)
+{
+	sensor_job_t job = {0};
+
+	for (;;) {
+		if (xQueueReceive(sensor_queue, &job, portMAX_DELAY) != pdTRUE)
+			continue;
+
+		if (job.req_id == CUSTOM_RPC_REQ_ID__READ_ADC) {
+			custom_rpc_adc_reading_t reading = {0};
+
+			read_adc(&job, &reading);
+			create_and_send_custom_rpc_unserialised_event(CUSTOM_RPC_EVENT_ID__ADC_READING,
+					&reading, sizeof(reading));
+		} else {
+			custom_rpc_chip_temp_t temp = {0};
+
+			read_chip_temp(&temp);
+			create_and_send_custom_rpc_unserialised_event(CUSTOM_RPC_EVENT_ID__CHIP_TEMP,
+					&temp, sizeof(temp));
+		}
+	}
+}
+
+static esp_err_t queue_job(const sensor_job_t *job)
+{
+	if (!sensor_queue || xQueueSend(sensor_queue, job, 0) != pdTRUE) {
+		ESP_LOGE(TAG, "Sensor queue full or not initialised");
+		return ESP_ERR_INVALID_STATE;
+	}
+
+	/* Empty response acks request, reading follows as event */
+	return ESP_OK;
+}
+#endif
+
+esp_err_t adc_sensor_init(void)
+{
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 0, 0)
+	adc_oneshot_unit_init_cfg_t unit_cfg = { .unit_id = ADC_UNIT_1 };
+#if SOC_TEMP_SENSOR_SUPPORTED
+	temperature_sensor_config_t temp_cfg = TEMPERATURE_SENSOR_CONFIG_DEFAULT(-10, 80);
+#endif
+	esp_err_t ret = ESP_OK;
+
+	ret = adc_oneshot_new_unit(&unit_cfg, &adc1_handle);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to init ADC1: %d", ret);
+		adc1_handle = NULL;
+	} else {
+		for (int atten = 0; atten <= CUSTOM_RPC_ADC_ATTEN_DB_12; atten++)
+			adc_cali_create(atten, &adc1_cali[atten]);
+	}
+
+#if SOC_TEMP_SENSOR_SUPPORTED
+	ret = temperature_sensor_install(&temp_cfg, &temp_handle);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to install temperature sensor: %d", ret);
+		temp_handle = NULL;
+	}
+#endif
+
+	sensor_queue = xQueueCreate(SENSOR_QUEUE_LEN, sizeof(sensor_job_t));
+	if (!sensor_queue) {
+		ESP_LOGE(TAG, "Failed to create sensor queue");
+		return ESP_ERR_NO_MEM;
+	}
+
+	if (xTaskCreate(sensor_task, "sensor_task", CONFIG_ESP_DEFAULT_TASK_STACK_SIZE,
+				NULL, CONFIG_ESP_HOSTED_TASK_PRIORITY_DEFAULT, NULL) != pdTRUE) {
+		ESP_LOGE(TAG, "Failed to create sensor task");
+		vQueueDelete(sensor_queue);
+		sensor_queue = NULL;
+		return ESP_ERR_NO_MEM;
+	}
+#endif
+	return ESP_OK;
+}
+
+esp_err_t adc_sensor_read_adc(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 0, 0)
+	custom_rpc_adc_read_t rd = {0};
+	sensor_job_t job = { .req_id = CUSTOM_RPC_REQ_ID__READ_ADC };
+
+	if (!req->data || req->data_len < sizeof(rd)) {
+		ESP_LOGE(TAG, "Invalid ADC read request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&rd, req->data, sizeof(rd));
+
+	if (rd.channel >= SOC_ADC_CHANNEL_NUM(0) || rd.atten > CUSTOM_RPC_ADC_ATTEN_DB_12) {
+		ESP_LOGE(TAG, "Invalid ADC1 channel %u or atten %u", rd.channel, rd.atten);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	if (!adc1_handle) {
+		ESP_LOGE(TAG, "ADC1 not initialised");
+		return ESP_ERR_INVALID_STATE;
+	}
+
+	job.channel = rd.channel;
+	job.atten = rd.atten;
+	return queue_job(&job);
+#else
+	ESP_LOGW(TAG, "ADC read needs ESP-IDF v5.0 or later");
+	return ESP_ERR_NOT_SUPPORTED;
+#endif
+}
+
+esp_err_t adc_sensor_read_chip_temp(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+#if (ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 0, 0)) && SOC_TEMP_SENSOR_SUPPORTED
+	sensor_job_t job = { .req_id = CUSTOM_RPC_REQ_ID__READ_CHIP_TEMP };
+
+	if (!temp_handle) {
+		ESP_LOGE(TAG, "Temperature sensor not installed");
+		return ESP_ERR_INVALID_STATE;
+	}
+
+	return queue_job(&job);
+#else
+	ESP_LOGW(TAG, "Internal temperature sensor not supported on this chip or ESP-IDF");
+	return ESP_ERR_NOT_SUPPORTED;
+#endif
+}
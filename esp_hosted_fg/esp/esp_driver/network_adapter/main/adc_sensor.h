@@ -0,0 +1,24 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __ADC_SENSOR_H__
+#define __ADC_SENSOR_H__
+
+#include "slave_control.h"
+
+/* Inits ADC1, its calibration and temperature sensor once, and starts
+ * sensor task. Call before custom RPC handler is registered */
+esp_err_t adc_sensor_init(void);
+
+/* Custom RPC handlers to read ADC1 channels and internal temperature sensor.
+ * Check and queue request, reading is sent by sensor task as event.
+ * Return ESP_ERR_NOT_SUPPORTED where chip or ESP-IDF version lacks these */
+esp_err_t adc_sensor_read_adc(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t adc_sensor_read_chip_temp(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
@@ -47,6 +47,18 @@
 #include "host_power_save.h"
 
 #include "esp_hosted_custom_rpc.h"
+#include "softap_sta_mgmt.h"
+#include "wifi_phy_config.h"
+#include "vendor_ie_monitor.h"
+#include "probe_req_monitor.h"
+#include "wifi_pmf_config.h"
+#include "flash_debug.h"
+#include "log_level_config.h"
+#include "adc_sensor.h"
+#include "traffic_filter.h"
+#include "event_log.h"
+#include "scan_config.h"
+#include "softap_acl.h"
 
 static const char TAG[] = "fg_slave";
 
@@ -80,7 +92,6 @@ static struct rx_data {
 } r;
 
 static esp_err_t handle_custom_unserialised_rpc_request(const custom_rpc_unserialised_data_t *req, custom_rpc_unserialised_data_t *resp_out);
-esp_err_t create_and_send_custom_rpc_unserialised_event(uint32_t custom_event_id, const void *data, size_t data_len);
 
 static void print_firmware_version()
 {
@@ -165,6 +176,9 @@ esp_err_t wlan_ap_rx_callback(void *buffer, uint16_t len, void *eb)
 		return ESP_OK;
 	}
 	ESP_HEXLOGV("AP_Get", buffer, len, 32);
+	if (!softap_acl_rx_allowed(buffer, len))
+		goto DONE;
+	softap_sta_mgmt_account_rx(buffer, len);
 
 	populate_wifi_buffer_handle(&buf_handle, ESP_AP_IF, buffer, len);
 
@@ -192,6 +206,9 @@ esp_err_t wlan_sta_rx_callback(void *buffer, uint16_t len, void *eb)
 
 	ESP_HEXLOGV("STA_Get", buffer, len, 64);
 
+	if (traffic_filter_check(buffer, len) == CUSTOM_RPC_FILTER_ACTION_DROP)
+		goto DONE;
+
 	populate_wifi_buffer_handle(&buf_handle, ESP_STA_IF, buffer, len);
 
 	if (unlikely(send_to_host_queue(&buf_handle, PRIO_Q_OTHERS)))
@@ -361,6 +378,7 @@ static void process_rx_pkt(interface_buffer_handle_t *buf_handle)
         /* Forward data to wlan driver */
         esp_wifi_internal_tx(WIFI_IF_AP, payload, payload_len);
         ESP_HEXLOGV("AP_Put", payload, payload_len, 32);
+        softap_sta_mgmt_account_tx(payload, payload_len);
     } else if (buf_handle->if_type == ESP_SERIAL_IF) {
 #if ESP_PKT_STATS
 		pkt_stats.serial_rx++;
@@ -817,6 +835,10 @@ esp_err_t esp_hosted_coprocessor_init(void)
 
 	host_power_save_init(host_wakeup_callback);
 
+	flash_debug_init();
+	adc_sensor_init();
+	softap_acl_init();
+
 	/* Register how you are going to handle the user defined RPC requests */
 
 	register_custom_rpc_unserialised_req_handler(handle_custom_unserialised_rpc_request);
@@ -836,6 +858,8 @@ void app_main(void)
 	}
 	ESP_ERROR_CHECK( ret );
 
+	event_log_init();
+
 	esp_hosted_coprocessor_init();
 }
 
@@ -844,7 +868,9 @@ static esp_err_t handle_custom_unserialised_rpc_request(const custom_rpc_unseria
 	/* --------- Caution ----------
 	 *  Keep this function as simple, small and fast as possible
 	 *  This function is as callback in the Rx thread.
-	 *  Do not use any blocking calls here
+	 *  Do not use any blocking calls here, nor in handlers called
+	 *  from it. Slow work is queued to a task, which sends result
+	 *  as event (e.g. flash_debug_read)
 	 * ----------------------------
 	 */
 	esp_err_t ret = ESP_FAIL;
@@ -892,6 +918,105 @@ static esp_err_t handle_custom_unserialised_rpc_request(const custom_rpc_unseria
 			}
 			break;
 
+		case CUSTOM_RPC_REQ_ID__SOFTAP_GET_STA_DETAILS:
+			ret = softap_sta_mgmt_get_details(&resp_out->data, &resp_out->data_len);
+			if (ret == ESP_OK)
+				resp_out->free_func = free;
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SOFTAP_KICK_STA:
+			if (req->data_len >= CUSTOM_RPC_MAC_LEN && req->data) {
+				ret = softap_sta_mgmt_kick(req->data);
+			} else {
+				ESP_LOGE(TAG, "Station MAC missing in kick request");
+				ret = ESP_FAIL;
+			}
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_WIFI_PROTOCOL:
+			ret = wifi_phy_config_get_protocol(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_WIFI_PROTOCOL:
+			ret = wifi_phy_config_set_protocol(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_WIFI_BANDWIDTH:
+			ret = wifi_phy_config_get_bandwidth(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_WIFI_BANDWIDTH:
+			ret = wifi_phy_config_set_bandwidth(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__VENDOR_IE_MONITOR:
+			ret = vendor_ie_monitor_config(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__PROBE_REQ_MONITOR:
+			ret = probe_req_monitor_config(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_PMF_CONFIG:
+			ret = wifi_pmf_config_get(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_PMF_CONFIG:
+			ret = wifi_pmf_config_set(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_PARTITION_TABLE:
+			ret = flash_debug_get_partition_table(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__READ_FLASH:
+			ret = flash_debug_read(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_LOG_LEVEL:
+			ret = log_level_config_set(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__READ_ADC:
+			ret = adc_sensor_read_adc(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__READ_CHIP_TEMP:
+			ret = adc_sensor_read_chip_temp(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_TRAFFIC_FILTER:
+			ret = traffic_filter_set(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_TRAFFIC_FILTER:
+			ret = traffic_filter_get(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_EVENT_LOG:
+			ret = event_log_get(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__CLEAR_EVENT_LOG:
+			ret = event_log_clear(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_SCAN_CONFIG:
+			ret = scan_config_set(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_SCAN_CONFIG:
+			ret = scan_config_get(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__SET_SOFTAP_ACL:
+			ret = softap_acl_set(req, resp_out);
+			break;
+
+		case CUSTOM_RPC_REQ_ID__GET_SOFTAP_ACL:
+			ret = softap_acl_get(req, resp_out);
+			break;
+
 		case CUSTOM_RPC_REQ_ID__ONLY_ACK:
 			/* Just process the request, don't return any data */
 			ESP_LOGI(TAG, "Processing request with ID [%" PRIu32 "] - acknowledgement only", req->custom_msg_id);
@@ -31,4 +31,5 @@ typedef struct {
 } adapter;
 
 esp_err_t esp_hosted_coprocessor_init(void);
+esp_err_t create_and_send_custom_rpc_unserialised_event(uint32_t custom_event_id, const void *data, size_t data_len);
 #endif
@@ -0,0 +1,195 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <inttypes.h>
+#include <time.h>
+#include "freertos/FreeRTOS.h"
+#include "esp_log.h"
+#include "esp_system.h"
+#include "esp_timer.h"
+#include "nvs.h"
+#include "endian.h"
+#include "event_log.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define NVS_NAMESPACE                "event_log"
+#define NVS_KEY_BOOTS                "boots"
+#define NVS_KEY_ENTRIES              "entries"
+/* Batches repeated disconnects into one flash write */
+#define COMMIT_DELAY_US              (30 * 1000 * 1000)
+/* Earlier wall clock means ESP clock was never set */
+#define MIN_VALID_UNIX_TIME          1577836800 /* 2020-01-01 */
+
+static const char *TAG = "event_log";
+
+static custom_rpc_event_log_entry_t entries[CUSTOM_RPC_EVENT_LOG_MAX_ENTRIES];
+static uint8_t num_entries;
+static uint32_t boot_count;
+static esp_timer_handle_t commit_timer;
+static portMUX_TYPE log_lock = portMUX_INITIALIZER_UNLOCKED;
+
+static void commit(void)
+{
+	custom_rpc_event_log_entry_t copy[CUSTOM_RPC_EVENT_LOG_MAX_ENTRIES];
+	uint32_t boots = 0;
+	uint8_t num = 0;
+	nvs_handle_t handle = 0;
+	esp_err_t ret = ESP_OK;
+
+	portENTER_CRITICAL(&log_lock);
+	memcpy(copy, entries, sizeof(copy));
+	num = num_entries;
+	boots = boot_count;
+	portEXIT_CRITICAL(&log_lock);
+
+	ret = nvs_open(NVS_NAMESPACE, NVS_READWRITE, &handle);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to open NVS: %d", ret);
+		return;
+	}
+
+	ret = nvs_set_u32(handle, NVS_KEY_BOOTS, boots);
+	if (!ret && num)
+		ret = nvs_set_blob(handle, NVS_KEY_ENTRIES, copy, num * sizeof(custom_rpc_event_log_entry_t));
+	else if (!ret)
+		nvs_erase_key(handle, NVS_KEY_ENTRIES);
+	if (!ret)
+		ret = nvs_commit(handle);
+	if (ret)
+		ESP_LOGE(TAG, "Failed to write event log: %d", ret);
+	nvs_close(handle);
+}
+
+static void commit_timer_cb(void *arg)
+{
+	commit();
+}
+
+static void schedule_commit(void)
+{
+	if (commit_timer && !esp_timer_is_active(commit_timer))
+		esp_timer_start_once(commit_timer, COMMIT_DELAY_US);
+}
+
+static void add_entry(uint8_t type, uint16_t reason)
+{
+	custom_rpc_event_log_entry_t *last = NULL;
+	time_t now = time(NULL);
+	uint32_t uptime_sec = esp_timer_get_time() / 1000000;
+	uint32_t unix_time = now >= MIN_VALID_UNIX_TIME ? (uint32_t)now : 0;
+
+	portENTER_CRITICAL(&log_lock);
+	last = num_entries ? &entries[num_entries - 1] : NULL;
+	if (last && type != CUSTOM_RPC_EVENT_LOG_RESET && last->type == type &&
+	    last->reason == reason && last->boot == boot_count) {
+		if (last->count < UINT16_MAX)
+			last->count++;
+	} else {
+		if (num_entries == CUSTOM_RPC_EVENT_LOG_MAX_ENTRIES) {
+			memmove(&entries[0], &entries[1], (num_entries - 1) * sizeof(entries[0]));
+			num_entries--;
+		}
+		last = &entries[num_entries++];
+		memset(last, 0, sizeof(*last));
+		last->type = type;
+		last->reason = reason;
+		last->count = 1;
+		last->boot = boot_count;
+	}
+	last->uptime_sec = uptime_sec;
+	last->unix_time = unix_time;
+	portEXIT_CRITICAL(&log_lock);
+}
+
+void event_log_init(void)
+{
+	esp_timer_create_args_t timer_args = {
+		.callback = commit_timer_cb,
+		.name = "event_log_commit",
+	};
+	nvs_handle_t handle = 0;
+	size_t len = sizeof(entries);
+
+	if (nvs_open(NVS_NAMESPACE, NVS_READONLY, &handle) == ESP_OK) {
+		nvs_get_u32(handle, NVS_KEY_BOOTS, &boot_count);
+		if (nvs_get_blob(handle, NVS_KEY_ENTRIES, entries, &len) == ESP_OK)
+			num_entries = len / sizeof(custom_rpc_event_log_entry_t);
+		nvs_close(handle);
+	}
+
+	boot_count++;
+	add_entry(CUSTOM_RPC_EVENT_LOG_RESET, esp_reset_reason());
+	/* Written right away, as next reset may come soon, e.g. on brownout */
+	commit();
+
+	if (esp_timer_create(&timer_args, &commit_timer))
+		ESP_LOGE(TAG, "Failed to create commit timer, only resets are kept");
+
+	ESP_LOGI(TAG, "boot %" PRIu32 ", reset reason %d, %u entries", boot_count,
+			esp_reset_reason(), num_entries);
+}
+
+void event_log_wifi_disconnect(bool was_connected, uint16_t reason)
+{
+	add_entry(was_connected ? CUSTOM_RPC_EVENT_LOG_DISCONNECT : CUSTOM_RPC_EVENT_LOG_ASSOC_FAIL, reason);
+	schedule_commit();
+}
+
+esp_err_t event_log_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_event_log_t *log = NULL;
+	size_t len = sizeof(custom_rpc_event_log_t) +
+		CUSTOM_RPC_EVENT_LOG_MAX_ENTRIES * sizeof(custom_rpc_event_log_entry_t);
+
+	log = calloc(1, len);
+	if (!log) {
+		ESP_LOGE(TAG, "Failed to allocate memory for response");
+		return ESP_ERR_NO_MEM;
+	}
+
+	portENTER_CRITICAL(&log_lock);
+	log->boot_count = htole32(boot_count);
+	log->num = num_entries;
+	memcpy(log->entry, entries, num_entries * sizeof(custom_rpc_event_log_entry_t));
+	portEXIT_CRITICAL(&log_lock);
+
+	for (int i = 0; i < log->num; i++) {
+		custom_rpc_event_log_entry_t *e = &log->entry[i];
+
+		e->reason = htole16(e->reason);
+		e->count = htole16(e->count);
+		e->boot = htole32(e->boot);
+		e->uptime_sec = htole32(e->uptime_sec);
+		e->unix_time = htole32(e->unix_time);
+	}
+
+	resp->data = (uint8_t *)log;
+	resp->data_len = sizeof(custom_rpc_event_log_t) + log->num * sizeof(custom_rpc_event_log_entry_t);
+	resp->free_func = free;
+	return ESP_OK;
+}
+
+esp_err_t event_log_clear(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	portENTER_CRITICAL(&log_lock);
+	num_entries = 0;
+	/* Current boot stays counted */
+	boot_count = 1;
+	portEXIT_CRITICAL(&log_lock);
+
+	/* Flash write is left to timer, not to block rx, but without batching
+	 * delay, so clear is not lost on reset */
+	if (commit_timer) {
+		esp_timer_stop(commit_timer);
+		esp_timer_start_once(commit_timer, 1000);
+	}
+	ESP_LOGI(TAG, "event log cleared by host");
+	return ESP_OK;
+}
@@ -0,0 +1,28 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __EVENT_LOG_H__
+#define __EVENT_LOG_H__
+
+#include <stdint.h>
+#include <stdbool.h>
+#include "slave_control.h"
+
+/* Loads log from NVS and records reset reason of this boot.
+ * Call once, after nvs_flash_init() */
+void event_log_init(void);
+
+/* Records station disconnect. Repeats are counted in one entry and
+ * written to flash in batches, to spare flash from retry loops */
+void event_log_wifi_disconnect(bool was_connected, uint16_t reason);
+
+/* Custom RPC handlers to read and clear log */
+esp_err_t event_log_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t event_log_clear(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
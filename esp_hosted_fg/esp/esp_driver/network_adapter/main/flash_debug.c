@@ -0,0 +1,201 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <inttypes.h>
+#include "freertos/FreeRTOS.h"
+#include "freertos/queue.h"
+#include "freertos/task.h"
+#include "esp_log.h"
+#include "esp_partition.h"
+#include "esp_flash.h"
+#include "sdkconfig.h"
+#include "flash_debug.h"
+#include "esp_hosted_coprocessor.h"
+#include "esp_hosted_custom_rpc.h"
+
+static const char *TAG = "flash_debug";
+
+#ifdef CONFIG_ESP_HOSTED_FLASH_READ_RPC
+#define FLASH_READ_QUEUE_LEN 4
+
+typedef struct {
+	esp_flash_t *chip;
+	uint32_t addr;
+	uint32_t offset;
+	uint32_t len;
+} flash_read_job_t;
+
+static QueueHandle_t read_queue;
+
+/* Flash read may stall for ms, so done here instead of Rx callback */
+static void flash_read_task(void *arg)
+{
+	flash_read_job_t job = {0};
+	custom_rpc_flash_read_data_t *ev = NULL;
+	custom_rpc_flash_read_data_t failed = {0};
+	esp_err_t ret = ESP_OK;
+
+	for (;;) {
+		if (xQueueReceive(read_queue, &job, portMAX_DELAY) != pdTRUE)
+			continue;
+
+		ev = malloc(sizeof(custom_rpc_flash_read_data_t) + job.len);
+		if (!ev) {
+			ESP_LOGE(TAG, "Failed to allocate memory for flash read event");
+			failed.offset = job.offset;
+			failed.status = ESP_ERR_NO_MEM;
+			create_and_send_custom_rpc_unserialised_event(CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA,
+					&failed, sizeof(failed));
+			continue;
+		}
+
+		/* Raw read, encrypted data is not decrypted */
+		ret = esp_flash_read(job.chip, ev->data, job.addr, job.len);
+		if (ret) {
+			ESP_LOGE(TAG, "Flash read at 0x%" PRIx32 " failed: %d", job.addr, ret);
+		} else {
+			ESP_LOGI(TAG, "Read %" PRIu32 " bytes at 0x%" PRIx32 " for host", job.len, job.addr);
+		}
+
+		ev->offset = job.offset;
+		ev->status = ret;
+		ev->len = ret ? 0 : job.len;
+		create_and_send_custom_rpc_unserialised_event(CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA,
+				ev, sizeof(custom_rpc_flash_read_data_t) + ev->len);
+		free(ev);
+	}
+}
+#endif
+
+esp_err_t flash_debug_init(void)
+{
+#ifdef CONFIG_ESP_HOSTED_FLASH_READ_RPC
+	read_queue = xQueueCreate(FLASH_READ_QUEUE_LEN, sizeof(flash_read_job_t));
+	if (!read_queue) {
+		ESP_LOGE(TAG, "Failed to create flash read queue");
+		return ESP_ERR_NO_MEM;
+	}
+
+	if (xTaskCreate(flash_read_task, "flash_read_task", CONFIG_ESP_DEFAULT_TASK_STACK_SIZE,
+				NULL, CONFIG_ESP_HOSTED_TASK_PRIORITY_DEFAULT, NULL) != pdTRUE) {
+		ESP_LOGE(TAG, "Failed to create flash read task");
+		vQueueDelete(read_queue);
+		read_queue = NULL;
+		return ESP_ERR_NO_MEM;
+	}
+#endif
+	return ESP_OK;
+}
+
+static uint8_t add_partitions(esp_partition_type_t type,
+		custom_rpc_partition_table_t *table, uint8_t num)
+{
+	esp_partition_iterator_t it = esp_partition_find(type, ESP_PARTITION_SUBTYPE_ANY, NULL);
+
+	for (; it && num < CUSTOM_RPC_MAX_PARTITIONS; it = esp_partition_next(it)) {
+		const esp_partition_t *p = esp_partition_get(it);
+		custom_rpc_partition_entry_t *e = &table->entry[num++];
+
+		e->type = p->type;
+		e->subtype = p->subtype;
+		e->address = p->address;
+		e->size = p->size;
+		e->encrypted = p->encrypted;
+		strlcpy(e->label, p->label, sizeof(e->label));
+	}
+	esp_partition_iterator_release(it);
+
+	return num;
+}
+
+esp_err_t flash_debug_get_partition_table(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_partition_table_t *table = NULL;
+	size_t len = sizeof(custom_rpc_partition_table_t) +
+		CUSTOM_RPC_MAX_PARTITIONS * sizeof(custom_rpc_partition_entry_t);
+
+	table = calloc(1, len);
+	if (!table) {
+		ESP_LOGE(TAG, "Failed to allocate memory for partition table");
+		return ESP_ERR_NO_MEM;
+	}
+
+	table->num = add_partitions(ESP_PARTITION_TYPE_APP, table, 0);
+	table->num = add_partitions(ESP_PARTITION_TYPE_DATA, table, table->num);
+
+	resp->data = (uint8_t *)table;
+	resp->data_len = sizeof(custom_rpc_partition_table_t) +
+		table->num * sizeof(custom_rpc_partition_entry_t);
+	resp->free_func = free;
+	return ESP_OK;
+}
+
+esp_err_t flash_debug_read(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+#ifdef CONFIG_ESP_HOSTED_FLASH_READ_RPC
+	custom_rpc_flash_read_t rd = {0};
+	flash_read_job_t job = {0};
+	const esp_partition_t *p = NULL;
+	esp_flash_t *chip = NULL;
+	uint32_t addr = 0;
+
+	if (!req->data || req->data_len < sizeof(rd)) {
+		ESP_LOGE(TAG, "Invalid flash read request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&rd, req->data, sizeof(rd));
+	rd.label[CUSTOM_RPC_PARTITION_LABEL_LEN - 1] = '\0';
+
+	if (!rd.len || rd.len > CUSTOM_RPC_FLASH_READ_MAX_LEN) {
+		ESP_LOGE(TAG, "Flash read length %" PRIu32 " not in 1..%u",
+				rd.len, CUSTOM_RPC_FLASH_READ_MAX_LEN);
+		return ESP_ERR_INVALID_SIZE;
+	}
+
+	if (rd.label[0]) {
+		p = esp_partition_find_first(ESP_PARTITION_TYPE_DATA, ESP_PARTITION_SUBTYPE_ANY, rd.label);
+		if (!p)
+			p = esp_partition_find_first(ESP_PARTITION_TYPE_APP, ESP_PARTITION_SUBTYPE_ANY, rd.label);
+		if (!p) {
+			ESP_LOGE(TAG, "No partition with label [%s]", rd.label);
+			return ESP_ERR_NOT_FOUND;
+		}
+		if (rd.offset >= p->size || rd.len > p->size - rd.offset) {
+			ESP_LOGE(TAG, "Read beyond partition [%s] of size 0x%" PRIx32, rd.label, p->size);
+			return ESP_ERR_INVALID_SIZE;
+		}
+		chip = p->flash_chip;
+		addr = p->address + rd.offset;
+	} else {
+		chip = esp_flash_default_chip;
+		addr = rd.offset;
+	}
+
+	if (!chip || addr >= chip->size || rd.len > chip->size - addr) {
+		ESP_LOGE(TAG, "Read beyond flash size");
+		return ESP_ERR_INVALID_SIZE;
+	}
+
+	job.chip = chip;
+	job.addr = addr;
+	job.offset = rd.offset;
+	job.len = rd.len;
+	if (!read_queue || xQueueSend(read_queue, &job, 0) != pdTRUE) {
+		ESP_LOGE(TAG, "Flash read queue full or not initialised");
+		return ESP_ERR_INVALID_STATE;
+	}
+
+	/* Empty response acks request, data follows as event */
+	return ESP_OK;
+#else
+	ESP_LOGW(TAG, "Flash read disabled, enable CONFIG_ESP_HOSTED_FLASH_READ_RPC");
+	return ESP_ERR_NOT_SUPPORTED;
+#endif
+}
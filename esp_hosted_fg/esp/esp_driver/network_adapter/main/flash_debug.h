@@ -0,0 +1,25 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __FLASH_DEBUG_H__
+#define __FLASH_DEBUG_H__
+
+#include "slave_control.h"
+
+/* Starts flash read task, call before custom RPC handler is registered */
+esp_err_t flash_debug_init(void);
+
+/* Custom RPC handlers to inspect flash from host */
+esp_err_t flash_debug_get_partition_table(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+/* Needs CONFIG_ESP_HOSTED_FLASH_READ_RPC, else returns ESP_ERR_NOT_SUPPORTED.
+ * Checks and queues read, data is sent by flash read task in
+ * CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA */
+esp_err_t flash_debug_read(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
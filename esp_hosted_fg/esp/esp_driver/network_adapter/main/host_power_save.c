@@ -14,6 +14,7 @@
 #include <string.h>
 #include "esp_timer.h"
 #include "adapter.h"
+#include "traffic_filter.h"
 
 static char *TAG = "host_ps";
 
@@ -86,7 +87,8 @@ int is_host_wakeup_needed(interface_buffer_handle_t *buf_handle)
 
 		case ESP_STA_IF:
 			  strlcpy(reason, "sta tx msg", sizeof(reason));
-			  wakup_needed = 1;
+			  /* Host may choose frames worth waking for */
+			  wakup_needed = traffic_filter_wakeup_needed(buf_start, buf_handle->payload_len);
 			  goto end;
 			  break;
 
@@ -0,0 +1,45 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include "esp_log.h"
+#include "sdkconfig.h"
+#include "log_level_config.h"
+#include "esp_hosted_custom_rpc.h"
+
+static const char *TAG = "log_level";
+
+esp_err_t log_level_config_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_log_level_t cfg = {0};
+
+	if (!req->data || req->data_len < sizeof(cfg)) {
+		ESP_LOGE(TAG, "Invalid set log level request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&cfg, req->data, sizeof(cfg));
+	cfg.tag[CUSTOM_RPC_LOG_TAG_LEN - 1] = '\0';
+
+	if (cfg.level > CUSTOM_RPC_LOG_VERBOSE) {
+		ESP_LOGE(TAG, "Invalid log level %u", cfg.level);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	if (!cfg.tag[0])
+		strcpy(cfg.tag, "*");
+
+#ifdef CONFIG_LOG_MAXIMUM_LEVEL
+	/* Logs above CONFIG_LOG_MAXIMUM_LEVEL are compiled out */
+	if (cfg.level > CONFIG_LOG_MAXIMUM_LEVEL)
+		ESP_LOGW(TAG, "Level %u is above CONFIG_LOG_MAXIMUM_LEVEL %u, only up to that level will be printed",
+				cfg.level, CONFIG_LOG_MAXIMUM_LEVEL);
+#endif
+
+	esp_log_level_set(cfg.tag, (esp_log_level_t)cfg.level);
+	ESP_LOGI(TAG, "Log level of [%s] set to %u by host", cfg.tag, cfg.level);
+	return ESP_OK;
+}
@@ -0,0 +1,16 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __LOG_LEVEL_CONFIG_H__
+#define __LOG_LEVEL_CONFIG_H__
+
+#include "slave_control.h"
+
+/* Changes runtime log level of a tag, or of all tags, from host */
+esp_err_t log_level_config_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
@@ -0,0 +1,200 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include "freertos/FreeRTOS.h"
+#include "esp_log.h"
+#include "esp_wifi.h"
+#include "esp_timer.h"
+#include "esp_idf_version.h"
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 0, 0)
+#include "esp_random.h"
+#else
+#include "esp_system.h"
+#endif
+#include "mbedtls/sha256.h"
+#include "probe_req_monitor.h"
+#include "esp_hosted_coprocessor.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define DEFAULT_REPORT_INTERVAL_SEC  10
+#define SALT_LEN                     16
+
+/* 802.11 management header */
+#define WLAN_FC_PROBE_REQ            0x40
+#define WLAN_FC_TYPE_SUBTYPE_MASK    0xFC
+#define WLAN_ADDR2_OFFSET            10
+#define WLAN_MGMT_HDR_LEN            24
+
+static const char *TAG = "probe_req";
+
+typedef struct {
+	uint8_t mac_hash[CUSTOM_RPC_PROBE_REQ_HASH_LEN];
+	int8_t rssi;
+	uint8_t channel;
+	uint16_t count;
+	int64_t last_seen_us;
+} probe_entry_t;
+
+static bool monitor_enabled;
+static uint8_t salt[SALT_LEN];
+static probe_entry_t entries[CUSTOM_RPC_PROBE_REQ_MAX_ENTRIES];
+static uint8_t num_entries;
+static esp_timer_handle_t report_timer;
+static portMUX_TYPE monitor_lock = portMUX_INITIALIZER_UNLOCKED;
+
+static void hash_mac(const uint8_t *mac, uint8_t *out)
+{
+	uint8_t buf[SALT_LEN + CUSTOM_RPC_MAC_LEN];
+	uint8_t digest[32];
+
+	memcpy(buf, salt, SALT_LEN);
+	memcpy(buf + SALT_LEN, mac, CUSTOM_RPC_MAC_LEN);
+	mbedtls_sha256(buf, sizeof(buf), digest, 0);
+	memcpy(out, digest, CUSTOM_RPC_PROBE_REQ_HASH_LEN);
+}
+
+static void promiscuous_rx_cb(void *buf, wifi_promiscuous_pkt_type_t type)
+{
+	const wifi_promiscuous_pkt_t *pkt = buf;
+	uint8_t mac_hash[CUSTOM_RPC_PROBE_REQ_HASH_LEN];
+	probe_entry_t *entry = NULL;
+
+	if (type != WIFI_PKT_MGMT || !pkt ||
+	    pkt->rx_ctrl.sig_len < WLAN_MGMT_HDR_LEN ||
+	    (pkt->payload[0] & WLAN_FC_TYPE_SUBTYPE_MASK) != WLAN_FC_PROBE_REQ)
+		return;
+
+	hash_mac(pkt->payload + WLAN_ADDR2_OFFSET, mac_hash);
+
+	portENTER_CRITICAL(&monitor_lock);
+	for (int i = 0; i < num_entries; i++) {
+		if (!memcmp(entries[i].mac_hash, mac_hash, sizeof(mac_hash))) {
+			entry = &entries[i];
+			break;
+		}
+	}
+	if (!entry && num_entries < CUSTOM_RPC_PROBE_REQ_MAX_ENTRIES) {
+		entry = &entries[num_entries++];
+		memcpy(entry->mac_hash, mac_hash, sizeof(mac_hash));
+		entry->rssi = pkt->rx_ctrl.rssi;
+		entry->count = 0;
+	}
+	if (entry) {
+		if (pkt->rx_ctrl.rssi > entry->rssi)
+			entry->rssi = pkt->rx_ctrl.rssi;
+		entry->channel = pkt->rx_ctrl.channel;
+		if (entry->count < UINT16_MAX)
+			entry->count++;
+		entry->last_seen_us = esp_timer_get_time();
+	}
+	portEXIT_CRITICAL(&monitor_lock);
+}
+
+static void report_timer_cb(void *arg)
+{
+	custom_rpc_probe_req_report_t *report = NULL;
+	int64_t now = esp_timer_get_time();
+	size_t len = sizeof(custom_rpc_probe_req_report_t) +
+		CUSTOM_RPC_PROBE_REQ_MAX_ENTRIES * sizeof(custom_rpc_probe_req_entry_t);
+
+	report = calloc(1, len);
+	if (!report) {
+		ESP_LOGE(TAG, "Failed to allocate memory for probe request report");
+		return;
+	}
+
+	portENTER_CRITICAL(&monitor_lock);
+	report->num = num_entries;
+	for (int i = 0; i < num_entries; i++) {
+		custom_rpc_probe_req_entry_t *out = &report->entry[i];
+
+		memcpy(out->mac_hash, entries[i].mac_hash, CUSTOM_RPC_PROBE_REQ_HASH_LEN);
+		out->rssi = entries[i].rssi;
+		out->channel = entries[i].channel;
+		out->count = entries[i].count;
+		out->age_ms = (uint32_t)((now - entries[i].last_seen_us) / 1000);
+	}
+	num_entries = 0;
+	portEXIT_CRITICAL(&monitor_lock);
+
+	len = sizeof(custom_rpc_probe_req_report_t) +
+		report->num * sizeof(custom_rpc_probe_req_entry_t);
+	create_and_send_custom_rpc_unserialised_event(CUSTOM_RPC_EVENT_ID__PROBE_REQ_REPORT,
+			report, len);
+	free(report);
+}
+
+static void stop_monitor(void)
+{
+	if (report_timer) {
+		esp_timer_stop(report_timer);
+		esp_timer_delete(report_timer);
+		report_timer = NULL;
+	}
+	esp_wifi_set_promiscuous(false);
+	esp_wifi_set_promiscuous_rx_cb(NULL);
+
+	portENTER_CRITICAL(&monitor_lock);
+	num_entries = 0;
+	portEXIT_CRITICAL(&monitor_lock);
+	monitor_enabled = false;
+}
+
+esp_err_t probe_req_monitor_config(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_probe_req_monitor_t cfg = {0};
+	wifi_promiscuous_filter_t filter = { .filter_mask = WIFI_PROMIS_FILTER_MASK_MGMT };
+	esp_timer_create_args_t timer_args = {
+		.callback = report_timer_cb,
+		.name = "probe_req_report",
+	};
+	uint8_t interval = 0;
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < sizeof(cfg)) {
+		ESP_LOGE(TAG, "Invalid probe request monitor request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&cfg, req->data, sizeof(cfg));
+
+	if (monitor_enabled)
+		stop_monitor();
+
+	if (!cfg.enable) {
+		ESP_LOGI(TAG, "probe request monitor disabled");
+		return ESP_OK;
+	}
+
+	interval = cfg.report_interval_sec ? cfg.report_interval_sec : DEFAULT_REPORT_INTERVAL_SEC;
+	esp_fill_random(salt, sizeof(salt));
+
+	ret = esp_timer_create(&timer_args, &report_timer);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to create report timer: %d", ret);
+		return ret;
+	}
+
+	ret = esp_wifi_set_promiscuous_filter(&filter);
+	if (!ret)
+		ret = esp_wifi_set_promiscuous_rx_cb(promiscuous_rx_cb);
+	if (!ret)
+		ret = esp_wifi_set_promiscuous(true);
+	if (!ret)
+		ret = esp_timer_start_periodic(report_timer, (uint64_t)interval * 1000 * 1000);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to start probe request monitor: %d", ret);
+		stop_monitor();
+		return ret;
+	}
+
+	monitor_enabled = true;
+	ESP_LOGI(TAG, "probe request monitor enabled, reporting every %u sec", interval);
+	return ESP_OK;
+}
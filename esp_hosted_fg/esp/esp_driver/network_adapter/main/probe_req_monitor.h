@@ -0,0 +1,16 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __PROBE_REQ_MONITOR_H__
+#define __PROBE_REQ_MONITOR_H__
+
+#include "slave_control.h"
+
+/* Enables or disables periodic reporting of sniffed probe requests to host */
+esp_err_t probe_req_monitor_config(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
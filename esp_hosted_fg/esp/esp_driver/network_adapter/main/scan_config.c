@@ -0,0 +1,96 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include "esp_log.h"
+#include "esp_idf_version.h"
+#include "endian.h"
+#include "scan_config.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define DEFAULT_DWELL_MS             60
+#define DEFAULT_HOME_DWELL_MS        CUSTOM_RPC_SCAN_HOME_DWELL_MIN_MS
+/* Passive channels need about a beacon interval to find an AP */
+#define MIN_PASSIVE_DWELL_MS         110
+
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 1, 0)
+#define SCAN_CAPS                    CUSTOM_RPC_SCAN_CAP_HOME_DWELL
+#else
+#define SCAN_CAPS                    0
+#endif
+
+static const char *TAG = "scan_config";
+
+static bool background;
+static uint16_t dwell_ms = DEFAULT_DWELL_MS;
+static uint8_t home_dwell_ms = DEFAULT_HOME_DWELL_MS;
+
+esp_err_t scan_config_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_scan_config_t *cfg = NULL;
+
+	cfg = calloc(1, sizeof(*cfg));
+	if (!cfg) {
+		ESP_LOGE(TAG, "Failed to allocate memory for response");
+		return ESP_ERR_NO_MEM;
+	}
+
+	cfg->background = background;
+	cfg->dwell_ms = htole16(dwell_ms);
+	cfg->home_dwell_ms = home_dwell_ms;
+	cfg->caps = SCAN_CAPS;
+
+	resp->data = (uint8_t *)cfg;
+	resp->data_len = sizeof(*cfg);
+	resp->free_func = free;
+	return ESP_OK;
+}
+
+esp_err_t scan_config_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_scan_config_t cfg = {0};
+	uint16_t dwell = 0;
+
+	if (!req->data || req->data_len < sizeof(cfg)) {
+		ESP_LOGE(TAG, "Invalid set scan config request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&cfg, req->data, sizeof(cfg));
+	dwell = le16toh(cfg.dwell_ms);
+
+	if ((dwell && (dwell < CUSTOM_RPC_SCAN_DWELL_MIN_MS || dwell > CUSTOM_RPC_SCAN_DWELL_MAX_MS)) ||
+	    (cfg.home_dwell_ms && (cfg.home_dwell_ms < CUSTOM_RPC_SCAN_HOME_DWELL_MIN_MS ||
+	                           cfg.home_dwell_ms > CUSTOM_RPC_SCAN_HOME_DWELL_MAX_MS))) {
+		ESP_LOGE(TAG, "Dwell %u ms or home dwell %u ms out of range", dwell, cfg.home_dwell_ms);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	background = cfg.background;
+	dwell_ms = dwell ? dwell : DEFAULT_DWELL_MS;
+	home_dwell_ms = cfg.home_dwell_ms ? cfg.home_dwell_ms : DEFAULT_HOME_DWELL_MS;
+
+	ESP_LOGI(TAG, "background scan %u, dwell %u ms, home dwell %u ms",
+			background, dwell_ms, home_dwell_ms);
+	return ESP_OK;
+}
+
+void scan_config_apply(wifi_scan_config_t *conf, bool sta_connected)
+{
+	if (!conf || !background || !sta_connected)
+		return;
+
+	conf->scan_type = WIFI_SCAN_TYPE_ACTIVE;
+	conf->scan_time.active.min = dwell_ms / 2;
+	conf->scan_time.active.max = dwell_ms;
+	conf->scan_time.passive = dwell_ms > MIN_PASSIVE_DWELL_MS ? dwell_ms : MIN_PASSIVE_DWELL_MS;
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(5, 1, 0)
+	conf->home_chan_dwell_time = home_dwell_ms;
+#endif
+	ESP_LOGI(TAG, "Background scan, dwell %u ms", dwell_ms);
+}
@@ -0,0 +1,24 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __SCAN_CONFIG_H__
+#define __SCAN_CONFIG_H__
+
+#include <stdbool.h>
+#include "esp_wifi.h"
+#include "slave_control.h"
+
+/* Custom RPC handlers for background scan settings */
+esp_err_t scan_config_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t scan_config_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+/* Shortens dwell times of conf for background scan, if enabled by host
+ * and station is connected. Called before starting scan */
+void scan_config_apply(wifi_scan_config_t *conf, bool sta_connected);
+
+#endif
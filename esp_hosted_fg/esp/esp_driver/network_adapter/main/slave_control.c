@@ -25,6 +25,11 @@
 #include "esp_fw_version.h"
 #include "host_power_save.h"
 #include "esp_timer.h"
+#include "softap_sta_mgmt.h"
+#include "wifi_pmf_config.h"
+#include "event_log.h"
+#include "scan_config.h"
+#include "softap_acl.h"
 
 
 #define MAC_STR_LEN                 17
@@ -176,6 +181,7 @@ static void station_event_handler(void *arg, esp_event_base_t event_base,
 	 * Please make sure that this callback function is as small as possible to avoid stack overflow */
 
 	if (event_id == WIFI_EVENT_STA_DISCONNECTED) {
+		bool was_connected = station_connected;
 
 		/* Mark as station disconnected */
 		station_connected = false;
@@ -187,6 +193,7 @@ static void station_event_handler(void *arg, esp_event_base_t event_base,
 			disconnected_event, sizeof(wifi_event_sta_disconnected_t));
 	ESP_LOGI(TAG, "Station disconnected, reason[%u]",
 			disconnected_event->reason);
+	event_log_wifi_disconnect(was_connected, disconnected_event->reason);
 
 		ESP_LOGI(TAG, "Sta mode disconnect, retry[%u]", sta_connect_retry);
 		sta_connect_retry++;
@@ -265,6 +272,9 @@ static void softap_event_handler(void *arg, esp_event_base_t event_base,
 		wifi_event_ap_staconnected_t *event = (wifi_event_ap_staconnected_t *) event_data;
 		ESP_LOGI(TAG, "station "MACSTR" join, AID=%d",
 				MAC2STR(event->mac), event->aid);
+		if (!softap_acl_sta_connected(event->mac, event->aid))
+			return;
+		softap_sta_mgmt_sta_connected(event->mac);
 		send_wifi_event_data_to_host(CTRL_MSG_ID__Event_StationConnectedToESPSoftAP,
 				event, sizeof(wifi_event_ap_staconnected_t));
 	} else if (event_id == WIFI_EVENT_AP_STADISCONNECTED) {
@@ -272,6 +282,7 @@ static void softap_event_handler(void *arg, esp_event_base_t event_base,
 			(wifi_event_ap_stadisconnected_t *) event_data;
 		ESP_LOGI(TAG, "station "MACSTR" leave, AID=%d",
 				MAC2STR(event->mac), event->aid);
+		softap_sta_mgmt_sta_disconnected(event->mac);
 		send_wifi_event_data_to_host(CTRL_MSG_ID__Event_StationDisconnectFromESPSoftAP,
 				event, sizeof(wifi_event_ap_stadisconnected_t));
 	} else if (event_id == WIFI_EVENT_AP_START) {
@@ -279,6 +290,7 @@ static void softap_event_handler(void *arg, esp_event_base_t event_base,
 	} else if (event_id == WIFI_EVENT_AP_STOP) {
 		ESP_LOGI(TAG,"softap stop handler stop");
 		esp_wifi_internal_reg_rxcb(WIFI_IF_AP,NULL);
+		softap_sta_mgmt_reset();
 	}
 }
 
@@ -637,8 +649,31 @@ static esp_err_t req_connect_ap_handler (CtrlMsg *req,
 		wifi_cfg->sta.bssid_set = true;
 	}
 	if (req->req_connect_ap->is_wpa3_supported) {
+#if !defined(CONFIG_ESP_WIFI_ENABLE_WPA3_SAE) && !defined(CONFIG_ESP32_WIFI_ENABLE_WPA3_SAE)
+		/* Without SAE, WPA3 only APs reject association with no
+		 * useful reason, so refuse early with explicit error */
+		ESP_LOGE(TAG, "WPA3 requested but SAE is disabled in firmware, enable CONFIG_ESP_WIFI_ENABLE_WPA3_SAE");
+		/* Host decodes this as CTRL_ERR_ESP_NOT_SUPPORTED of ctrl_api.h */
+		_Static_assert(ESP_ERR_NOT_SUPPORTED == 0x106,
+				"ESP_ERR_NOT_SUPPORTED must match CTRL_ERR_ESP_NOT_SUPPORTED");
+		resp_payload->resp = ESP_ERR_NOT_SUPPORTED;
+		/* Skips SUCCESS reset at err, so host sees the error */
+		goto done;
+#endif
+		/* PMF capable (not required) lets WPA2/WPA3 transition APs
+		 * use SAE while WPA2 only APs still work */
 		wifi_cfg->sta.pmf_cfg.capable = true;
 		wifi_cfg->sta.pmf_cfg.required = false;
+#if ESP_IDF_VERSION >= ESP_IDF_VERSION_VAL(4, 4, 0)
+		/* Hunt-and-peck and H2E, whichever AP supports */
+		wifi_cfg->sta.sae_pwe_h2e = WPA3_SAE_PWE_BOTH;
+#endif
+	}
+	wifi_pmf_config_apply(WIFI_IF_STA, &wifi_cfg->sta.pmf_cfg);
+	if (req->req_connect_ap->is_wpa3_supported && !wifi_cfg->sta.pmf_cfg.capable) {
+		/* SAE can't work without PMF, host setting must not disable it */
+		ESP_LOGW(TAG, "PMF capable forced on for WPA3, overriding host PMF setting");
+		wifi_cfg->sta.pmf_cfg.capable = true;
 	}
 	if (req->req_connect_ap->listen_interval >= 0) {
 		wifi_cfg->sta.listen_interval = req->req_connect_ap->listen_interval;
@@ -751,12 +786,15 @@ static esp_err_t req_connect_ap_handler (CtrlMsg *req,
 
 
 err:
-#if WIFI_DUALBAND_SUPPORT
-	resp_payload->band_mode = band_mode;
-#endif
 	ESP_LOGI(TAG, "%s:%u Set resp to Success",__func__,__LINE__);
 	resp_payload->resp = SUCCESS;
 
+#if !defined(CONFIG_ESP_WIFI_ENABLE_WPA3_SAE) && !defined(CONFIG_ESP32_WIFI_ENABLE_WPA3_SAE)
+done:
+#endif
+#if WIFI_DUALBAND_SUPPORT
+	resp_payload->band_mode = band_mode;
+#endif
 	if (wifi_cfg) {
 		mem_free(wifi_cfg);
 	}
@@ -1115,6 +1153,7 @@ static esp_err_t req_start_softap_handler (CtrlMsg *req,
 	wifi_config->ap.channel = req->req_start_softap->chnl;
 	wifi_config->ap.max_connection = req->req_start_softap-> max_conn;
 	wifi_config->ap.ssid_hidden = req->req_start_softap->ssid_hidden;
+	wifi_pmf_config_apply(WIFI_IF_AP, &wifi_config->ap.pmf_cfg);
 
 	ret = esp_wifi_get_mac(WIFI_IF_AP, mac);
 	if (ret) {
@@ -1281,6 +1320,7 @@ static esp_err_t req_get_ap_scan_list_handler (CtrlMsg *req,
 	}
 #endif
 
+	scan_config_apply(&scanConf, station_connected);
 	ret = esp_wifi_scan_start(&scanConf, true);
 	if (ret) {
 		ESP_LOGE(TAG,"Failed to start scan start command");
@@ -0,0 +1,192 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include "freertos/FreeRTOS.h"
+#include "freertos/task.h"
+#include "esp_log.h"
+#include "esp_wifi.h"
+#include "endian.h"
+#include "softap_acl.h"
+#include "softap_sta_mgmt.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define ETH_DST_MAC_OFFSET           0
+#define ETH_SRC_MAC_OFFSET           6
+#define ETH_HDR_MIN_LEN              14
+
+#ifndef MACSTR
+#define MAC2STR(a)                   (a)[0], (a)[1], (a)[2], (a)[3], (a)[4], (a)[5]
+#define MACSTR                       "%02x:%02x:%02x:%02x:%02x:%02x"
+#endif
+
+static const char *TAG = "softap_acl";
+
+static uint8_t acl_mode = CUSTOM_RPC_SOFTAP_ACL_OFF;
+static bool isolate;
+static uint8_t macs[CUSTOM_RPC_SOFTAP_ACL_MAX_MACS][CUSTOM_RPC_MAC_LEN];
+static uint8_t num_macs;
+static uint32_t rejected;
+static portMUX_TYPE acl_lock = portMUX_INITIALIZER_UNLOCKED;
+static TaskHandle_t kick_task_handle;
+
+/* Must be called with acl_lock held */
+static bool is_permitted(const uint8_t *mac)
+{
+	bool listed = false;
+
+	if (acl_mode == CUSTOM_RPC_SOFTAP_ACL_OFF)
+		return true;
+
+	for (int i = 0; i < num_macs && !listed; i++)
+		listed = !memcmp(macs[i], mac, CUSTOM_RPC_MAC_LEN);
+
+	return acl_mode == CUSTOM_RPC_SOFTAP_ACL_ALLOW ? listed : !listed;
+}
+
+static bool permitted(const uint8_t *mac)
+{
+	bool ret = false;
+
+	portENTER_CRITICAL(&acl_lock);
+	ret = is_permitted(mac);
+	if (!ret)
+		rejected++;
+	portEXIT_CRITICAL(&acl_lock);
+	return ret;
+}
+
+/* Kicks stations already associated but no longer permitted */
+static void kick_not_permitted(void)
+{
+	wifi_sta_list_t *sta_list = NULL;
+
+	sta_list = calloc(1, sizeof(wifi_sta_list_t));
+	if (!sta_list)
+		return;
+
+	if (esp_wifi_ap_get_sta_list(sta_list) == ESP_OK) {
+		for (int i = 0; i < sta_list->num; i++) {
+			if (!permitted(sta_list->sta[i].mac))
+				softap_sta_mgmt_kick(sta_list->sta[i].mac);
+		}
+	}
+	free(sta_list);
+}
+
+/* Deauth waits on Wi-Fi task, so done here instead of Rx callback */
+static void kick_task(void *arg)
+{
+	for (;;) {
+		ulTaskNotifyTake(pdTRUE, portMAX_DELAY);
+		kick_not_permitted();
+	}
+}
+
+esp_err_t softap_acl_init(void)
+{
+	if (xTaskCreate(kick_task, "softap_acl_kick", CONFIG_ESP_DEFAULT_TASK_STACK_SIZE,
+				NULL, CONFIG_ESP_HOSTED_TASK_PRIORITY_DEFAULT, &kick_task_handle) != pdTRUE) {
+		ESP_LOGE(TAG, "Failed to create kick task");
+		kick_task_handle = NULL;
+		return ESP_ERR_NO_MEM;
+	}
+	return ESP_OK;
+}
+
+esp_err_t softap_acl_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_softap_acl_t *acl = NULL;
+	size_t len = sizeof(custom_rpc_softap_acl_t) + sizeof(macs);
+
+	acl = calloc(1, len);
+	if (!acl) {
+		ESP_LOGE(TAG, "Failed to allocate memory for response");
+		return ESP_ERR_NO_MEM;
+	}
+
+	portENTER_CRITICAL(&acl_lock);
+	acl->mode = acl_mode;
+	acl->isolate = isolate;
+	acl->rejected = htole32(rejected);
+	acl->num = num_macs;
+	memcpy(acl->mac, macs, num_macs * CUSTOM_RPC_MAC_LEN);
+	portEXIT_CRITICAL(&acl_lock);
+
+	resp->data = (uint8_t *)acl;
+	resp->data_len = sizeof(custom_rpc_softap_acl_t) + acl->num * CUSTOM_RPC_MAC_LEN;
+	resp->free_func = free;
+	return ESP_OK;
+}
+
+esp_err_t softap_acl_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	const custom_rpc_softap_acl_t *acl = (const custom_rpc_softap_acl_t *)req->data;
+
+	if (!acl || req->data_len < sizeof(custom_rpc_softap_acl_t) ||
+	    acl->num > CUSTOM_RPC_SOFTAP_ACL_MAX_MACS ||
+	    req->data_len < sizeof(custom_rpc_softap_acl_t) + acl->num * CUSTOM_RPC_MAC_LEN ||
+	    acl->mode > CUSTOM_RPC_SOFTAP_ACL_DENY) {
+		ESP_LOGE(TAG, "Invalid set SoftAP ACL request");
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	portENTER_CRITICAL(&acl_lock);
+	acl_mode = acl->mode;
+	isolate = acl->isolate;
+	num_macs = acl->num;
+	memcpy(macs, acl->mac, acl->num * CUSTOM_RPC_MAC_LEN);
+	rejected = 0;
+	portEXIT_CRITICAL(&acl_lock);
+
+	ESP_LOGI(TAG, "mode %u with %u MACs, isolate %u", acl_mode, num_macs, isolate);
+	/* Repeated sets before task runs are kicked in one go */
+	if (kick_task_handle)
+		xTaskNotifyGive(kick_task_handle);
+	else
+		ESP_LOGW(TAG, "Kick task not running, joined stations kept till they rejoin");
+	return ESP_OK;
+}
+
+bool softap_acl_sta_connected(const uint8_t *mac, uint16_t aid)
+{
+	if (!mac || permitted(mac))
+		return true;
+
+	ESP_LOGW(TAG, "station "MACSTR" not permitted, deauth", MAC2STR(mac));
+	esp_wifi_deauth_sta(aid);
+	return false;
+}
+
+bool softap_acl_rx_allowed(const uint8_t *frame, uint16_t len)
+{
+	const uint8_t *dst = NULL;
+	bool ret = true;
+
+	if (!frame || len < ETH_HDR_MIN_LEN)
+		return true;
+
+	/* Checked per frame too, so nothing reaches host before deauth */
+	if (!permitted(frame + ETH_SRC_MAC_OFFSET))
+		return false;
+
+	/* Only frames ESP passes on to host are seen here. Multicast and
+	 * broadcast only go to host, ESP does not relay them */
+	dst = frame + ETH_DST_MAC_OFFSET;
+	if (!isolate || (dst[0] & 0x01))
+		return true;
+
+	if (softap_sta_mgmt_is_sta(dst)) {
+		portENTER_CRITICAL(&acl_lock);
+		rejected++;
+		portEXIT_CRITICAL(&acl_lock);
+		ret = false;
+	}
+	return ret;
+}
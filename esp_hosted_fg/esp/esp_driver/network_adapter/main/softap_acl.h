@@ -0,0 +1,34 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __SOFTAP_ACL_H__
+#define __SOFTAP_ACL_H__
+
+#include <stdint.h>
+#include <stdbool.h>
+#include "slave_control.h"
+
+/* Starts task kicking joined stations no longer permitted after ACL
+ * change. Call before custom RPC handler is registered */
+esp_err_t softap_acl_init(void);
+
+/* Custom RPC handlers for SoftAP access control */
+esp_err_t softap_acl_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t softap_acl_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+/* Called from softap event handler on station join. Deauthenticates
+ * station not permitted, and returns false for it */
+bool softap_acl_sta_connected(const uint8_t *mac, uint16_t aid);
+
+/* Called from data path with ethernet frame received on SoftAP.
+ * Returns false if frame must not be passed to host. Isolation drops
+ * only unicast from one station to another that goes through here,
+ * frames host sends back out on SoftAP are not checked */
+bool softap_acl_rx_allowed(const uint8_t *frame, uint16_t len);
+
+#endif
@@ -0,0 +1,211 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include "freertos/FreeRTOS.h"
+#include "esp_log.h"
+#include "esp_wifi.h"
+#include "esp_timer.h"
+#include "endian.h"
+#include "softap_sta_mgmt.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define ETH_DST_MAC_OFFSET           0
+#define ETH_SRC_MAC_OFFSET           6
+#define ETH_HDR_MIN_LEN              14
+
+#ifndef MACSTR
+#define MAC2STR(a)                   (a)[0], (a)[1], (a)[2], (a)[3], (a)[4], (a)[5]
+#define MACSTR                       "%02x:%02x:%02x:%02x:%02x:%02x"
+#endif
+
+static const char *TAG = "softap_sta";
+
+typedef struct {
+	bool in_use;
+	uint8_t mac[CUSTOM_RPC_MAC_LEN];
+	int64_t connect_time_us;
+	uint64_t rx_bytes;
+	uint64_t tx_bytes;
+} sta_entry_t;
+
+static sta_entry_t sta_table[CUSTOM_RPC_SOFTAP_MAX_STA];
+static portMUX_TYPE sta_table_lock = portMUX_INITIALIZER_UNLOCKED;
+
+/* Must be called with sta_table_lock held */
+static sta_entry_t *find_entry(const uint8_t *mac)
+{
+	for (int i = 0; i < CUSTOM_RPC_SOFTAP_MAX_STA; i++) {
+		if (sta_table[i].in_use &&
+		    !memcmp(sta_table[i].mac, mac, CUSTOM_RPC_MAC_LEN))
+			return &sta_table[i];
+	}
+	return NULL;
+}
+
+void softap_sta_mgmt_sta_connected(const uint8_t *mac)
+{
+	sta_entry_t *entry = NULL;
+
+	if (!mac)
+		return;
+
+	portENTER_CRITICAL(&sta_table_lock);
+	entry = find_entry(mac);
+	for (int i = 0; !entry && i < CUSTOM_RPC_SOFTAP_MAX_STA; i++) {
+		if (!sta_table[i].in_use)
+			entry = &sta_table[i];
+	}
+	if (entry) {
+		memset(entry, 0, sizeof(sta_entry_t));
+		entry->in_use = true;
+		memcpy(entry->mac, mac, CUSTOM_RPC_MAC_LEN);
+		entry->connect_time_us = esp_timer_get_time();
+	}
+	portEXIT_CRITICAL(&sta_table_lock);
+
+	if (!entry)
+		ESP_LOGW(TAG, "station table full, not tracking new station");
+}
+
+void softap_sta_mgmt_sta_disconnected(const uint8_t *mac)
+{
+	sta_entry_t *entry = NULL;
+
+	if (!mac)
+		return;
+
+	portENTER_CRITICAL(&sta_table_lock);
+	entry = find_entry(mac);
+	if (entry)
+		entry->in_use = false;
+	portEXIT_CRITICAL(&sta_table_lock);
+}
+
+void softap_sta_mgmt_reset(void)
+{
+	portENTER_CRITICAL(&sta_table_lock);
+	memset(sta_table, 0, sizeof(sta_table));
+	portEXIT_CRITICAL(&sta_table_lock);
+}
+
+void softap_sta_mgmt_account_rx(const uint8_t *frame, uint16_t len)
+{
+	sta_entry_t *entry = NULL;
+
+	if (!frame || len < ETH_HDR_MIN_LEN)
+		return;
+
+	portENTER_CRITICAL(&sta_table_lock);
+	entry = find_entry(frame + ETH_SRC_MAC_OFFSET);
+	if (entry)
+		entry->rx_bytes += len;
+	portEXIT_CRITICAL(&sta_table_lock);
+}
+
+void softap_sta_mgmt_account_tx(const uint8_t *frame, uint16_t len)
+{
+	sta_entry_t *entry = NULL;
+
+	if (!frame || len < ETH_HDR_MIN_LEN)
+		return;
+
+	portENTER_CRITICAL(&sta_table_lock);
+	entry = find_entry(frame + ETH_DST_MAC_OFFSET);
+	if (entry)
+		entry->tx_bytes += len;
+	portEXIT_CRITICAL(&sta_table_lock);
+}
+
+esp_err_t softap_sta_mgmt_get_details(uint8_t **out_data, size_t *out_len)
+{
+	wifi_sta_list_t *sta_list = NULL;
+	custom_rpc_softap_sta_list_t *resp = NULL;
+	sta_entry_t *entry = NULL;
+	int64_t now = esp_timer_get_time();
+	size_t len = 0;
+	esp_err_t ret = ESP_OK;
+
+	if (!out_data || !out_len)
+		return ESP_ERR_INVALID_ARG;
+
+	sta_list = calloc(1, sizeof(wifi_sta_list_t));
+	if (!sta_list)
+		return ESP_ERR_NO_MEM;
+
+	ret = esp_wifi_ap_get_sta_list(sta_list);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to get connected stations list: %d", ret);
+		free(sta_list);
+		return ret;
+	}
+
+	if (sta_list->num > CUSTOM_RPC_SOFTAP_MAX_STA)
+		sta_list->num = CUSTOM_RPC_SOFTAP_MAX_STA;
+
+	len = sizeof(custom_rpc_softap_sta_list_t) +
+		sta_list->num * sizeof(custom_rpc_softap_sta_info_t);
+	resp = calloc(1, len);
+	if (!resp) {
+		free(sta_list);
+		return ESP_ERR_NO_MEM;
+	}
+
+	resp->num = sta_list->num;
+	for (int i = 0; i < sta_list->num; i++) {
+		custom_rpc_softap_sta_info_t *info = &resp->sta[i];
+
+		memcpy(info->mac, sta_list->sta[i].mac, CUSTOM_RPC_MAC_LEN);
+		info->rssi = sta_list->sta[i].rssi;
+
+		portENTER_CRITICAL(&sta_table_lock);
+		entry = find_entry(sta_list->sta[i].mac);
+		if (entry) {
+			info->connected_secs = htole32((uint32_t)((now - entry->connect_time_us) / 1000000));
+			info->rx_bytes = htole64(entry->rx_bytes);
+			info->tx_bytes = htole64(entry->tx_bytes);
+		}
+		portEXIT_CRITICAL(&sta_table_lock);
+	}
+
+	free(sta_list);
+	*out_data = (uint8_t *)resp;
+	*out_len = len;
+	return ESP_OK;
+}
+
+bool softap_sta_mgmt_is_sta(const uint8_t *mac)
+{
+	bool ret = false;
+
+	if (!mac)
+		return false;
+
+	portENTER_CRITICAL(&sta_table_lock);
+	ret = find_entry(mac) != NULL;
+	portEXIT_CRITICAL(&sta_table_lock);
+	return ret;
+}
+
+esp_err_t softap_sta_mgmt_kick(const uint8_t *mac)
+{
+	uint16_t aid = 0;
+	esp_err_t ret = ESP_OK;
+
+	if (!mac)
+		return ESP_ERR_INVALID_ARG;
+
+	ret = esp_wifi_ap_get_sta_aid(mac, &aid);
+	if (ret || !aid) {
+		ESP_LOGE(TAG, "station "MACSTR" not associated", MAC2STR(mac));
+		return ESP_ERR_NOT_FOUND;
+	}
+
+	ESP_LOGI(TAG, "deauth station "MACSTR" aid %u", MAC2STR(mac), aid);
+	return esp_wifi_deauth_sta(aid);
+}
@@ -0,0 +1,33 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __SOFTAP_STA_MGMT_H__
+#define __SOFTAP_STA_MGMT_H__
+
+#include <stdint.h>
+#include <stddef.h>
+#include <stdbool.h>
+#include "esp_err.h"
+
+/* Station join/leave tracking, called from softap event handler */
+void softap_sta_mgmt_sta_connected(const uint8_t *mac);
+void softap_sta_mgmt_sta_disconnected(const uint8_t *mac);
+void softap_sta_mgmt_reset(void);
+
+/* Per station byte accounting, called from data path with ethernet frame */
+void softap_sta_mgmt_account_rx(const uint8_t *frame, uint16_t len);
+void softap_sta_mgmt_account_tx(const uint8_t *frame, uint16_t len);
+
+/* Builds custom_rpc_softap_sta_list_t. Caller frees *out_data */
+esp_err_t softap_sta_mgmt_get_details(uint8_t **out_data, size_t *out_len);
+
+/* True if MAC is a station associated to SoftAP, from data path */
+bool softap_sta_mgmt_is_sta(const uint8_t *mac);
+
+/* Deauthenticates station with given MAC */
+esp_err_t softap_sta_mgmt_kick(const uint8_t *mac);
+
+#endif
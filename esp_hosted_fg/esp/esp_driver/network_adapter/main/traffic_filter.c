@@ -0,0 +1,217 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include "freertos/FreeRTOS.h"
+#include "esp_log.h"
+#include "endian.h"
+#include "traffic_filter.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define ETH_HDR_LEN                  14
+#define ETH_TYPE_OFFSET              12
+#define ETH_TYPE_IPV4                0x0800
+#define ETH_TYPE_IPV6                0x86DD
+#define IPV4_PROTO_OFFSET            9
+#define IPV4_MIN_IHL                 5
+#define IPV4_MIN_HDR_LEN             (IPV4_MIN_IHL * 4)
+#define IPV6_NEXT_HDR_OFFSET         6
+#define IPV6_HDR_LEN                 40
+#define TCP_HDR_LEN                  20
+#define UDP_HDR_LEN                  8
+
+static const char *TAG = "traffic_filter";
+
+static custom_rpc_filter_rule_t rules[CUSTOM_RPC_FILTER_MAX_RULES];
+static uint8_t num_rules;
+static uint8_t default_action = CUSTOM_RPC_FILTER_ACTION_FORWARD;
+static bool has_wake_rule;
+static uint32_t dropped;
+static portMUX_TYPE filter_lock = portMUX_INITIALIZER_UNLOCKED;
+
+typedef struct {
+	bool multicast;
+	uint8_t proto;
+	uint16_t port;
+} frame_info_t;
+
+static size_t l4_hdr_len(uint8_t proto)
+{
+	if (proto == CUSTOM_RPC_FILTER_PROTO_TCP)
+		return TCP_HDR_LEN;
+	if (proto == CUSTOM_RPC_FILTER_PROTO_UDP)
+		return UDP_HDR_LEN;
+	return 0;
+}
+
+/* false if IP or TCP/UDP header is malformed or runs past len */
+static bool parse_frame(const uint8_t *frame, uint16_t len, frame_info_t *info)
+{
+	uint16_t eth_type = 0;
+	size_t l4_offset = 0;
+	uint8_t ihl = 0;
+
+	memset(info, 0, sizeof(*info));
+	if (len < ETH_HDR_LEN)
+		return false;
+
+	/* Group bit of destination MAC covers broadcast too */
+	info->multicast = frame[0] & 0x01;
+	eth_type = (frame[ETH_TYPE_OFFSET] << 8) | frame[ETH_TYPE_OFFSET + 1];
+
+	if (eth_type == ETH_TYPE_IPV4) {
+		if (len < ETH_HDR_LEN + IPV4_MIN_HDR_LEN)
+			return false;
+		ihl = frame[ETH_HDR_LEN] & 0x0F;
+		if (ihl < IPV4_MIN_IHL)
+			return false;
+		info->proto = frame[ETH_HDR_LEN + IPV4_PROTO_OFFSET];
+		l4_offset = ETH_HDR_LEN + ihl * 4;
+	} else if (eth_type == ETH_TYPE_IPV6) {
+		if (len < ETH_HDR_LEN + IPV6_HDR_LEN)
+			return false;
+		/* Extension headers are not walked */
+		info->proto = frame[ETH_HDR_LEN + IPV6_NEXT_HDR_OFFSET];
+		l4_offset = ETH_HDR_LEN + IPV6_HDR_LEN;
+	} else {
+		return true;
+	}
+
+	if (l4_offset + l4_hdr_len(info->proto) > len)
+		return false;
+
+	if (info->proto == CUSTOM_RPC_FILTER_PROTO_TCP || info->proto == CUSTOM_RPC_FILTER_PROTO_UDP)
+		info->port = (frame[l4_offset + 2] << 8) | frame[l4_offset + 3];
+	return true;
+}
+
+static bool rule_matches(const custom_rpc_filter_rule_t *rule, const frame_info_t *info)
+{
+	if (rule->multicast && !info->multicast)
+		return false;
+	if (rule->proto != CUSTOM_RPC_FILTER_PROTO_ANY && rule->proto != info->proto)
+		return false;
+	if (rule->port && rule->port != info->port)
+		return false;
+	return true;
+}
+
+static uint8_t get_action(const uint8_t *frame, uint16_t len)
+{
+	frame_info_t info = {0};
+	uint8_t action = CUSTOM_RPC_FILTER_ACTION_FORWARD;
+
+	/* Malformed frames pass through unfiltered */
+	if (!parse_frame(frame, len, &info))
+		return CUSTOM_RPC_FILTER_ACTION_FORWARD;
+
+	portENTER_CRITICAL(&filter_lock);
+	action = default_action;
+	for (int i = 0; i < num_rules; i++) {
+		if (rule_matches(&rules[i], &info)) {
+			action = rules[i].action;
+			break;
+		}
+	}
+	portEXIT_CRITICAL(&filter_lock);
+
+	return action;
+}
+
+uint8_t traffic_filter_check(const uint8_t *frame, uint16_t len)
+{
+	uint8_t action = CUSTOM_RPC_FILTER_ACTION_FORWARD;
+
+	if (!num_rules && default_action == CUSTOM_RPC_FILTER_ACTION_FORWARD)
+		return action;
+
+	action = get_action(frame, len);
+	if (action == CUSTOM_RPC_FILTER_ACTION_DROP) {
+		portENTER_CRITICAL(&filter_lock);
+		dropped++;
+		portEXIT_CRITICAL(&filter_lock);
+	}
+	return action;
+}
+
+bool traffic_filter_wakeup_needed(const uint8_t *frame, uint16_t len)
+{
+	if (!has_wake_rule)
+		return true;
+	return get_action(frame, len) == CUSTOM_RPC_FILTER_ACTION_WAKE;
+}
+
+esp_err_t traffic_filter_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	const custom_rpc_traffic_filter_t *cfg = (const custom_rpc_traffic_filter_t *)req->data;
+	custom_rpc_filter_rule_t new_rules[CUSTOM_RPC_FILTER_MAX_RULES] = {0};
+	bool wake = false;
+
+	if (!req->data || req->data_len < sizeof(custom_rpc_traffic_filter_t) ||
+	    cfg->num > CUSTOM_RPC_FILTER_MAX_RULES ||
+	    req->data_len < sizeof(custom_rpc_traffic_filter_t) + cfg->num * sizeof(custom_rpc_filter_rule_t)) {
+		ESP_LOGE(TAG, "Invalid set traffic filter request");
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	if (cfg->default_action > CUSTOM_RPC_FILTER_ACTION_WAKE) {
+		ESP_LOGE(TAG, "Invalid default action %u", cfg->default_action);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	memcpy(new_rules, cfg->rule, cfg->num * sizeof(custom_rpc_filter_rule_t));
+	for (int i = 0; i < cfg->num; i++) {
+		if (new_rules[i].action > CUSTOM_RPC_FILTER_ACTION_WAKE) {
+			ESP_LOGE(TAG, "Invalid action %u in rule %d", new_rules[i].action, i);
+			return ESP_ERR_INVALID_ARG;
+		}
+		new_rules[i].port = le16toh(new_rules[i].port);
+		wake |= new_rules[i].action == CUSTOM_RPC_FILTER_ACTION_WAKE;
+	}
+	wake |= cfg->default_action == CUSTOM_RPC_FILTER_ACTION_WAKE;
+
+	portENTER_CRITICAL(&filter_lock);
+	memcpy(rules, new_rules, sizeof(rules));
+	num_rules = cfg->num;
+	default_action = cfg->default_action;
+	has_wake_rule = wake;
+	dropped = 0;
+	portEXIT_CRITICAL(&filter_lock);
+
+	ESP_LOGI(TAG, "Traffic filter set: %u rule(s), default action %u", cfg->num, cfg->default_action);
+	return ESP_OK;
+}
+
+esp_err_t traffic_filter_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_traffic_filter_t *cfg = NULL;
+	size_t len = sizeof(custom_rpc_traffic_filter_t) +
+		CUSTOM_RPC_FILTER_MAX_RULES * sizeof(custom_rpc_filter_rule_t);
+
+	cfg = calloc(1, len);
+	if (!cfg) {
+		ESP_LOGE(TAG, "Failed to allocate memory for response");
+		return ESP_ERR_NO_MEM;
+	}
+
+	portENTER_CRITICAL(&filter_lock);
+	cfg->default_action = default_action;
+	cfg->dropped = htole32(dropped);
+	cfg->num = num_rules;
+	memcpy(cfg->rule, rules, num_rules * sizeof(custom_rpc_filter_rule_t));
+	portEXIT_CRITICAL(&filter_lock);
+
+	for (int i = 0; i < cfg->num; i++)
+		cfg->rule[i].port = htole16(cfg->rule[i].port);
+
+	resp->data = (uint8_t *)cfg;
+	resp->data_len = sizeof(custom_rpc_traffic_filter_t) + cfg->num * sizeof(custom_rpc_filter_rule_t);
+	resp->free_func = free;
+	return ESP_OK;
+}
@@ -0,0 +1,28 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __TRAFFIC_FILTER_H__
+#define __TRAFFIC_FILTER_H__
+
+#include <stdint.h>
+#include <stdbool.h>
+#include "slave_control.h"
+
+/* Custom RPC handlers to set/get filter of frames received by station */
+esp_err_t traffic_filter_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t traffic_filter_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+/* Returns CUSTOM_RPC_FILTER_ACTION_* for ethernet frame.
+ * Called from Wi-Fi rx path, so kept short */
+uint8_t traffic_filter_check(const uint8_t *frame, uint16_t len);
+
+/* True if frame should wake sleeping host. Without any wake rule, every
+ * forwarded frame does, as before */
+bool traffic_filter_wakeup_needed(const uint8_t *frame, uint16_t len);
+
+#endif
@@ -0,0 +1,136 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include "freertos/FreeRTOS.h"
+#include "esp_log.h"
+#include "esp_wifi.h"
+#include "esp_timer.h"
+#include "vendor_ie_monitor.h"
+#include "esp_hosted_coprocessor.h"
+#include "esp_hosted_custom_rpc.h"
+
+/* Same device keeps sending same IE in every beacon, so only report
+ * an (sa, type) pair again once this interval has passed */
+#define REPORT_INTERVAL_US           (5 * 1000 * 1000)
+#define REPORT_CACHE_SIZE            16
+#define VENDOR_IE_HDR_LEN            2
+
+static const char *TAG = "vendor_ie";
+
+typedef struct {
+	bool in_use;
+	uint8_t sa[CUSTOM_RPC_MAC_LEN];
+	uint8_t type;
+	int64_t last_report_us;
+} report_entry_t;
+
+static bool monitor_enabled;
+static uint8_t monitor_oui[CUSTOM_RPC_VENDOR_OUI_LEN];
+static report_entry_t report_cache[REPORT_CACHE_SIZE];
+static portMUX_TYPE monitor_lock = portMUX_INITIALIZER_UNLOCKED;
+
+/* Returns true if this (sa, type) was not reported recently.
+ * Must be called with monitor_lock held */
+static bool should_report(const uint8_t *sa, uint8_t type, int64_t now)
+{
+	report_entry_t *oldest = &report_cache[0];
+
+	for (int i = 0; i < REPORT_CACHE_SIZE; i++) {
+		report_entry_t *entry = &report_cache[i];
+
+		if (entry->in_use && entry->type == type &&
+		    !memcmp(entry->sa, sa, CUSTOM_RPC_MAC_LEN)) {
+			if (now - entry->last_report_us < REPORT_INTERVAL_US)
+				return false;
+			entry->last_report_us = now;
+			return true;
+		}
+		if (!entry->in_use ||
+		    (oldest->in_use && entry->last_report_us < oldest->last_report_us))
+			oldest = entry;
+	}
+
+	oldest->in_use = true;
+	oldest->type = type;
+	memcpy(oldest->sa, sa, CUSTOM_RPC_MAC_LEN);
+	oldest->last_report_us = now;
+	return true;
+}
+
+static void vendor_ie_cb(void *ctx, wifi_vendor_ie_type_t type, const uint8_t sa[6],
+		const vendor_ie_data_t *vnd_ie, int rssi)
+{
+	custom_rpc_vendor_ie_event_t *event = NULL;
+	size_t ie_len = 0;
+	bool report = false;
+
+	if (!vnd_ie || !sa)
+		return;
+
+	portENTER_CRITICAL(&monitor_lock);
+	if (monitor_enabled &&
+	    !memcmp(vnd_ie->vendor_oui, monitor_oui, CUSTOM_RPC_VENDOR_OUI_LEN))
+		report = should_report(sa, type, esp_timer_get_time());
+	portEXIT_CRITICAL(&monitor_lock);
+
+	if (!report)
+		return;
+
+	ie_len = VENDOR_IE_HDR_LEN + vnd_ie->length;
+	event = malloc(sizeof(custom_rpc_vendor_ie_event_t) + ie_len);
+	if (!event) {
+		ESP_LOGE(TAG, "Failed to allocate memory for vendor IE event");
+		return;
+	}
+
+	event->type = type;
+	memcpy(event->sa, sa, CUSTOM_RPC_MAC_LEN);
+	event->rssi = rssi;
+	event->ie_len = ie_len;
+	memcpy(event->ie, vnd_ie, ie_len);
+
+	create_and_send_custom_rpc_unserialised_event(CUSTOM_RPC_EVENT_ID__VENDOR_IE_RECEIVED,
+			event, sizeof(custom_rpc_vendor_ie_event_t) + ie_len);
+	free(event);
+}
+
+esp_err_t vendor_ie_monitor_config(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_vendor_ie_monitor_t cfg = {0};
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < sizeof(cfg)) {
+		ESP_LOGE(TAG, "Invalid vendor IE monitor request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&cfg, req->data, sizeof(cfg));
+
+	portENTER_CRITICAL(&monitor_lock);
+	monitor_enabled = cfg.enable;
+	memcpy(monitor_oui, cfg.oui, CUSTOM_RPC_VENDOR_OUI_LEN);
+	memset(report_cache, 0, sizeof(report_cache));
+	portEXIT_CRITICAL(&monitor_lock);
+
+	if (cfg.enable)
+		ret = esp_wifi_set_vendor_ie_cb(vendor_ie_cb, NULL);
+	else
+		ret = esp_wifi_set_vendor_ie_cb(NULL, NULL);
+
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to %s vendor IE callback: %d",
+				cfg.enable ? "register" : "unregister", ret);
+		return ret;
+	}
+
+	ESP_LOGI(TAG, "vendor IE monitor %s for OUI %02x:%02x:%02x",
+			cfg.enable ? "enabled" : "disabled",
+			cfg.oui[0], cfg.oui[1], cfg.oui[2]);
+	return ESP_OK;
+}
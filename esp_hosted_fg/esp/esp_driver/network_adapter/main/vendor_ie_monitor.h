@@ -0,0 +1,16 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __VENDOR_IE_MONITOR_H__
+#define __VENDOR_IE_MONITOR_H__
+
+#include "slave_control.h"
+
+/* Enables or disables reporting of received vendor IEs to host */
+esp_err_t vendor_ie_monitor_config(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
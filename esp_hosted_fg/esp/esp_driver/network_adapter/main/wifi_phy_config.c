@@ -0,0 +1,142 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include "esp_log.h"
+#include "esp_wifi.h"
+#include "wifi_phy_config.h"
+#include "esp_hosted_custom_rpc.h"
+
+#define ALL_PROTOCOLS (CUSTOM_RPC_WIFI_PROTOCOL_11B | CUSTOM_RPC_WIFI_PROTOCOL_11G | \
+		CUSTOM_RPC_WIFI_PROTOCOL_11N | CUSTOM_RPC_WIFI_PROTOCOL_LR)
+
+static const char *TAG = "wifi_phy";
+
+static bool is_iface_valid(uint8_t iface)
+{
+	return (iface == CUSTOM_RPC_WIFI_IF_STA || iface == CUSTOM_RPC_WIFI_IF_AP);
+}
+
+/* Allocates response of given size and fills it from src */
+static esp_err_t fill_resp(custom_rpc_unserialised_data_t *resp, const void *src, size_t len)
+{
+	resp->data = malloc(len);
+	if (!resp->data) {
+		ESP_LOGE(TAG, "Failed to allocate memory for response");
+		return ESP_ERR_NO_MEM;
+	}
+	memcpy(resp->data, src, len);
+	resp->data_len = len;
+	resp->free_func = free;
+	return ESP_OK;
+}
+
+esp_err_t wifi_phy_config_get_protocol(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_wifi_protocol_t proto = {0};
+	uint8_t bitmap = 0;
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < 1 || !is_iface_valid(req->data[0])) {
+		ESP_LOGE(TAG, "Invalid interface in get protocol request");
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	proto.iface = req->data[0];
+	ret = esp_wifi_get_protocol((wifi_interface_t)proto.iface, &bitmap);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to get protocol of iface %u: %d", proto.iface, ret);
+		return ret;
+	}
+	proto.protocol = bitmap;
+
+	return fill_resp(resp, &proto, sizeof(proto));
+}
+
+esp_err_t wifi_phy_config_set_protocol(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_wifi_protocol_t proto = {0};
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < sizeof(proto)) {
+		ESP_LOGE(TAG, "Invalid set protocol request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&proto, req->data, sizeof(proto));
+
+	if (!is_iface_valid(proto.iface) || !proto.protocol ||
+	    (proto.protocol & ~ALL_PROTOCOLS)) {
+		ESP_LOGE(TAG, "Invalid iface %u or protocol 0x%x", proto.iface, proto.protocol);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	ret = esp_wifi_set_protocol((wifi_interface_t)proto.iface, proto.protocol);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to set protocol 0x%x on iface %u: %d",
+				proto.protocol, proto.iface, ret);
+		return ret;
+	}
+
+	ESP_LOGI(TAG, "iface %u protocol set to 0x%x", proto.iface, proto.protocol);
+	return ESP_OK;
+}
+
+esp_err_t wifi_phy_config_get_bandwidth(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_wifi_bandwidth_t bw_cfg = {0};
+	wifi_bandwidth_t bw = 0;
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < 1 || !is_iface_valid(req->data[0])) {
+		ESP_LOGE(TAG, "Invalid interface in get bandwidth request");
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	bw_cfg.iface = req->data[0];
+	ret = esp_wifi_get_bandwidth((wifi_interface_t)bw_cfg.iface, &bw);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to get bandwidth of iface %u: %d", bw_cfg.iface, ret);
+		return ret;
+	}
+	bw_cfg.bandwidth = bw;
+
+	return fill_resp(resp, &bw_cfg, sizeof(bw_cfg));
+}
+
+esp_err_t wifi_phy_config_set_bandwidth(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_wifi_bandwidth_t bw_cfg = {0};
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < sizeof(bw_cfg)) {
+		ESP_LOGE(TAG, "Invalid set bandwidth request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&bw_cfg, req->data, sizeof(bw_cfg));
+
+	if (!is_iface_valid(bw_cfg.iface) ||
+	    (bw_cfg.bandwidth != CUSTOM_RPC_WIFI_BW_HT20 &&
+	     bw_cfg.bandwidth != CUSTOM_RPC_WIFI_BW_HT40)) {
+		ESP_LOGE(TAG, "Invalid iface %u or bandwidth %u", bw_cfg.iface, bw_cfg.bandwidth);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	ret = esp_wifi_set_bandwidth((wifi_interface_t)bw_cfg.iface, (wifi_bandwidth_t)bw_cfg.bandwidth);
+	if (ret) {
+		ESP_LOGE(TAG, "Failed to set bandwidth %u on iface %u: %d",
+				bw_cfg.bandwidth, bw_cfg.iface, ret);
+		return ret;
+	}
+
+	ESP_LOGI(TAG, "iface %u bandwidth set to %s", bw_cfg.iface,
+			bw_cfg.bandwidth == CUSTOM_RPC_WIFI_BW_HT40 ? "HT40" : "HT20");
+	return ESP_OK;
+}
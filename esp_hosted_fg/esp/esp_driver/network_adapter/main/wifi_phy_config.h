@@ -0,0 +1,22 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __WIFI_PHY_CONFIG_H__
+#define __WIFI_PHY_CONFIG_H__
+
+#include "slave_control.h"
+
+/* Custom RPC handlers for Wi-Fi PHY settings */
+esp_err_t wifi_phy_config_get_protocol(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t wifi_phy_config_set_protocol(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t wifi_phy_config_get_bandwidth(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t wifi_phy_config_set_bandwidth(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+#endif
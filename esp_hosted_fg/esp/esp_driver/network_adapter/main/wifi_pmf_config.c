@@ -0,0 +1,106 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include "esp_log.h"
+#include "wifi_pmf_config.h"
+#include "esp_hosted_custom_rpc.h"
+
+static const char *TAG = "wifi_pmf";
+
+typedef struct {
+	bool configured;
+	bool capable;
+	bool required;
+} pmf_setting_t;
+
+/* Indexed by CUSTOM_RPC_WIFI_IF_STA / CUSTOM_RPC_WIFI_IF_AP */
+static pmf_setting_t pmf_settings[2];
+
+static bool is_iface_valid(uint8_t iface)
+{
+	return (iface == CUSTOM_RPC_WIFI_IF_STA || iface == CUSTOM_RPC_WIFI_IF_AP);
+}
+
+esp_err_t wifi_pmf_config_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_pmf_config_t pmf = {0};
+	wifi_config_t wifi_cfg = {0};
+	esp_err_t ret = ESP_OK;
+
+	if (!req->data || req->data_len < 1 || !is_iface_valid(req->data[0])) {
+		ESP_LOGE(TAG, "Invalid interface in get PMF request");
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	pmf.iface = req->data[0];
+	if (pmf_settings[pmf.iface].configured) {
+		pmf.capable = pmf_settings[pmf.iface].capable;
+		pmf.required = pmf_settings[pmf.iface].required;
+	} else {
+		/* Not set by host, report what driver currently uses */
+		ret = esp_wifi_get_config((wifi_interface_t)pmf.iface, &wifi_cfg);
+		if (ret) {
+			ESP_LOGE(TAG, "Failed to get config of iface %u: %d", pmf.iface, ret);
+			return ret;
+		}
+		if (pmf.iface == CUSTOM_RPC_WIFI_IF_STA) {
+			pmf.capable = wifi_cfg.sta.pmf_cfg.capable;
+			pmf.required = wifi_cfg.sta.pmf_cfg.required;
+		} else {
+			pmf.capable = wifi_cfg.ap.pmf_cfg.capable;
+			pmf.required = wifi_cfg.ap.pmf_cfg.required;
+		}
+	}
+
+	resp->data = malloc(sizeof(pmf));
+	if (!resp->data) {
+		ESP_LOGE(TAG, "Failed to allocate memory for response");
+		return ESP_ERR_NO_MEM;
+	}
+	memcpy(resp->data, &pmf, sizeof(pmf));
+	resp->data_len = sizeof(pmf);
+	resp->free_func = free;
+	return ESP_OK;
+}
+
+esp_err_t wifi_pmf_config_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp)
+{
+	custom_rpc_pmf_config_t pmf = {0};
+
+	if (!req->data || req->data_len < sizeof(pmf)) {
+		ESP_LOGE(TAG, "Invalid set PMF request");
+		return ESP_ERR_INVALID_ARG;
+	}
+	memcpy(&pmf, req->data, sizeof(pmf));
+
+	if (!is_iface_valid(pmf.iface)) {
+		ESP_LOGE(TAG, "Invalid iface %u", pmf.iface);
+		return ESP_ERR_INVALID_ARG;
+	}
+
+	pmf_settings[pmf.iface].configured = true;
+	pmf_settings[pmf.iface].required = pmf.required;
+	pmf_settings[pmf.iface].capable = pmf.capable || pmf.required;
+
+	ESP_LOGI(TAG, "iface %u PMF capable %u required %u, effective from next %s",
+			pmf.iface, pmf_settings[pmf.iface].capable, pmf.required,
+			pmf.iface == CUSTOM_RPC_WIFI_IF_STA ? "connect" : "softap start");
+	return ESP_OK;
+}
+
+void wifi_pmf_config_apply(wifi_interface_t iface, wifi_pmf_config_t *pmf_cfg)
+{
+	if (!pmf_cfg || !is_iface_valid(iface) || !pmf_settings[iface].configured)
+		return;
+
+	pmf_cfg->capable = pmf_settings[iface].capable;
+	pmf_cfg->required = pmf_settings[iface].required;
+}
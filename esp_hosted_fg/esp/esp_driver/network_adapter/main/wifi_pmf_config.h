@@ -0,0 +1,23 @@
+/*
+ * SPDX-FileCopyrightText: 2025 Espressif Systems (Shanghai) CO LTD
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#ifndef __WIFI_PMF_CONFIG_H__
+#define __WIFI_PMF_CONFIG_H__
+
+#include "esp_wifi.h"
+#include "slave_control.h"
+
+/* Custom RPC handlers for PMF settings */
+esp_err_t wifi_pmf_config_get(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+esp_err_t wifi_pmf_config_set(const custom_rpc_unserialised_data_t *req,
+		custom_rpc_unserialised_data_t *resp);
+
+/* Overrides pmf_cfg with host configured setting of iface, if any.
+ * Called while building station/softAP config */
+void wifi_pmf_config_apply(wifi_interface_t iface, wifi_pmf_config_t *pmf_cfg);
+
+#endif
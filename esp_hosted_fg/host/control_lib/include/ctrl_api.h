@@ -79,9 +79,15 @@ enum {
 	CTRL_ERR_TRANSPORT_SEND,
 	CTRL_ERR_REQUEST_TIMEOUT,
 	CTRL_ERR_REQ_IN_PROG,
+	CTRL_ERR_RATE_LIMITED,
+	CTRL_ERR_FRAME_TOO_LARGE,
 	OUT_OF_RANGE
 };
 
+/* ESP-IDF error passed as is by ESP in connect response, when requested
+ * security (e.g. WPA3-SAE) is not enabled in ESP firmware */
+#define CTRL_ERR_ESP_NOT_SUPPORTED           0x106
+
 
 typedef enum {
 
@@ -500,6 +506,66 @@ typedef int (*ctrl_resp_cb_t) (ctrl_cmd_t * resp);
 /* event callback */
 typedef int (*ctrl_event_cb_t) (ctrl_cmd_t * event);
 
+/* Reasons for dropping a message received from ESP32 */
+typedef enum {
+	CTRL_RX_UNEXPECTED_MALFORMED = 1, /* TLV framing or protobuf decode failed */
+	CTRL_RX_UNEXPECTED_MSG_TYPE,      /* Neither response nor event */
+	CTRL_RX_UNEXPECTED_MSG_ID,        /* Response or event unknown to this host */
+	CTRL_RX_UNEXPECTED_STALE_RESP,    /* Response uid not of pending request */
+} ctrl_rx_unexpected_e;
+
+/* Count of messages from ESP32 dropped, per `ctrl_rx_unexpected_e` */
+typedef struct {
+	uint32_t malformed;
+	uint32_t unknown_msg_type;
+	uint32_t unknown_msg_id;
+	uint32_t stale_resp;
+} ctrl_rx_stats_t;
+
+/* Health of control link to ESP32, see `get_ctrl_link_status` */
+typedef enum {
+	CTRL_LINK_HEALTH_OK = 1,           /* ESP32 responds, station connected to AP */
+	CTRL_LINK_HEALTH_WIFI_DOWN,        /* ESP32 responds, station not connected */
+	CTRL_LINK_HEALTH_ESP_UNRESPONSIVE, /* Requests time out or heartbeats stopped */
+} ctrl_link_health_e;
+
+typedef struct {
+	int health;                    /* `ctrl_link_health_e` */
+	uint32_t consecutive_timeouts; /* Requests timed out since last message from ESP32 */
+	int32_t last_rx_age_sec;       /* Seconds since last message from ESP32, -1 if none */
+	uint32_t heartbeat_duration;   /* Heartbeat interval in sec, 0 if heartbeat is off */
+	uint8_t sta_connected;
+} ctrl_link_status_t;
+
+#define CTRL_EVENT_QUEUE_MAX_DEPTH 256
+
+/* Event queue overflow policy, see `set_ctrl_event_queue` */
+typedef enum {
+	CTRL_EVENT_OVERFLOW_DROP_OLDEST = 1, /* Drop oldest queued event */
+	CTRL_EVENT_OVERFLOW_DROP_NEWEST,     /* Drop event just received */
+	CTRL_EVENT_OVERFLOW_BLOCK,           /* Stall rx until callbacks catch up */
+} ctrl_event_overflow_e;
+
+typedef struct {
+	uint16_t depth;          /* 0 if events are not queued */
+	uint16_t queued;         /* Events waiting for callback now */
+	uint16_t high_watermark; /* Most events ever waiting at once */
+	uint32_t dropped;        /* Events dropped due to overflow */
+} ctrl_event_queue_stats_t;
+
+/* Control request rate limit and requests held back by it, see
+ * `set_ctrl_req_rate_limit` */
+typedef struct {
+	uint16_t rate_per_sec;   /* 0 if requests are not limited */
+	uint16_t burst;          /* Requests allowed back to back */
+	uint32_t max_delay_ms;   /* Longest a request waits for its turn */
+	uint32_t delayed;        /* Requests held back until within rate */
+	uint32_t dropped;        /* Requests failed with CTRL_ERR_RATE_LIMITED */
+} ctrl_req_rate_stats_t;
+
+/* unexpected rx callback, msg_id is 0 when not decoded */
+typedef void (*ctrl_rx_unexpected_cb_t) (int reason, uint32_t msg_id);
+
 
 /*---- Control API Function ----*/
 
@@ -555,6 +621,99 @@ ctrl_resp_cb_t get_event_callback(int event);
  **/
 int reset_event_callback(int event);
 
+/* Set unexpected rx callback (strict mode)
+ *
+ * Messages from ESP32 which host cannot make sense of, e.g. due to
+ * firmware and host versions drifting apart, are dropped. They are
+ * always counted, see `get_ctrl_rx_stats`.
+ * With callback set, each is also reported as it is dropped.
+ * Callback runs in control path rx thread, so it should return quickly
+ *
+ * Inputs:
+ * > rx_cb - NULL - resets callback
+ *           Function pointer - Registers callback
+ **/
+void set_ctrl_rx_unexpected_callback(ctrl_rx_unexpected_cb_t rx_cb);
+
+/* Get count of messages from ESP32 dropped since library init
+ *
+ * Returns:
+ * > SUCCESS - 0
+ * > FAILURE - -1, if stats is NULL
+ **/
+int get_ctrl_rx_stats(ctrl_rx_stats_t *stats);
+
+/* Queue events for callbacks instead of calling them from rx thread
+ *
+ * By default event callbacks run in control path rx thread, so a slow
+ * callback delays responses too, and a blocked one makes every request
+ * time out. With queue, callbacks run in their own thread and up to
+ * depth events wait for it. When queue is full, `overflow_policy`
+ * decides which event is dropped, or whether rx waits.
+ * ESP init and station disconnect events are never dropped: another
+ * event is dropped in their place, or rx waits if there is none.
+ * Can be called before init. Depth can be set once per process, later
+ * calls can only change overflow policy
+ *
+ * Inputs:
+ * > depth - Number of events queue holds, 1 to CTRL_EVENT_QUEUE_MAX_DEPTH
+ * > overflow_policy - `ctrl_event_overflow_e`
+ *
+ * Returns:
+ * > SUCCESS - 0
+ * > FAILURE - -1, on invalid input or failure to create queue
+ **/
+int set_ctrl_event_queue(uint16_t depth, int overflow_policy);
+
+/* Get event queue state and overflow count since queue was set
+ *
+ * Returns:
+ * > SUCCESS - 0
+ * > FAILURE - -1, if stats is NULL
+ **/
+int get_ctrl_event_queue_stats(ctrl_event_queue_stats_t *stats);
+
+/* Get health of control link to ESP32
+ *
+ * Tells "ESP32 alive but Wi-Fi down" from "ESP32 dead or serial broken",
+ * to pick remediation, e.g. rescan vs. reset ESP32.
+ * ESP32 is taken as unresponsive after 2 consecutive request timeouts
+ * with nothing received in between, or, with heartbeat enabled, after
+ * no message for 3 heartbeat intervals
+ *
+ * Returns:
+ * > SUCCESS - 0
+ * > FAILURE - -1, if status is NULL
+ **/
+int get_ctrl_link_status(ctrl_link_status_t *status);
+
+/* Limit rate of control requests sent to ESP32
+ *
+ * Token bucket: up to `burst` requests go back to back, then one per
+ * 1/`rate_per_sec` second. Request over rate waits for its turn, or fails
+ * with CTRL_ERR_RATE_LIMITED if that is more than `max_delay_ms` away.
+ * Keeps a caller polling in a tight loop from hogging the bus shared
+ * with network traffic. Can be called before init, counts are reset
+ *
+ * Inputs:
+ * > rate_per_sec - Sustained requests per second, 0 to remove limit
+ * > burst - Requests allowed back to back, at least 1
+ * > max_delay_ms - Longest request is held back, 0 to fail at once
+ *
+ * Returns:
+ * > SUCCESS - 0
+ * > FAILURE - -1, on invalid input
+ **/
+int set_ctrl_req_rate_limit(uint16_t rate_per_sec, uint16_t burst, uint32_t max_delay_ms);
+
+/* Get rate limit and count of requests delayed or dropped by it
+ *
+ * Returns:
+ * > SUCCESS - 0
+ * > FAILURE - -1, if stats is NULL
+ **/
+int get_ctrl_req_rate_stats(ctrl_req_rate_stats_t *stats);
+
 
 /* Initialize hosted control library
  *
@@ -9,6 +9,7 @@
 #include "platform_wrapper.h"
 #include "esp_queue.h"
 #include <unistd.h>
+#include <time.h>
 
 #ifdef MCU_SYS
 #include "common.h"
@@ -29,6 +30,9 @@
 #define MAX_PWD_LENGTH               64
 #define STATUS_LENGTH                14
 #define TIMEOUT_PSERIAL_RESP         30
+/* See `get_ctrl_link_status` */
+#define CTRL_LINK_MAX_TIMEOUTS       2
+#define CTRL_LINK_MISSED_HEARTBEATS  3
 #define MIN_CHNL_NO                  1
 #define MAX_CHNL_NO                  11
 #define MIN_CONN_NO                  1
@@ -98,6 +102,7 @@ static void * async_timer_handle;
 static struct ctrl_lib_context ctrl_lib_ctxt;
 
 static int call_event_callback(ctrl_cmd_t *app_event);
+static void dispatch_event(ctrl_cmd_t *app_event);
 static int is_async_resp_callback_registered_by_resp_msg_id(int resp_msg_id);
 static int call_async_resp_callback(ctrl_cmd_t *app_resp);
 
@@ -109,6 +114,8 @@ static int32_t uid = 0;
  * -1 means not a valid id
  *  0 means slave fw was not updated to support UIDs */
 static int32_t expected_resp_uid = -1;
+/* msg id of response expected with expected_resp_uid */
+static int expected_resp_msg_id;
 
 /* Control response callbacks
  * These will be updated per control request received
@@ -134,6 +141,74 @@ static ctrl_resp_cb_t ctrl_resp_cb_table [CTRL_RESP_MAX - CTRL_RESP_BASE] = { NU
  */
 static ctrl_event_cb_t ctrl_event_cb_table[CTRL_EVENT_MAX - CTRL_EVENT_BASE] = { NULL };
 
+/* Messages from ESP32 dropped as unexpected, updated only by rx thread */
+static ctrl_rx_stats_t ctrl_rx_stats;
+static ctrl_rx_unexpected_cb_t ctrl_rx_unexpected_cb;
+
+/* Count dropped message and report it, if strict mode callback is set */
+static void report_unexpected_rx(int reason, uint32_t msg_id)
+{
+	ctrl_rx_unexpected_cb_t rx_cb = ctrl_rx_unexpected_cb;
+
+	switch (reason) {
+		case CTRL_RX_UNEXPECTED_MALFORMED:
+			ctrl_rx_stats.malformed++;
+			break;
+		case CTRL_RX_UNEXPECTED_MSG_TYPE:
+			ctrl_rx_stats.unknown_msg_type++;
+			break;
+		case CTRL_RX_UNEXPECTED_MSG_ID:
+			ctrl_rx_stats.unknown_msg_id++;
+			break;
+		case CTRL_RX_UNEXPECTED_STALE_RESP:
+			ctrl_rx_stats.stale_resp++;
+			break;
+		default:
+			return;
+	}
+
+	if (rx_cb)
+		rx_cb(reason, msg_id);
+}
+
+/* Events waiting for callback thread, see `set_ctrl_event_queue`
+ * Ring of event_q_depth entries, guarded by event_q_lock */
+static ctrl_cmd_t **event_q;
+static uint16_t event_q_depth;
+static uint16_t event_q_head;
+static uint16_t event_q_count;
+static int event_q_policy;
+static uint8_t event_q_rx_waiting;
+static ctrl_event_queue_stats_t event_q_stats;
+static void * event_q_lock;
+static void * event_q_items_sem;
+static void * event_q_space_sem;
+static void * event_q_thread_handle;
+
+/* Control link state for `get_ctrl_link_status` */
+static struct timespec link_last_rx_ts;
+static uint8_t link_rx_seen;
+static uint32_t link_consecutive_timeouts;
+static uint32_t link_heartbeat_duration;
+static uint32_t link_heartbeat_pending;
+static uint8_t link_sta_connected;
+
+/* Control request token bucket, see `set_ctrl_req_rate_limit`
+ * Tokens are in thousandths of a request, may go negative for requests
+ * already promised a later turn. Guarded by rate_lock */
+static int64_t rate_tokens;
+static struct timespec rate_last_ts;
+static ctrl_req_rate_stats_t rate_stats;
+static void * rate_lock;
+
+/* Any message decoded from ESP32 shows it is alive */
+static void note_link_rx(void)
+{
+	clock_gettime(CLOCK_MONOTONIC, &link_last_rx_ts);
+	link_rx_seen = 1;
+	link_consecutive_timeouts = 0;
+}
+
 /* Open serial interface
  * This function may fail if the ESP32 kernel module is not loaded
  **/
@@ -188,6 +263,7 @@ static int ctrl_app_parse_event(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_ntfy)
 	switch (ctrl_msg->msg_id) {
 		case CTRL_EVENT_ESP_INIT: {
 			app_ntfy->resp_event_status = SUCCESS;
+			link_sta_connected = 0;
 			//command_log("EVENT: ESP INIT\n");
 			break;
 		} case CTRL_EVENT_HEARTBEAT: {
@@ -200,6 +276,7 @@ static int ctrl_app_parse_event(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_ntfy)
 			CHECK_CTRL_MSG_NON_NULL(event_station_connected_to_ap);
 			app_ntfy->resp_event_status = ctrl_msg->event_station_connected_to_ap->resp;
 			if(SUCCESS==app_ntfy->resp_event_status) {
+				link_sta_connected = 1;
 				if (ctrl_msg->event_station_connected_to_ap->ssid.len && ctrl_msg->event_station_connected_to_ap->ssid.data) {
 					strncpy((char *)app_ntfy->u.e_sta_conn.ssid,
 						(char *)ctrl_msg->event_station_connected_to_ap->ssid.data,
@@ -225,6 +302,7 @@ static int ctrl_app_parse_event(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_ntfy)
 			//		ctrl_msg->event_station_disconnect_from_ap->resp);
 			app_ntfy->resp_event_status = ctrl_msg->event_station_disconnect_from_ap->resp;
 			if(SUCCESS==app_ntfy->resp_event_status) {
+				link_sta_connected = 0;
 				if (ctrl_msg->event_station_disconnect_from_ap->ssid.len && ctrl_msg->event_station_disconnect_from_ap->ssid.data) {
 					strncpy((char *)app_ntfy->u.e_sta_disconn.ssid,
 							(char *)ctrl_msg->event_station_disconnect_from_ap->ssid.data,
@@ -318,6 +396,7 @@ static int ctrl_app_parse_event(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_ntfy)
 			break;
 		} default: {
 			command_log("Invalid/unsupported event[%u] received\n",ctrl_msg->msg_id);
+			report_unexpected_rx(CTRL_RX_UNEXPECTED_MSG_ID, ctrl_msg->msg_id);
 			goto fail_parse_ctrl_msg;
 			break;
 		}
@@ -357,6 +436,7 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 	 * so we skip this check */
 	if (app_resp->uid && (expected_resp_uid != app_resp->uid)) {
 		// response uid mismatch: ignore this response
+		report_unexpected_rx(CTRL_RX_UNEXPECTED_STALE_RESP, ctrl_msg->msg_id);
 		goto fail_parse_ctrl_msg2;
 	}
 
@@ -435,6 +515,7 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 			switch (ctrl_msg->resp_get_ap_config->resp) {
 
 				case CTRL_ERR_NOT_CONNECTED:
+					link_sta_connected = 0;
 					strncpy(p->status, NOT_CONNECTED_STR, STATUS_LENGTH);
 					p->status[STATUS_LENGTH-1] = '\0';
 					command_log("Station is not connected to AP \n");
@@ -442,6 +523,7 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 					break;
 
 				case SUCCESS:
+					link_sta_connected = 1;
 					strncpy(p->status, SUCCESS_STR, STATUS_LENGTH);
 					p->status[STATUS_LENGTH-1] = '\0';
 					if (ctrl_msg->resp_get_ap_config->ssid.data && ctrl_msg->resp_get_ap_config->ssid.len) {
@@ -495,6 +577,10 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 					command_log("SSID: not found/connectable\n");
 					goto fail_parse_ctrl_msg;
 					break;
+				case CTRL_ERR_ESP_NOT_SUPPORTED:
+					command_log("Requested security not supported by ESP firmware\n");
+					goto fail_parse_ctrl_msg;
+					break;
 				case SUCCESS:
 					//command_log("Info: Connect band_mode is %d\n", ctrl_msg->resp_connect_ap->band_mode);
 					//CHECK_CTRL_MSG_NON_NULL(resp_connect_ap->mac.data);
@@ -511,10 +597,12 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 						(char *)ctrl_msg->resp_connect_ap->mac.data, len_l);
 				app_resp->u.wifi_ap_config.out_mac[len_l] = '\0';
 			}
+			link_sta_connected = 1;
 			break;
 		} case CTRL_RESP_DISCONNECT_AP : {
 			CHECK_CTRL_MSG_NON_NULL(resp_disconnect_ap);
 			CHECK_CTRL_MSG_FAILED(resp_disconnect_ap);
+			link_sta_connected = 0;
 			break;
 		} case CTRL_RESP_GET_SOFTAP_CONFIG : {
 			CHECK_CTRL_MSG_NON_NULL(resp_get_softap_config);
@@ -667,6 +755,7 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 		} case CTRL_RESP_CONFIG_HEARTBEAT: {
 			CHECK_CTRL_MSG_NON_NULL(resp_config_heartbeat);
 			CHECK_CTRL_MSG_FAILED(resp_config_heartbeat);
+			link_heartbeat_duration = link_heartbeat_pending;
 			break;
 		} case CTRL_RESP_ENABLE_DISABLE: {
 			CHECK_CTRL_MSG_NON_NULL(resp_enable_disable_feat);
@@ -708,6 +797,7 @@ static int ctrl_app_parse_resp(CtrlMsg *ctrl_msg, ctrl_cmd_t *app_resp)
 			break;
 		} default: {
 			command_log("Unsupported Control Resp[%u]\n", ctrl_msg->msg_id);
+			report_unexpected_rx(CTRL_RX_UNEXPECTED_MSG_ID, ctrl_msg->msg_id);
 			goto fail_parse_ctrl_msg;
 			break;
 		}
@@ -763,9 +853,95 @@ static int is_event_callback_registered(int event)
 
 
 /* Process control msg (response or event) received from ESP32 */
-static int process_ctrl_rx_msg(CtrlMsg * proto_msg, ctrl_rx_ind_t ctrl_rx_func)
+/* Pass response to async callback, or to app waiting for it synchronously,
+ * and free request slot */
+static int deliver_ctrl_resp(ctrl_cmd_t *app_resp, ctrl_rx_ind_t ctrl_rx_func)
 {
 	esp_queue_elem_t *elem = NULL;
+
+	/* Is callback is available,
+	 * progress as async response */
+	if (CALLBACK_AVAILABLE ==
+		is_async_resp_callback_registered_by_resp_msg_id(app_resp->msg_id)) {
+
+		/* User registered control async response callback
+		 * function is available for this proto_msg,
+		 * so call to that function should be done and
+		 * return to select
+		 */
+		//command_log("async_resp_callback for msg_id [%d] available: %p\n", app_resp->msg_id, ctrl_resp_cb_table[app_resp->msg_id-CTRL_RESP_BASE]);
+		call_async_resp_callback(app_resp);
+
+		//CLEANUP_APP_MSG(app_resp);
+
+	} else {
+
+		/* as control async response callback function is
+		 * NOT available/registered, treat this response as
+		 * synchronous response. forward this response to app
+		 * using 'esp_queue' and help of semaphore
+		 **/
+		//command_log("async_resp_callback for msg_id [%d] NOT available\n", app_resp->msg_id);
+
+		elem = (esp_queue_elem_t*)hosted_malloc(sizeof(esp_queue_elem_t));
+		if (!elem) {
+			command_log("%s %u: Malloc failed\n",__func__,__LINE__);
+			return FAILURE;
+		}
+
+		/* User is RESPONSIBLE to free memory from
+		 * app_resp in case of async callbacks NOT provided
+		 * to free memory, please refer CLEANUP_APP_MSG macro
+		 **/
+		elem->buf = app_resp;
+		elem->buf_len = sizeof(ctrl_cmd_t);
+		if (esp_queue_put(ctrl_msg_Q, (void*)elem)) {
+			command_log("%s %u: ctrl Q put fail\n",__func__,__LINE__);
+			mem_free(elem);
+			return FAILURE;
+		}
+
+		/* Call up rx ind to unblock user */
+		if (ctrl_rx_func)
+			ctrl_rx_func();
+	}
+	hosted_post_semaphore(ctrl_req_sem);
+	return SUCCESS;
+}
+
+/* Frame from ESP was dropped as too large, most likely the response
+ * being waited for. Fail pending request now instead of on timeout */
+static void fail_pending_ctrl_resp(uint8_t status, ctrl_rx_ind_t ctrl_rx_func)
+{
+	ctrl_cmd_t *app_resp = NULL;
+
+	if (expected_resp_uid == -1)
+		return;
+
+	app_resp = (ctrl_cmd_t *)hosted_calloc(1, sizeof(ctrl_cmd_t));
+	if (!app_resp) {
+		command_log("Failed to allocate app_resp\n");
+		return;
+	}
+
+	if (async_timer_handle) {
+		/* async_timer_handle will be cleaned in hosted_timer_stop */
+		hosted_timer_stop(async_timer_handle);
+		async_timer_handle = NULL;
+	}
+
+	app_resp->msg_type = CTRL_RESP;
+	app_resp->msg_id = expected_resp_msg_id;
+	app_resp->uid = expected_resp_uid;
+	app_resp->resp_event_status = status;
+	expected_resp_uid = -1;
+
+	if (deliver_ctrl_resp(app_resp, ctrl_rx_func))
+		mem_free(app_resp);
+}
+
+static int process_ctrl_rx_msg(CtrlMsg * proto_msg, ctrl_rx_ind_t ctrl_rx_func)
+{
 	ctrl_cmd_t *app_resp = NULL;
 	ctrl_cmd_t *app_event = NULL;
 
@@ -777,11 +953,11 @@ static int process_ctrl_rx_msg(CtrlMsg * proto_msg, ctrl_rx_ind_t ctrl_rx_func)
 	/* 2. Check if it is event msg */
 	if (proto_msg->msg_type == CTRL_MSG_TYPE__Event) {
 		/* Events are handled only asynchronously */
+		int event_cb_state = is_event_callback_registered(proto_msg->msg_id);
 
 		/* check if callback is available.
 		 * if not, silently drop the msg */
-		if (CALLBACK_AVAILABLE ==
-				is_event_callback_registered(proto_msg->msg_id)) {
+		if (CALLBACK_AVAILABLE == event_cb_state) {
 			/* if event callback is registered, we need to
 			 * parse the event into app structs and
 			 * call the registered callback function
@@ -800,11 +976,13 @@ static int process_ctrl_rx_msg(CtrlMsg * proto_msg, ctrl_rx_ind_t ctrl_rx_func)
 			ctrl_app_parse_event(proto_msg, app_event);
 
 			/* callback to registered function */
-			call_event_callback(app_event);
+			dispatch_event(app_event);
 
 			//CLEANUP_APP_MSG(app_event);
 		} else {
-			/* silently drop */
+			/* silently drop, unless event is not known at all */
+			if (MSG_ID_OUT_OF_ORDER == event_cb_state)
+				report_unexpected_rx(CTRL_RX_UNEXPECTED_MSG_ID, proto_msg->msg_id);
 			goto free_buffers;
 		}
 
@@ -835,63 +1013,19 @@ static int process_ctrl_rx_msg(CtrlMsg * proto_msg, ctrl_rx_ind_t ctrl_rx_func)
 		 * copy into app structures */
 		ctrl_app_parse_resp(proto_msg, app_resp);
 
-		/* Is callback is available,
-		 * progress as async response */
-		if (CALLBACK_AVAILABLE ==
-			is_async_resp_callback_registered_by_resp_msg_id(app_resp->msg_id)) {
-
-			/* User registered control async response callback
-			 * function is available for this proto_msg,
-			 * so call to that function should be done and
-			 * return to select
-			 */
-			//command_log("async_resp_callback for msg_id [%d] available: %p\n", app_resp->msg_id, ctrl_resp_cb_table[app_resp->msg_id-CTRL_RESP_BASE]);
-			call_async_resp_callback(app_resp);
-
-			//CLEANUP_APP_MSG(app_resp);
-
-		} else {
-
-			/* as control async response callback function is
-			 * NOT available/registered, treat this response as
-			 * synchronous response. forward this response to app
-			 * using 'esp_queue' and help of semaphore
-			 **/
-			//command_log("async_resp_callback for msg_id [%d] NOT available\n", app_resp->msg_id);
-
-			elem = (esp_queue_elem_t*)hosted_malloc(sizeof(esp_queue_elem_t));
-			if (!elem) {
-				command_log("%s %u: Malloc failed\n",__func__,__LINE__);
-				goto free_buffers;
-			}
-
-			/* User is RESPONSIBLE to free memory from
-			 * app_resp in case of async callbacks NOT provided
-			 * to free memory, please refer CLEANUP_APP_MSG macro
-			 **/
-			elem->buf = app_resp;
-			elem->buf_len = sizeof(ctrl_cmd_t);
-			if (esp_queue_put(ctrl_msg_Q, (void*)elem)) {
-				command_log("%s %u: ctrl Q put fail\n",__func__,__LINE__);
-				goto free_buffers;
-			}
-
-			/* Call up rx ind to unblock user */
-			if (ctrl_rx_func)
-				ctrl_rx_func();
-		}
-		hosted_post_semaphore(ctrl_req_sem);
+		if (deliver_ctrl_resp(app_resp, ctrl_rx_func))
+			goto free_buffers;
 
 	} else {
 		/* 4. some unsupported msg, drop it */
 		command_log("Incorrect Ctrl Msg Type[%u]\n",proto_msg->msg_type);
+		report_unexpected_rx(CTRL_RX_UNEXPECTED_MSG_TYPE, proto_msg->msg_id);
 		goto free_buffers;
 	}
 	return SUCCESS;
 
 	/* 5. cleanup */
 free_buffers:
-	mem_free(elem);
 	mem_free(app_event);
 	if (proto_msg) {
 		ctrl_msg__free_unpacked(proto_msg, NULL);
@@ -933,18 +1067,31 @@ static void ctrl_rx_thread(void const *arg)
 			sleep(1);
 			continue;
 		}
+		errno = 0;
 		buf = transport_pserial_read(&buf_len);
 
+		if ((!buf_len || !buf) && errno == EMSGSIZE) {
+			/* Dropped by driver or serial_drv_read, ESP is alive though */
+			command_log("Frame from ESP too large, dropped\n");
+			note_link_rx();
+			fail_pending_ctrl_resp(CTRL_ERR_FRAME_TOO_LARGE, ctrl_rx_func);
+			goto free_bufs;
+		}
+
 		if (!buf_len || !buf) {
 			command_log("%s buf_len read = 0\n",__func__);
+			report_unexpected_rx(CTRL_RX_UNEXPECTED_MALFORMED, 0);
 			goto free_bufs;
 		}
 
 		/* 3.2 Decode protobuf */
 		resp = ctrl_msg__unpack(NULL, buf_len, buf);
 		if (!resp) {
+			report_unexpected_rx(CTRL_RX_UNEXPECTED_MALFORMED, 0);
 			goto free_bufs;
 		}
+		note_link_rx();
+
 		/* 3.3 Free the read buffer */
 		mem_free(buf);
 
@@ -1082,6 +1229,203 @@ static int call_event_callback(ctrl_cmd_t *app_event)
 	return CALLBACK_NOT_REGISTERED;
 }
 
+/* Events that are never dropped from event queue */
+static int is_critical_event(ctrl_cmd_t *app_event)
+{
+	return (app_event->msg_id == CTRL_EVENT_ESP_INIT) ||
+		(app_event->msg_id == CTRL_EVENT_STATION_DISCONNECT_FROM_AP);
+}
+
+static void free_event(ctrl_cmd_t *app_event)
+{
+	if (app_event->free_buffer_handle && app_event->free_buffer_func)
+		app_event->free_buffer_func(app_event->free_buffer_handle);
+	mem_free(app_event);
+}
+
+/* Decide what to do with full event queue
+ * Returns:
+ * > index from head of queued event to drop
+ * > EVENT_Q_DROP_NEW - drop new event
+ * > EVENT_Q_WAIT - wait for callback thread to make space
+ **/
+#define EVENT_Q_DROP_NEW -1
+#define EVENT_Q_WAIT     -2
+static int pick_event_to_drop(ctrl_cmd_t *new_event)
+{
+	int i = 0;
+
+	if (event_q_policy == CTRL_EVENT_OVERFLOW_BLOCK)
+		return EVENT_Q_WAIT;
+
+	if ((event_q_policy == CTRL_EVENT_OVERFLOW_DROP_NEWEST) &&
+	    !is_critical_event(new_event))
+		return EVENT_Q_DROP_NEW;
+
+	for (i = 0; i < event_q_count; i++) {
+		if (!is_critical_event(event_q[(event_q_head + i) % event_q_depth]))
+			return i;
+	}
+
+	return is_critical_event(new_event) ? EVENT_Q_WAIT : EVENT_Q_DROP_NEW;
+}
+
+/* Called from rx thread. Callback is called directly, unless queue is set */
+static void dispatch_event(ctrl_cmd_t *app_event)
+{
+	int drop = 0;
+	int i = 0;
+
+	if (!event_q_depth) {
+		call_event_callback(app_event);
+		return;
+	}
+
+	hosted_get_semaphore(event_q_lock, HOSTED_SEM_BLOCKING);
+	while (event_q_count == event_q_depth) {
+		drop = pick_event_to_drop(app_event);
+
+		if (drop == EVENT_Q_WAIT) {
+			event_q_rx_waiting = 1;
+			hosted_post_semaphore(event_q_lock);
+			hosted_get_semaphore(event_q_space_sem, HOSTED_SEM_BLOCKING);
+			hosted_get_semaphore(event_q_lock, HOSTED_SEM_BLOCKING);
+			continue;
+		}
+
+		event_q_stats.dropped++;
+		if (drop == EVENT_Q_DROP_NEW) {
+			hosted_post_semaphore(event_q_lock);
+			command_log("Event queue full, dropped event[%u]\n", app_event->msg_id);
+			free_event(app_event);
+			return;
+		}
+
+		/* Close gap left by dropped event, moving older ones up */
+		command_log("Event queue full, dropped event[%u]\n",
+				event_q[(event_q_head + drop) % event_q_depth]->msg_id);
+		free_event(event_q[(event_q_head + drop) % event_q_depth]);
+		for (i = drop; i > 0; i--) {
+			event_q[(event_q_head + i) % event_q_depth] =
+				event_q[(event_q_head + i - 1) % event_q_depth];
+		}
+		event_q_head = (event_q_head + 1) % event_q_depth;
+		event_q_count--;
+	}
+
+	event_q[(event_q_head + event_q_count) % event_q_depth] = app_event;
+	event_q_count++;
+	if (event_q_count > event_q_stats.high_watermark)
+		event_q_stats.high_watermark = event_q_count;
+	hosted_post_semaphore(event_q_lock);
+
+	hosted_post_semaphore(event_q_items_sem);
+}
+
+/* Event callback thread, when queue is set */
+static void event_q_thread(void const *arg)
+{
+	ctrl_cmd_t *app_event = NULL;
+
+	while (1) {
+		hosted_get_semaphore(event_q_items_sem, HOSTED_SEM_BLOCKING);
+
+		hosted_get_semaphore(event_q_lock, HOSTED_SEM_BLOCKING);
+		/* Item may have been dropped meanwhile */
+		if (!event_q_count) {
+			hosted_post_semaphore(event_q_lock);
+			continue;
+		}
+		app_event = event_q[event_q_head];
+		event_q_head = (event_q_head + 1) % event_q_depth;
+		event_q_count--;
+		if (event_q_rx_waiting) {
+			event_q_rx_waiting = 0;
+			hosted_post_semaphore(event_q_space_sem);
+		}
+		hosted_post_semaphore(event_q_lock);
+
+		call_event_callback(app_event);
+	}
+}
+
+int set_ctrl_event_queue(uint16_t depth, int overflow_policy)
+{
+	if ((overflow_policy < CTRL_EVENT_OVERFLOW_DROP_OLDEST) ||
+	    (overflow_policy > CTRL_EVENT_OVERFLOW_BLOCK)) {
+		command_log("Invalid event queue overflow policy[%d]\n", overflow_policy);
+		return FAILURE;
+	}
+
+	if (event_q_depth) {
+		if (depth != event_q_depth) {
+			command_log("Event queue depth already set to %u\n", event_q_depth);
+			return FAILURE;
+		}
+		event_q_policy = overflow_policy;
+		return SUCCESS;
+	}
+
+	if (!depth || (depth > CTRL_EVENT_QUEUE_MAX_DEPTH)) {
+		command_log("Event queue depth must be 1 to %u\n", CTRL_EVENT_QUEUE_MAX_DEPTH);
+		return FAILURE;
+	}
+
+	event_q = (ctrl_cmd_t **)hosted_calloc(depth, sizeof(ctrl_cmd_t *));
+	event_q_lock = hosted_create_semaphore(1);
+	event_q_items_sem = hosted_create_semaphore(0);
+	event_q_space_sem = hosted_create_semaphore(0);
+	if (!event_q || !event_q_lock || !event_q_items_sem || !event_q_space_sem) {
+		command_log("Failed to create event queue\n");
+		goto fail_event_q;
+	}
+
+	event_q_policy = overflow_policy;
+	event_q_head = 0;
+	event_q_count = 0;
+	memset(&event_q_stats, 0, sizeof(event_q_stats));
+
+	event_q_thread_handle = hosted_thread_create(event_q_thread, NULL);
+	if (!event_q_thread_handle) {
+		command_log("Thread creation failed for event queue\n");
+		goto fail_event_q;
+	}
+
+	/* Set last, rx thread starts queueing once depth is non zero */
+	event_q_depth = depth;
+	return SUCCESS;
+
+fail_event_q:
+	mem_free(event_q);
+	if (event_q_lock)
+		hosted_destroy_semaphore(event_q_lock);
+	if (event_q_items_sem)
+		hosted_destroy_semaphore(event_q_items_sem);
+	if (event_q_space_sem)
+		hosted_destroy_semaphore(event_q_space_sem);
+	event_q_lock = event_q_items_sem = event_q_space_sem = NULL;
+	return FAILURE;
+}
+
+int get_ctrl_event_queue_stats(ctrl_event_queue_stats_t *stats)
+{
+	if (!stats) {
+		command_log("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	memset(stats, 0, sizeof(ctrl_event_queue_stats_t));
+	if (!event_q_depth)
+		return SUCCESS;
+
+	hosted_get_semaphore(event_q_lock, HOSTED_SEM_BLOCKING);
+	memcpy(stats, &event_q_stats, sizeof(ctrl_event_queue_stats_t));
+	stats->depth = event_q_depth;
+	stats->queued = event_q_count;
+	hosted_post_semaphore(event_q_lock);
+	return SUCCESS;
+}
+
 /* Set asynchronous control response callback from control **request**
  * In case of synchronous request, `resp_cb` will be NULL and table
  * `ctrl_resp_cb_table` will be updated with NULL
@@ -1166,6 +1510,140 @@ int set_event_callback(int event, ctrl_resp_cb_t event_cb)
 	return CALLBACK_SET_SUCCESS;
 }
 
+void set_ctrl_rx_unexpected_callback(ctrl_rx_unexpected_cb_t rx_cb)
+{
+	ctrl_rx_unexpected_cb = rx_cb;
+}
+
+int get_ctrl_rx_stats(ctrl_rx_stats_t *stats)
+{
+	if (!stats) {
+		command_log("Invalid parameter\n");
+		return FAILURE;
+	}
+	memcpy(stats, &ctrl_rx_stats, sizeof(ctrl_rx_stats_t));
+	return SUCCESS;
+}
+
+int get_ctrl_link_status(ctrl_link_status_t *status)
+{
+	struct timespec now = {0};
+
+	if (!status) {
+		command_log("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	memset(status, 0, sizeof(ctrl_link_status_t));
+	status->consecutive_timeouts = link_consecutive_timeouts;
+	status->heartbeat_duration = link_heartbeat_duration;
+	status->sta_connected = link_sta_connected;
+	status->last_rx_age_sec = -1;
+	if (link_rx_seen) {
+		clock_gettime(CLOCK_MONOTONIC, &now);
+		status->last_rx_age_sec = now.tv_sec - link_last_rx_ts.tv_sec;
+	}
+
+	if ((status->consecutive_timeouts >= CTRL_LINK_MAX_TIMEOUTS) ||
+	    (status->heartbeat_duration && status->last_rx_age_sec >
+	     (int32_t)(status->heartbeat_duration * CTRL_LINK_MISSED_HEARTBEATS)))
+		status->health = CTRL_LINK_HEALTH_ESP_UNRESPONSIVE;
+	else if (!status->sta_connected)
+		status->health = CTRL_LINK_HEALTH_WIFI_DOWN;
+	else
+		status->health = CTRL_LINK_HEALTH_OK;
+
+	return SUCCESS;
+}
+
+/* Wait for turn of request within rate limit
+ * Returns SUCCESS once request may go, FAILURE if turn is too far away */
+static int ctrl_req_rate_wait(void)
+{
+	struct timespec now = {0};
+	int64_t elapsed_ms = 0;
+	uint32_t wait_ms = 0;
+
+	if (!rate_stats.rate_per_sec)
+		return SUCCESS;
+
+	hosted_get_semaphore(rate_lock, HOSTED_SEM_BLOCKING);
+	clock_gettime(CLOCK_MONOTONIC, &now);
+	elapsed_ms = (now.tv_sec - rate_last_ts.tv_sec) * 1000 +
+		(now.tv_nsec - rate_last_ts.tv_nsec) / 1000000;
+	if (elapsed_ms > 0) {
+		/* rate_per_sec requests per second is as many thousandths per ms */
+		rate_tokens += elapsed_ms * rate_stats.rate_per_sec;
+		if (rate_tokens > (int64_t)rate_stats.burst * 1000)
+			rate_tokens = (int64_t)rate_stats.burst * 1000;
+		rate_last_ts = now;
+	}
+
+	if (rate_tokens < 1000) {
+		wait_ms = (uint32_t)((1000 - rate_tokens + rate_stats.rate_per_sec - 1) /
+				rate_stats.rate_per_sec);
+		if (wait_ms > rate_stats.max_delay_ms) {
+			rate_stats.dropped++;
+			hosted_post_semaphore(rate_lock);
+			return FAILURE;
+		}
+		rate_stats.delayed++;
+	}
+	rate_tokens -= 1000;
+	hosted_post_semaphore(rate_lock);
+
+	if (wait_ms)
+		usleep(wait_ms * 1000);
+	return SUCCESS;
+}
+
+int set_ctrl_req_rate_limit(uint16_t rate_per_sec, uint16_t burst, uint32_t max_delay_ms)
+{
+	if (rate_per_sec && !burst) {
+		command_log("Rate limit burst must be at least 1\n");
+		return FAILURE;
+	}
+
+	if (!rate_lock) {
+		rate_lock = hosted_create_semaphore(1);
+		if (!rate_lock) {
+			command_log("Failed to create rate limit lock\n");
+			return FAILURE;
+		}
+	}
+
+	hosted_get_semaphore(rate_lock, HOSTED_SEM_BLOCKING);
+	memset(&rate_stats, 0, sizeof(rate_stats));
+	if (rate_per_sec) {
+		rate_stats.burst = burst;
+		rate_stats.max_delay_ms = max_delay_ms;
+		/* Start with full bucket */
+		rate_tokens = (int64_t)burst * 1000;
+		clock_gettime(CLOCK_MONOTONIC, &rate_last_ts);
+	}
+	/* Set last, send path skips lock while this is 0 */
+	rate_stats.rate_per_sec = rate_per_sec;
+	hosted_post_semaphore(rate_lock);
+	return SUCCESS;
+}
+
+int get_ctrl_req_rate_stats(ctrl_req_rate_stats_t *stats)
+{
+	if (!stats) {
+		command_log("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	memset(stats, 0, sizeof(ctrl_req_rate_stats_t));
+	if (!rate_lock)
+		return SUCCESS;
+
+	hosted_get_semaphore(rate_lock, HOSTED_SEM_BLOCKING);
+	memcpy(stats, &rate_stats, sizeof(ctrl_req_rate_stats_t));
+	hosted_post_semaphore(rate_lock);
+	return SUCCESS;
+}
+
 /* Get control event callback
  * Returns:
  * > NULL - If event is not registered with hosted control lib
@@ -1212,6 +1690,7 @@ ctrl_cmd_t * ctrl_wait_and_parse_sync_resp(ctrl_cmd_t *app_req)
 		 * If a response arrives after this, it will be flagged
 		 * as an invalid response */
 		expected_resp_uid = -1;
+		link_consecutive_timeouts++;
 	}
 	return rx_buf;
 }
@@ -1239,6 +1718,7 @@ static void ctrl_async_timeout_handler(void const *arg)
 	}
 	app_resp->msg_type = CTRL_RESP;
 	app_resp->resp_event_status = CTRL_ERR_REQUEST_TIMEOUT;
+	link_consecutive_timeouts++;
 
 	/* call func pointer to notify failure */
 	func(app_resp);
@@ -1285,6 +1765,13 @@ int ctrl_app_send_req(ctrl_cmd_t *app_req)
 		goto fail_req;
 	}
 
+	/* Held back before taking request slot, not to block other callers */
+	if (ctrl_req_rate_wait()) {
+		failure_status = CTRL_ERR_RATE_LIMITED;
+		command_log("Request rate limited\n");
+		goto fail_req;
+	}
+
 	/* 1. Check if any ongoing request present
 	 * Send failure in that case */
 	ret = hosted_get_semaphore(ctrl_req_sem, WAIT_TIME_B2B_CTRL_REQ);
@@ -1316,6 +1803,7 @@ int ctrl_app_send_req(ctrl_cmd_t *app_req)
 	assert(expected_resp_uid == -1);
 	// set the expected response uid
 	expected_resp_uid = req.uid;
+	expected_resp_msg_id = app_req->msg_id - CTRL_REQ_BASE + CTRL_RESP_BASE;
 
 	/* 3. identify request and compose CtrlMsg */
 	switch(req.msg_id) {
@@ -1564,6 +2052,7 @@ int ctrl_app_send_req(ctrl_cmd_t *app_req)
 			ctrl_msg__req__config_heartbeat__init(req_payload);
 			req_payload->enable = app_req->u.e_heartbeat.enable;
 			req_payload->duration = app_req->u.e_heartbeat.duration;
+			link_heartbeat_pending = req_payload->enable ? req_payload->duration : 0;
 			if (req_payload->enable) {
 				command_log("Enable heartbeat with duration %ld\n", (long int)req_payload->duration);
 				if (CALLBACK_AVAILABLE != is_event_callback_registered(CTRL_EVENT_HEARTBEAT))
@@ -1736,6 +2225,13 @@ int init_hosted_control_lib_internal(void)
 {
 	int ret = SUCCESS;
 
+	memset(&ctrl_rx_stats, 0, sizeof(ctrl_rx_stats));
+	link_rx_seen = 0;
+	link_consecutive_timeouts = 0;
+	link_heartbeat_duration = 0;
+	link_heartbeat_pending = 0;
+	link_sta_connected = 0;
+
 	/* semaphore init */
 	read_sem = hosted_create_semaphore(1);
 	ctrl_req_sem = hosted_create_semaphore(1);
@@ -24,7 +24,11 @@
 #include <unistd.h>
 #include <inttypes.h>
 #include <time.h>
+#include <endian.h>
+#include <errno.h>
+#include <pthread.h>
 #include "test.h"
+#include "nw_helper_func.h"
 #include "ctrl_api.h"
 #include "app_custom_rpc.h"
 #include "esp_hosted_custom_rpc.h"
@@ -154,6 +158,987 @@ int custom_rpc_demo2_request_echo_back_as_response(void) {
 	return ret;
 }
 
+/* -------------- SoftAP station management -------------- */
+int custom_rpc_softap_get_sta_details(void) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_softap_sta_list_t *list = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	int ret = SUCCESS;
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SOFTAP_GET_STA_DETAILS, &unused, sizeof(unused),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get SoftAP station details\n");
+		return FAILURE;
+	}
+
+	list = (custom_rpc_softap_sta_list_t *)recv_data;
+	if (!list || recv_data_len < sizeof(custom_rpc_softap_sta_list_t) ||
+	    recv_data_len < sizeof(custom_rpc_softap_sta_list_t) + list->num * sizeof(custom_rpc_softap_sta_info_t)) {
+		printf("Invalid SoftAP station details response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+		goto cleanup;
+	}
+
+	printf("Number of connected stations: %u\n", list->num);
+	for (int i = 0; i < list->num; i++) {
+		custom_rpc_softap_sta_info_t *sta = &list->sta[i];
+
+		printf("%d) %02x:%02x:%02x:%02x:%02x:%02x rssi %d connected %" PRIu32 "s rx %" PRIu64 " bytes tx %" PRIu64 " bytes\n",
+				i + 1, sta->mac[0], sta->mac[1], sta->mac[2], sta->mac[3], sta->mac[4], sta->mac[5],
+				sta->rssi, le32toh(sta->connected_secs),
+				le64toh(sta->rx_bytes), le64toh(sta->tx_bytes));
+	}
+
+cleanup:
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_softap_kick_sta(const char *mac) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	uint8_t mac_bytes[CUSTOM_RPC_MAC_LEN] = {0};
+	int ret = SUCCESS;
+
+	if (convert_mac_to_bytes(mac_bytes, sizeof(mac_bytes), mac) != SUCCESS) {
+		printf("Invalid station MAC address\n");
+		return FAILURE;
+	}
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SOFTAP_KICK_STA, mac_bytes, sizeof(mac_bytes),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to deauthenticate station %s\n", mac);
+	} else {
+		printf("Station %s deauthenticated\n", mac);
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_set_softap_acl(uint8_t mode, bool isolate, const char *macs) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	uint8_t buf[sizeof(custom_rpc_softap_acl_t) +
+		CUSTOM_RPC_SOFTAP_ACL_MAX_MACS * CUSTOM_RPC_MAC_LEN] = {0};
+	custom_rpc_softap_acl_t *req = (custom_rpc_softap_acl_t *)buf;
+	char list[CUSTOM_RPC_SOFTAP_ACL_MAX_MACS * 20] = {0};
+	char *saveptr = NULL;
+	char *tok = NULL;
+	int ret = SUCCESS;
+
+	if (macs) {
+		if (strlen(macs) >= sizeof(list)) {
+			printf("MAC list too long\n");
+			return FAILURE;
+		}
+		strncpy(list, macs, sizeof(list) - 1);
+		for (tok = strtok_r(list, ",", &saveptr); tok; tok = strtok_r(NULL, ",", &saveptr)) {
+			if (req->num >= CUSTOM_RPC_SOFTAP_ACL_MAX_MACS) {
+				printf("At most %d MACs supported\n", CUSTOM_RPC_SOFTAP_ACL_MAX_MACS);
+				return FAILURE;
+			}
+			if (convert_mac_to_bytes(req->mac[req->num], CUSTOM_RPC_MAC_LEN, tok) != SUCCESS) {
+				printf("Invalid MAC address %s\n", tok);
+				return FAILURE;
+			}
+			req->num++;
+		}
+	}
+
+	if (mode == CUSTOM_RPC_SOFTAP_ACL_ALLOW && !req->num) {
+		printf("Allow mode needs at least one MAC\n");
+		return FAILURE;
+	}
+	req->mode = mode;
+	req->isolate = isolate;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_SOFTAP_ACL, buf,
+			sizeof(custom_rpc_softap_acl_t) + req->num * CUSTOM_RPC_MAC_LEN,
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set SoftAP access control\n");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_get_softap_acl(void) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_softap_acl_t *acl = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	int ret = SUCCESS;
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_SOFTAP_ACL, &unused, sizeof(unused),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get SoftAP access control\n");
+		return FAILURE;
+	}
+
+	acl = (custom_rpc_softap_acl_t *)recv_data;
+	if (!acl || recv_data_len < sizeof(custom_rpc_softap_acl_t) ||
+	    recv_data_len < sizeof(custom_rpc_softap_acl_t) + acl->num * CUSTOM_RPC_MAC_LEN) {
+		printf("Invalid SoftAP access control response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+		goto cleanup;
+	}
+
+	printf("Mode: %s, client isolation: %s, rejected: %" PRIu32 "\n",
+			acl->mode == CUSTOM_RPC_SOFTAP_ACL_ALLOW ? "allow" :
+			acl->mode == CUSTOM_RPC_SOFTAP_ACL_DENY ? "deny" : "off",
+			acl->isolate ? "on" : "off", le32toh(acl->rejected));
+	for (int i = 0; i < acl->num; i++) {
+		printf("  %02x:%02x:%02x:%02x:%02x:%02x\n", acl->mac[i][0], acl->mac[i][1],
+				acl->mac[i][2], acl->mac[i][3], acl->mac[i][4], acl->mac[i][5]);
+	}
+
+cleanup:
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+/* -------------- Wi-Fi PHY configuration -------------- */
+int custom_rpc_get_wifi_protocol(uint8_t iface, uint8_t *protocol) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_wifi_protocol_t *resp = NULL;
+	int ret = SUCCESS;
+
+	if (!protocol) {
+		return FAILURE;
+	}
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_WIFI_PROTOCOL, &iface, sizeof(iface),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get Wi-Fi protocol\n");
+		return FAILURE;
+	}
+
+	if (!recv_data || recv_data_len < sizeof(custom_rpc_wifi_protocol_t)) {
+		printf("Invalid Wi-Fi protocol response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+	} else {
+		resp = (custom_rpc_wifi_protocol_t *)recv_data;
+		*protocol = resp->protocol;
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_set_wifi_protocol(uint8_t iface, uint8_t protocol) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_wifi_protocol_t req = {
+		.iface = iface,
+		.protocol = protocol,
+	};
+	int ret = SUCCESS;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_WIFI_PROTOCOL, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set Wi-Fi protocol 0x%x\n", protocol);
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_get_wifi_bandwidth(uint8_t iface, uint8_t *bandwidth) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_wifi_bandwidth_t *resp = NULL;
+	int ret = SUCCESS;
+
+	if (!bandwidth) {
+		return FAILURE;
+	}
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_WIFI_BANDWIDTH, &iface, sizeof(iface),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get Wi-Fi bandwidth\n");
+		return FAILURE;
+	}
+
+	if (!recv_data || recv_data_len < sizeof(custom_rpc_wifi_bandwidth_t)) {
+		printf("Invalid Wi-Fi bandwidth response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+	} else {
+		resp = (custom_rpc_wifi_bandwidth_t *)recv_data;
+		*bandwidth = resp->bandwidth;
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_set_wifi_bandwidth(uint8_t iface, uint8_t bandwidth) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_wifi_bandwidth_t req = {
+		.iface = iface,
+		.bandwidth = bandwidth,
+	};
+	int ret = SUCCESS;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_WIFI_BANDWIDTH, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set Wi-Fi bandwidth %u\n", bandwidth);
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+/* -------------- PMF configuration -------------- */
+int custom_rpc_get_pmf_config(uint8_t iface, bool *capable, bool *required) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_pmf_config_t *resp = NULL;
+	int ret = SUCCESS;
+
+	if (!capable || !required) {
+		return FAILURE;
+	}
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_PMF_CONFIG, &iface, sizeof(iface),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get PMF config\n");
+		return FAILURE;
+	}
+
+	if (!recv_data || recv_data_len < sizeof(custom_rpc_pmf_config_t)) {
+		printf("Invalid PMF config response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+	} else {
+		resp = (custom_rpc_pmf_config_t *)recv_data;
+		*capable = resp->capable;
+		*required = resp->required;
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_set_pmf_config(uint8_t iface, bool capable, bool required) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_pmf_config_t req = {0};
+	int ret = SUCCESS;
+
+	req.iface = iface;
+	req.capable = capable;
+	req.required = required;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_PMF_CONFIG, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set PMF config\n");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_get_scan_config(custom_rpc_scan_config_t *cfg) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	int ret = SUCCESS;
+
+	if (!cfg) {
+		return FAILURE;
+	}
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_SCAN_CONFIG, &unused, sizeof(unused),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get scan config\n");
+		return FAILURE;
+	}
+
+	if (!recv_data || recv_data_len < sizeof(custom_rpc_scan_config_t)) {
+		printf("Invalid scan config response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+	} else {
+		memcpy(cfg, recv_data, sizeof(*cfg));
+		cfg->dwell_ms = le16toh(cfg->dwell_ms);
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_set_scan_config(bool background, uint16_t dwell_ms, uint8_t home_dwell_ms) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_scan_config_t req = {0};
+	int ret = SUCCESS;
+
+	req.background = background;
+	req.dwell_ms = htole16(dwell_ms);
+	req.home_dwell_ms = home_dwell_ms;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_SCAN_CONFIG, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set scan config\n");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+/* -------------- Vendor IE monitor -------------- */
+int custom_rpc_vendor_ie_monitor(bool enable, const char *oui) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_vendor_ie_monitor_t req = {0};
+	unsigned int o[CUSTOM_RPC_VENDOR_OUI_LEN] = {0};
+	int ret = SUCCESS;
+
+	if (oui) {
+		if (sscanf(oui, "%2x:%2x:%2x", &o[0], &o[1], &o[2]) != CUSTOM_RPC_VENDOR_OUI_LEN) {
+			printf("Invalid OUI %s, expected format xx:xx:xx\n", oui);
+			return FAILURE;
+		}
+		for (int i = 0; i < CUSTOM_RPC_VENDOR_OUI_LEN; i++) {
+			req.oui[i] = o[i];
+		}
+	}
+	req.enable = enable;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__VENDOR_IE_MONITOR, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to %s vendor IE monitor\n", enable ? "enable" : "disable");
+	} else {
+		printf("Vendor IE monitor %s\n", enable ? "enabled" : "disabled");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+static const char *vendor_ie_type_str(uint8_t type) {
+	switch (type) {
+		case CUSTOM_RPC_VND_IE_TYPE_BEACON:     return "beacon";
+		case CUSTOM_RPC_VND_IE_TYPE_PROBE_REQ:  return "probe_req";
+		case CUSTOM_RPC_VND_IE_TYPE_PROBE_RESP: return "probe_resp";
+		case CUSTOM_RPC_VND_IE_TYPE_ASSOC_REQ:  return "assoc_req";
+		case CUSTOM_RPC_VND_IE_TYPE_ASSOC_RESP: return "assoc_resp";
+		default:                                return "unknown";
+	}
+}
+
+static void print_vendor_ie_event(const uint8_t *data, uint32_t len) {
+	const custom_rpc_vendor_ie_event_t *ev = (const custom_rpc_vendor_ie_event_t *)data;
+
+	if (!data || len < sizeof(custom_rpc_vendor_ie_event_t) ||
+	    len < sizeof(custom_rpc_vendor_ie_event_t) + ev->ie_len) {
+		printf("Malformed vendor IE event of %u bytes\n", len);
+		return;
+	}
+
+	printf("Vendor IE in %s from %02x:%02x:%02x:%02x:%02x:%02x rssi %d:",
+			vendor_ie_type_str(ev->type),
+			ev->sa[0], ev->sa[1], ev->sa[2], ev->sa[3], ev->sa[4], ev->sa[5],
+			ev->rssi);
+	for (uint32_t i = 0; i < ev->ie_len; i++) {
+		printf(" %02x", ev->ie[i]);
+	}
+	printf("\n");
+}
+
+/* -------------- Probe request monitor -------------- */
+int custom_rpc_probe_req_monitor(bool enable, uint8_t report_interval_sec) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_probe_req_monitor_t req = {0};
+	int ret = SUCCESS;
+
+	req.enable = enable;
+	req.report_interval_sec = report_interval_sec;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__PROBE_REQ_MONITOR, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to %s probe request monitor\n", enable ? "enable" : "disable");
+	} else {
+		printf("Probe request monitor %s\n", enable ? "enabled" : "disabled");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_get_partition_table(void) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_partition_table_t *table = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	int ret = SUCCESS;
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_PARTITION_TABLE, &unused, sizeof(unused),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get partition table\n");
+		return FAILURE;
+	}
+
+	table = (custom_rpc_partition_table_t *)recv_data;
+	if (!table || recv_data_len < sizeof(custom_rpc_partition_table_t) ||
+	    recv_data_len < sizeof(custom_rpc_partition_table_t) + table->num * sizeof(custom_rpc_partition_entry_t)) {
+		printf("Invalid partition table response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+		goto cleanup;
+	}
+
+	printf("%-16s %-4s %-7s %-10s %-10s %s\n", "label", "type", "subtype", "offset", "size", "encrypted");
+	for (int i = 0; i < table->num; i++) {
+		custom_rpc_partition_entry_t *e = &table->entry[i];
+
+		e->label[CUSTOM_RPC_PARTITION_LABEL_LEN - 1] = '\0';
+		printf("%-16s %-4s 0x%02x    0x%08" PRIx32 " 0x%08" PRIx32 " %s\n",
+				e->label, e->type ? "data" : "app", e->subtype,
+				le32toh(e->address), le32toh(e->size), e->encrypted ? "yes" : "no");
+	}
+
+cleanup:
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+static void print_flash_hexdump(uint32_t addr, const uint8_t *data, uint32_t len) {
+	for (uint32_t i = 0; i < len; i += 16) {
+		printf("%08" PRIx32 ": ", addr + i);
+		for (uint32_t j = i; j < i + 16 && j < len; j++) {
+			printf("%02x ", data[j]);
+		}
+		printf("\n");
+	}
+}
+
+#define CUSTOM_RPC_EVENT_TIMEOUT_SEC 5
+
+/* Result event of request in flight, filled by result_event_handler */
+static struct {
+	pthread_mutex_t lock;
+	pthread_cond_t cond;
+	bool waiting;
+	bool done;
+	uint32_t event_id;
+	uint32_t len;
+	uint8_t data[sizeof(custom_rpc_flash_read_data_t) + CUSTOM_RPC_FLASH_READ_MAX_LEN];
+} result_evt = { .lock = PTHREAD_MUTEX_INITIALIZER, .cond = PTHREAD_COND_INITIALIZER };
+static ctrl_resp_cb_t result_evt_prev_handler;
+
+/* Takes awaited result event, passes other events to previous handler */
+static int result_event_handler(ctrl_cmd_t *app_event) {
+	custom_rpc_unserialised_data_t *p_e = &app_event->u.custom_rpc_unserialised_data;
+	bool taken = false;
+
+	pthread_mutex_lock(&result_evt.lock);
+	if (app_event->msg_id == CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG &&
+	    result_evt.waiting && !result_evt.done && p_e->custom_msg_id == result_evt.event_id) {
+		result_evt.len = p_e->data_len < sizeof(result_evt.data) ? p_e->data_len : sizeof(result_evt.data);
+		if (p_e->data)
+			memcpy(result_evt.data, p_e->data, result_evt.len);
+		else
+			result_evt.len = 0;
+		result_evt.done = true;
+		taken = true;
+		pthread_cond_signal(&result_evt.cond);
+	}
+	pthread_mutex_unlock(&result_evt.lock);
+
+	if (!taken && result_evt_prev_handler)
+		return result_evt_prev_handler(app_event);
+
+	CLEANUP_CTRL_MSG(app_event);
+	return SUCCESS;
+}
+
+/* For requests ESP acks right away and answers later with event_id, once
+ * work is done outside of its Rx path. Event data is left in result_evt */
+static int custom_rpc_request_wait_event(uint32_t req_id, uint8_t *req, uint32_t req_len, uint32_t event_id) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	struct timespec ts = {0};
+	int rc = 0;
+	int ret = SUCCESS;
+
+	result_evt_prev_handler = get_event_callback(CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG);
+	if (set_event_callback(CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG, result_event_handler) != CALLBACK_SET_SUCCESS) {
+		printf("Failed to set custom RPC event handler\n");
+		return FAILURE;
+	}
+
+	/* Set before request, as event may come before response */
+	pthread_mutex_lock(&result_evt.lock);
+	result_evt.event_id = event_id;
+	result_evt.len = 0;
+	result_evt.done = false;
+	result_evt.waiting = true;
+	pthread_mutex_unlock(&result_evt.lock);
+
+	if (test_custom_rpc_unserialised_request(req_id, req, req_len,
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		ret = FAILURE;
+	}
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+
+	pthread_mutex_lock(&result_evt.lock);
+	if (ret == SUCCESS) {
+		clock_gettime(CLOCK_REALTIME, &ts);
+		ts.tv_sec += CUSTOM_RPC_EVENT_TIMEOUT_SEC;
+		while (!result_evt.done && rc != ETIMEDOUT)
+			rc = pthread_cond_timedwait(&result_evt.cond, &result_evt.lock, &ts);
+		if (!result_evt.done) {
+			printf("No result from ESP within %u sec\n", CUSTOM_RPC_EVENT_TIMEOUT_SEC);
+			ret = FAILURE;
+		}
+	}
+	result_evt.waiting = false;
+	pthread_mutex_unlock(&result_evt.lock);
+
+	set_event_callback(CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG, result_evt_prev_handler);
+	return ret;
+}
+
+int custom_rpc_read_flash(const char *label, uint32_t offset, uint32_t len, const char *out_file) {
+	custom_rpc_flash_read_t req = {0};
+	custom_rpc_flash_read_data_t *data = NULL;
+	uint32_t done = 0;
+	FILE *f = NULL;
+	int ret = SUCCESS;
+
+	if (label && strlen(label) >= sizeof(req.label)) {
+		printf("Partition label too long\n");
+		return FAILURE;
+	}
+
+	if (!len) {
+		printf("Length must be non zero\n");
+		return FAILURE;
+	}
+
+	if (out_file) {
+		f = fopen(out_file, "wb");
+		if (!f) {
+			printf("Failed to open %s\n", out_file);
+			return FAILURE;
+		}
+	}
+
+	if (label)
+		strncpy(req.label, label, sizeof(req.label) - 1);
+
+	/* ESP returns at most CUSTOM_RPC_FLASH_READ_MAX_LEN per request */
+	while (done < len) {
+		uint32_t chunk = len - done;
+
+		if (chunk > CUSTOM_RPC_FLASH_READ_MAX_LEN)
+			chunk = CUSTOM_RPC_FLASH_READ_MAX_LEN;
+
+		req.offset = htole32(offset + done);
+		req.len = htole32(chunk);
+
+		if (custom_rpc_request_wait_event(CUSTOM_RPC_REQ_ID__READ_FLASH, (uint8_t *)&req, sizeof(req),
+					CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA) != SUCCESS) {
+			printf("Failed to read flash at offset 0x%" PRIx32 ". Is flash read enabled in ESP firmware?\n",
+					offset + done);
+			ret = FAILURE;
+			break;
+		}
+
+		data = (custom_rpc_flash_read_data_t *)result_evt.data;
+		if (result_evt.len < sizeof(custom_rpc_flash_read_data_t) ||
+		    le32toh(data->offset) != offset + done) {
+			printf("Invalid flash read event of %u bytes\n", result_evt.len);
+			ret = FAILURE;
+		} else if (data->status) {
+			printf("ESP flash read failed: 0x%x\n", (int32_t)le32toh(data->status));
+			ret = FAILURE;
+		} else if (le32toh(data->len) != chunk ||
+		           result_evt.len < sizeof(custom_rpc_flash_read_data_t) + chunk) {
+			printf("Flash read returned %u bytes, expected %" PRIu32 "\n", le32toh(data->len), chunk);
+			ret = FAILURE;
+		} else if (f) {
+			if (fwrite(data->data, 1, chunk, f) != chunk) {
+				printf("Failed to write %s\n", out_file);
+				ret = FAILURE;
+			}
+		} else {
+			print_flash_hexdump(offset + done, data->data, chunk);
+		}
+
+		if (ret != SUCCESS)
+			break;
+		done += chunk;
+	}
+
+	if (f) {
+		fclose(f);
+		if (ret == SUCCESS)
+			printf("Saved %" PRIu32 " bytes to %s\n", done, out_file);
+	}
+	return ret;
+}
+
+int custom_rpc_set_log_level(const char *tag, uint8_t level) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_log_level_t req = {0};
+	int ret = SUCCESS;
+
+	if (tag && strlen(tag) >= sizeof(req.tag)) {
+		printf("Log tag too long, max %zu characters\n", sizeof(req.tag) - 1);
+		return FAILURE;
+	}
+
+	req.level = level;
+	if (tag)
+		strncpy(req.tag, tag, sizeof(req.tag) - 1);
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_LOG_LEVEL, (uint8_t *)&req, sizeof(req),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set ESP log level\n");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_read_adc(uint8_t channel, uint8_t atten, uint16_t *raw, uint16_t *mv) {
+	custom_rpc_adc_read_t req = {0};
+	custom_rpc_adc_reading_t *reading = NULL;
+
+	if (!raw || !mv) {
+		return FAILURE;
+	}
+
+	req.channel = channel;
+	req.atten = atten;
+
+	if (custom_rpc_request_wait_event(CUSTOM_RPC_REQ_ID__READ_ADC, (uint8_t *)&req, sizeof(req),
+				CUSTOM_RPC_EVENT_ID__ADC_READING) != SUCCESS) {
+		printf("Failed to read ADC channel %u. Is ADC supported by ESP chip and firmware?\n", channel);
+		return FAILURE;
+	}
+
+	reading = (custom_rpc_adc_reading_t *)result_evt.data;
+	if (result_evt.len < sizeof(custom_rpc_adc_reading_t)) {
+		printf("Invalid ADC reading of %u bytes\n", result_evt.len);
+		return FAILURE;
+	}
+	if (reading->status) {
+		printf("ESP ADC read failed: 0x%x\n", (int32_t)le32toh(reading->status));
+		return FAILURE;
+	}
+
+	*raw = le16toh(reading->raw);
+	*mv = le16toh(reading->mv);
+	return SUCCESS;
+}
+
+int custom_rpc_read_chip_temp(float *celsius) {
+	custom_rpc_chip_temp_t *temp = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+
+	if (!celsius) {
+		return FAILURE;
+	}
+
+	if (custom_rpc_request_wait_event(CUSTOM_RPC_REQ_ID__READ_CHIP_TEMP, &unused, sizeof(unused),
+				CUSTOM_RPC_EVENT_ID__CHIP_TEMP) != SUCCESS) {
+		printf("Failed to read chip temperature. Does ESP chip have temperature sensor?\n");
+		return FAILURE;
+	}
+
+	temp = (custom_rpc_chip_temp_t *)result_evt.data;
+	if (result_evt.len < sizeof(custom_rpc_chip_temp_t)) {
+		printf("Invalid chip temperature reading of %u bytes\n", result_evt.len);
+		return FAILURE;
+	}
+	if (temp->status) {
+		printf("ESP temperature read failed: 0x%x\n", (int32_t)le32toh(temp->status));
+		return FAILURE;
+	}
+
+	*celsius = (int16_t)le16toh(temp->deci_celsius) / 10.0f;
+	return SUCCESS;
+}
+
+int custom_rpc_set_traffic_filter(uint8_t default_action, const custom_rpc_filter_rule_t *rules, uint8_t num) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	uint8_t buf[sizeof(custom_rpc_traffic_filter_t) +
+		CUSTOM_RPC_FILTER_MAX_RULES * sizeof(custom_rpc_filter_rule_t)] = {0};
+	custom_rpc_traffic_filter_t *req = (custom_rpc_traffic_filter_t *)buf;
+	int ret = SUCCESS;
+
+	if (num > CUSTOM_RPC_FILTER_MAX_RULES || (num && !rules)) {
+		printf("At most %d filter rules supported\n", CUSTOM_RPC_FILTER_MAX_RULES);
+		return FAILURE;
+	}
+
+	req->default_action = default_action;
+	req->num = num;
+	for (int i = 0; i < num; i++) {
+		req->rule[i] = rules[i];
+		req->rule[i].port = htole16(rules[i].port);
+	}
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__SET_TRAFFIC_FILTER, buf,
+			sizeof(custom_rpc_traffic_filter_t) + num * sizeof(custom_rpc_filter_rule_t),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to set traffic filter\n");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+static const char *filter_action_str(uint8_t action) {
+	switch (action) {
+		case CUSTOM_RPC_FILTER_ACTION_FORWARD: return "forward";
+		case CUSTOM_RPC_FILTER_ACTION_DROP:    return "drop";
+		case CUSTOM_RPC_FILTER_ACTION_WAKE:    return "wake";
+		default:                               return "unknown";
+	}
+}
+
+int custom_rpc_get_traffic_filter(void) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_traffic_filter_t *cfg = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	int ret = SUCCESS;
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_TRAFFIC_FILTER, &unused, sizeof(unused),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get traffic filter\n");
+		return FAILURE;
+	}
+
+	cfg = (custom_rpc_traffic_filter_t *)recv_data;
+	if (!cfg || recv_data_len < sizeof(custom_rpc_traffic_filter_t) ||
+	    recv_data_len < sizeof(custom_rpc_traffic_filter_t) + cfg->num * sizeof(custom_rpc_filter_rule_t)) {
+		printf("Invalid traffic filter response of %u bytes\n", recv_data_len);
+		ret = FAILURE;
+		goto cleanup;
+	}
+
+	printf("Default action: %s, dropped: %" PRIu32 " frame(s)\n",
+			filter_action_str(cfg->default_action), le32toh(cfg->dropped));
+	for (int i = 0; i < cfg->num; i++) {
+		custom_rpc_filter_rule_t *r = &cfg->rule[i];
+
+		printf("%d) %s proto %u port %u%s\n", i + 1, filter_action_str(r->action),
+				r->proto, le16toh(r->port), r->multicast ? " multicast only" : "");
+	}
+
+cleanup:
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+/* Names of esp_reset_reason_t values of ESP-IDF */
+static const char *reset_reason_str(uint16_t reason) {
+	static const char *names[] = {
+		"unknown", "power on", "external pin", "software", "panic",
+		"interrupt watchdog", "task watchdog", "other watchdog", "deep sleep wake",
+		"brownout", "sdio", "usb", "jtag", "efuse", "power glitch", "cpu lockup",
+	};
+
+	if (reason < sizeof(names) / sizeof(names[0]))
+		return names[reason];
+	return "unknown";
+}
+
+int custom_rpc_get_event_log(void) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	custom_rpc_event_log_t *log = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	char ts[32] = {0};
+	int ret = SUCCESS;
+
+	if (test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__GET_EVENT_LOG, &unused, sizeof(unused),
+				&recv_data, &recv_data_len, &recv_data_free_func) != SUCCESS) {
+		printf("Failed to get ESP event log\n");
+		return FAILURE;
+	}
+
+	log = (custom_rpc_event_log_t *)recv_data;
+	if (!log || recv_data_len < sizeof(custom_rpc_event_log_t) ||
+	    recv_data_len < sizeof(custom_rpc_event_log_t) + log->num * sizeof(custom_rpc_event_log_entry_t)) {
+		printf("Invalid event log response of %u bytes. Does ESP firmware keep event log?\n", recv_data_len);
+		ret = FAILURE;
+		goto cleanup;
+	}
+
+	printf("ESP boot %" PRIu32 ", %u entr%s, oldest first\n", le32toh(log->boot_count),
+			log->num, log->num == 1 ? "y" : "ies");
+	for (int i = 0; i < log->num; i++) {
+		custom_rpc_event_log_entry_t *e = &log->entry[i];
+		time_t unix_time = le32toh(e->unix_time);
+		uint16_t reason = le16toh(e->reason);
+		uint16_t count = le16toh(e->count);
+
+		if (unix_time)
+			strftime(ts, sizeof(ts), "%Y-%m-%d %H:%M:%S", localtime(&unix_time));
+		else
+			strcpy(ts, "-");
+		printf("boot %-4" PRIu32 " +%-7" PRIu32 "s %-19s ", le32toh(e->boot),
+				le32toh(e->uptime_sec), ts);
+
+		switch (e->type) {
+			case CUSTOM_RPC_EVENT_LOG_RESET:
+				printf("reset: %s[%u]\n", reset_reason_str(reason), reason);
+				break;
+			case CUSTOM_RPC_EVENT_LOG_ASSOC_FAIL:
+				printf("connect failed: reason %u", reason);
+				break;
+			case CUSTOM_RPC_EVENT_LOG_DISCONNECT:
+				printf("disconnected: reason %u", reason);
+				break;
+			default:
+				printf("unknown event %u: reason %u", e->type, reason);
+				break;
+		}
+		if (e->type != CUSTOM_RPC_EVENT_LOG_RESET)
+			printf(count > 1 ? ", %u times, time of last\n" : "\n", count);
+	}
+
+cleanup:
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+int custom_rpc_clear_event_log(void) {
+	uint8_t *recv_data = NULL;
+	uint32_t recv_data_len = 0;
+	void (*recv_data_free_func)(void*) = NULL;
+	/* Request has no payload, but the request API expects some data */
+	uint8_t unused = 0;
+	int ret = SUCCESS;
+
+	ret = test_custom_rpc_unserialised_request(CUSTOM_RPC_REQ_ID__CLEAR_EVENT_LOG, &unused, sizeof(unused),
+			&recv_data, &recv_data_len, &recv_data_free_func);
+	if (ret != SUCCESS) {
+		printf("Failed to clear ESP event log\n");
+	}
+
+	if (recv_data_free_func && recv_data) {
+		recv_data_free_func(recv_data);
+	}
+	return ret;
+}
+
+static void print_probe_req_report(const uint8_t *data, uint32_t len) {
+	const custom_rpc_probe_req_report_t *report = (const custom_rpc_probe_req_report_t *)data;
+	struct timespec now = {0};
+	char ts[32] = {0};
+
+	if (!data || len < sizeof(custom_rpc_probe_req_report_t) ||
+	    len < sizeof(custom_rpc_probe_req_report_t) + report->num * sizeof(custom_rpc_probe_req_entry_t)) {
+		printf("Malformed probe request report of %u bytes\n", len);
+		return;
+	}
+
+	clock_gettime(CLOCK_REALTIME, &now);
+	printf("Probe request report: %u device(s)\n", report->num);
+	for (int i = 0; i < report->num; i++) {
+		const custom_rpc_probe_req_entry_t *e = &report->entry[i];
+		time_t seen = now.tv_sec - le32toh(e->age_ms) / 1000;
+		struct tm tm_seen = {0};
+
+		localtime_r(&seen, &tm_seen);
+		strftime(ts, sizeof(ts), "%Y-%m-%d %H:%M:%S", &tm_seen);
+		printf("  ");
+		for (int j = 0; j < CUSTOM_RPC_PROBE_REQ_HASH_LEN; j++) {
+			printf("%02x", e->mac_hash[j]);
+		}
+		printf(" rssi %d ch %u count %u last seen %s\n",
+				e->rssi, e->channel, le16toh(e->count), ts);
+	}
+}
+
 /* -------------- Demo 3 : Send packed RPC request. Slave echoes back as event. -------------- */
 /* Function to set the reference data for verification */
 static void custom_rpc_set_verification_reference(uint8_t *data, uint32_t len) {
@@ -226,6 +1211,20 @@ int custom_rpc_event_handler(ctrl_cmd_t *app_event) {
 				}
 				break;
 
+			case CUSTOM_RPC_EVENT_ID__VENDOR_IE_RECEIVED:
+				print_vendor_ie_event(p_e->data, p_e->data_len);
+				break;
+
+			case CUSTOM_RPC_EVENT_ID__PROBE_REQ_REPORT:
+				print_probe_req_report(p_e->data, p_e->data_len);
+				break;
+
+			case CUSTOM_RPC_EVENT_ID__FLASH_READ_DATA:
+			case CUSTOM_RPC_EVENT_ID__ADC_READING:
+			case CUSTOM_RPC_EVENT_ID__CHIP_TEMP:
+				/* Late result of request that timed out */
+				break;
+
 			default:
 				printf("[Demo 3] Unhandled custom RPC event ID [%u] with data length: %u bytes\n",
 						p_e->custom_msg_id, p_e->data_len);
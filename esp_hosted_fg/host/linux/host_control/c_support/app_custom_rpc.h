@@ -61,6 +61,259 @@ int custom_rpc_demo2_request_echo_back_as_response(void);
  */
 int custom_rpc_demo3_request_echo_back_as_event(void);
 
+/**
+ * @brief Get details of stations connected to ESP SoftAP
+ *
+ * Prints MAC, RSSI, connected time and rx/tx byte counters of each station
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_softap_get_sta_details(void);
+
+/**
+ * @brief Deauthenticate a station connected to ESP SoftAP
+ *
+ * @param mac MAC address of station in "xx:xx:xx:xx:xx:xx" format
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_softap_kick_sta(const char *mac);
+
+/**
+ * @brief Get 802.11 protocol bitmap of ESP interface
+ *
+ * @param iface CUSTOM_RPC_WIFI_IF_STA or CUSTOM_RPC_WIFI_IF_AP
+ * @param protocol Output, bitmap of CUSTOM_RPC_WIFI_PROTOCOL_* values
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_wifi_protocol(uint8_t iface, uint8_t *protocol);
+
+/**
+ * @brief Set 802.11 protocol bitmap of ESP interface
+ *
+ * Setting CUSTOM_RPC_WIFI_PROTOCOL_LR enables Espressif long range mode,
+ * which only ESP devices can use. Set it before connect or softAP start.
+ *
+ * @param iface CUSTOM_RPC_WIFI_IF_STA or CUSTOM_RPC_WIFI_IF_AP
+ * @param protocol Bitmap of CUSTOM_RPC_WIFI_PROTOCOL_* values
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_wifi_protocol(uint8_t iface, uint8_t protocol);
+
+/**
+ * @brief Get channel bandwidth of ESP interface
+ *
+ * @param iface CUSTOM_RPC_WIFI_IF_STA or CUSTOM_RPC_WIFI_IF_AP
+ * @param bandwidth Output, CUSTOM_RPC_WIFI_BW_HT20 or CUSTOM_RPC_WIFI_BW_HT40
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_wifi_bandwidth(uint8_t iface, uint8_t *bandwidth);
+
+/**
+ * @brief Set channel bandwidth of ESP interface
+ *
+ * @param iface CUSTOM_RPC_WIFI_IF_STA or CUSTOM_RPC_WIFI_IF_AP
+ * @param bandwidth CUSTOM_RPC_WIFI_BW_HT20 or CUSTOM_RPC_WIFI_BW_HT40
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_wifi_bandwidth(uint8_t iface, uint8_t bandwidth);
+
+/**
+ * @brief Get Protected Management Frames setting of ESP interface
+ *
+ * @param iface CUSTOM_RPC_WIFI_IF_STA or CUSTOM_RPC_WIFI_IF_AP
+ * @param capable Output, PMF capable
+ * @param required Output, PMF required
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_pmf_config(uint8_t iface, bool *capable, bool *required);
+
+/**
+ * @brief Set Protected Management Frames setting of ESP interface
+ *
+ * Takes effect on next connect (station) or start (softAP).
+ * required implies capable
+ *
+ * @param iface CUSTOM_RPC_WIFI_IF_STA or CUSTOM_RPC_WIFI_IF_AP
+ * @param capable Advertise PMF support
+ * @param required Refuse peers without PMF
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_pmf_config(uint8_t iface, bool capable, bool required);
+
+/**
+ * @brief Get background scan setting and scan capabilities of ESP
+ *
+ * @param cfg Output, setting in host byte order
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_scan_config(custom_rpc_scan_config_t *cfg);
+
+/**
+ * @brief Set how ESP scans while station is connected
+ *
+ * With background on, scans keep data path of connected station going,
+ * at the cost of fewer probe responses heard per channel
+ *
+ * @param background Short dwell per channel while station is connected
+ * @param dwell_ms Max dwell per channel, 0 for default
+ * @param home_dwell_ms Time on home channel between scanned channels, 0 for default
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_scan_config(bool background, uint16_t dwell_ms, uint8_t home_dwell_ms);
+
+/**
+ * @brief Set which stations may join ESP SoftAP, and client isolation
+ *
+ * Enforced by ESP: stations not permitted are deauthenticated on join and
+ * their frames are dropped. Already joined stations not permitted are
+ * deauthenticated right away. Kept until ESP restarts
+ *
+ * @param mode CUSTOM_RPC_SOFTAP_ACL_OFF, _ALLOW or _DENY
+ * @param isolate Drop frames between SoftAP stations
+ * @param macs Comma separated MACs, up to CUSTOM_RPC_SOFTAP_ACL_MAX_MACS, or NULL
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_softap_acl(uint8_t mode, bool isolate, const char *macs);
+
+/**
+ * @brief Print SoftAP access control and rejected count
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_softap_acl(void);
+
+/**
+ * @brief Report vendor IEs with given OUI received by ESP
+ *
+ * Matching IEs are delivered as CUSTOM_RPC_EVENT_ID__VENDOR_IE_RECEIVED
+ *
+ * @param enable Start or stop reporting
+ * @param oui Vendor OUI as "xx:xx:xx", may be NULL when disabling
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_vendor_ie_monitor(bool enable, const char *oui);
+
+/**
+ * @brief Report probe requests sniffed by ESP
+ *
+ * ESP periodically sends CUSTOM_RPC_EVENT_ID__PROBE_REQ_REPORT with salted
+ * hash of each sender MAC, so individual devices are not identifiable
+ *
+ * @param enable Start or stop reporting
+ * @param report_interval_sec Seconds between reports, 0 for default
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_probe_req_monitor(bool enable, uint8_t report_interval_sec);
+
+/**
+ * @brief Print partition table of ESP flash
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_partition_table(void);
+
+/**
+ * @brief Read ESP flash region, e.g. nvs or otadata partition
+ *
+ * Needs ESP firmware built with CONFIG_ESP_HOSTED_FLASH_READ_RPC.
+ * Data is read raw, so encrypted partitions stay encrypted
+ *
+ * @param label Partition label, NULL or empty to use absolute flash address
+ * @param offset Offset within partition, or flash address without label
+ * @param len Number of bytes to read
+ * @param out_file File to save data to, NULL to print hexdump
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_read_flash(const char *label, uint32_t offset, uint32_t len, const char *out_file);
+
+/**
+ * @brief Set runtime log level of ESP firmware, like esp_log_level_set()
+ *
+ * Levels above CONFIG_LOG_MAXIMUM_LEVEL of ESP firmware have no effect
+ *
+ * @param tag Log tag, e.g. "wifi". NULL, empty or "*" for all tags
+ * @param level CUSTOM_RPC_LOG_NONE to CUSTOM_RPC_LOG_VERBOSE
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_log_level(const char *tag, uint8_t level);
+
+/**
+ * @brief Read ADC1 channel of ESP, averaged over a few samples
+ *
+ * Channel GPIO must not be used by SPI/SDIO transport to host
+ *
+ * @param channel ADC1 channel number
+ * @param atten CUSTOM_RPC_ADC_ATTEN_DB_*, selects input range
+ * @param raw Output, raw reading
+ * @param mv Output, calibrated millivolts, or CUSTOM_RPC_ADC_MV_UNKNOWN
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_read_adc(uint8_t channel, uint8_t atten, uint16_t *raw, uint16_t *mv);
+
+/**
+ * @brief Read internal temperature sensor of ESP
+ *
+ * Not available on ESP32, which has no usable internal sensor
+ *
+ * @param celsius Output, chip temperature
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_read_chip_temp(float *celsius);
+
+/**
+ * @brief Set filter deciding which frames received by ESP station reach host
+ *
+ * Rules are checked in order, first match decides between forward, drop
+ * and wake. Once any wake rule exists, only frames with wake verdict wake
+ * sleeping host. Replaces earlier filter, resets dropped counter
+ *
+ * @param default_action CUSTOM_RPC_FILTER_ACTION_* for frames matching no rule
+ * @param rules Rules, port in host byte order
+ * @param num Number of rules, up to CUSTOM_RPC_FILTER_MAX_RULES. Zero
+ *            rules with forward default disables filter
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_set_traffic_filter(uint8_t default_action, const custom_rpc_filter_rule_t *rules, uint8_t num);
+
+/**
+ * @brief Print current traffic filter and dropped frame count
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_traffic_filter(void);
+
+/**
+ * @brief Print ESP event log kept in ESP flash: resets with reason,
+ * failed connects and disconnects with Wi-Fi reason code
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_get_event_log(void);
+
+/**
+ * @brief Clear ESP event log, and restart its boot count
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int custom_rpc_clear_event_log(void);
+
 /**
  * @brief Custom RPC Event Handler
  *
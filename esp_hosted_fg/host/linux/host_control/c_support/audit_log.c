@@ -0,0 +1,109 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <unistd.h>
+#include <fcntl.h>
+#include <time.h>
+#include <pwd.h>
+#include <syslog.h>
+#include <pthread.h>
+#include <sys/types.h>
+
+#include "test.h"
+#include "redact.h"
+#include "audit_log.h"
+
+#define AUDIT_PATH_LEN      256
+#define AUDIT_RECORD_LEN    1024
+
+static char audit_path[AUDIT_PATH_LEN];
+static bool audit_syslog;
+static pthread_mutex_t audit_lock = PTHREAD_MUTEX_INITIALIZER;
+
+int audit_log_configure(const char *path, bool use_syslog)
+{
+	if (path && strlen(path) >= sizeof(audit_path)) {
+		printf("Audit log path too long\n");
+		return FAILURE;
+	}
+
+	pthread_mutex_lock(&audit_lock);
+	memset(audit_path, 0, sizeof(audit_path));
+	if (path)
+		strncpy(audit_path, path, sizeof(audit_path) - 1);
+	if (use_syslog && !audit_syslog)
+		openlog("esp_hosted", LOG_PID, LOG_AUTH);
+	else if (!use_syslog && audit_syslog)
+		closelog();
+	audit_syslog = use_syslog;
+	pthread_mutex_unlock(&audit_lock);
+
+	return SUCCESS;
+}
+
+/* Login name behind sudo if any, else name of real uid */
+static const char *get_caller(void)
+{
+	const char *sudo_user = getenv("SUDO_USER");
+	struct passwd *pw = NULL;
+
+	if (sudo_user && sudo_user[0])
+		return sudo_user;
+	pw = getpwuid(getuid());
+	return pw ? pw->pw_name : "unknown";
+}
+
+void audit_log_format_cmdline(char *dst, size_t dst_size, int argc, char **argv)
+{
+	char cmdline[AUDIT_RECORD_LEN] = {0};
+	size_t len = 0;
+	bool mask_next = false;
+
+	for (int i = 0; i < argc && argv[i] && len < sizeof(cmdline); i++) {
+		len += snprintf(cmdline + len, sizeof(cmdline) - len, "%s%s",
+				i ? " " : "", mask_next ? REDACT_MASK : argv[i]);
+		mask_next = redact_is_secret_option(argv[i]);
+	}
+	/* Secret may also be given to an option not named like one */
+	redact_known_secrets(dst, dst_size, cmdline);
+}
+
+void audit_log_record(int argc, char **argv, int result)
+{
+	char record[AUDIT_RECORD_LEN] = {0};
+	char cmdline[AUDIT_RECORD_LEN / 2] = {0};
+	char ts[32] = {0};
+	time_t now = time(NULL);
+	struct tm tm_now = {0};
+	int fd = -1;
+
+	if (argc < 1 || !argv || !argv[0])
+		return;
+
+	gmtime_r(&now, &tm_now);
+	strftime(ts, sizeof(ts), "%Y-%m-%dT%H:%M:%SZ", &tm_now);
+	audit_log_format_cmdline(cmdline, sizeof(cmdline), argc, argv);
+	snprintf(record, sizeof(record), "%s user=%s uid=%u cmd=\"%s\" result=%s\n",
+			ts, get_caller(), (unsigned)getuid(), cmdline,
+			result == SUCCESS ? "success" : "failure");
+
+	pthread_mutex_lock(&audit_lock);
+	if (audit_path[0]) {
+		/* O_APPEND, so records are never overwritten, also with
+		 * `chattr +a` set on the file */
+		fd = open(audit_path, O_WRONLY | O_APPEND | O_CREAT | O_CLOEXEC, 0640);
+		if (fd < 0 || write(fd, record, strlen(record)) < 0) {
+			printf("Failed to write audit log %s\n", audit_path);
+		}
+		if (fd >= 0)
+			close(fd);
+	}
+	if (audit_syslog) {
+		/* Without timestamp and newline, syslog adds its own */
+		syslog(LOG_NOTICE, "%.*s", (int)(strlen(record) - strlen(ts) - 2),
+				record + strlen(ts) + 1);
+	}
+	pthread_mutex_unlock(&audit_lock);
+}
@@ -0,0 +1,39 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef AUDIT_LOG_H
+#define AUDIT_LOG_H
+
+#include <stdbool.h>
+#include <stddef.h>
+
+/**
+ * @brief Configure audit log destinations
+ *
+ * @param path File to append records to, NULL or empty to not write file
+ * @param use_syslog Also send records to syslog, facility LOG_AUTH
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int audit_log_configure(const char *path, bool use_syslog);
+
+/**
+ * @brief Join operation name and arguments into dst, masking value
+ * following any option taking a secret (see redact_is_secret_option())
+ * and secrets given to redact_add_secret()
+ */
+void audit_log_format_cmdline(char *dst, size_t dst_size, int argc, char **argv);
+
+/**
+ * @brief Append one record of an operation
+ *
+ * Record is single line with UTC time, caller (sudo user if any, and
+ * uid), command line and result, masked as by
+ * audit_log_format_cmdline()
+ *
+ * @param argc Argument count, argv[0] is operation name
+ * @param argv Operation name and arguments
+ * @param result SUCCESS or FAILURE returned by operation
+ */
+void audit_log_record(int argc, char **argv, int result);
+
+#endif
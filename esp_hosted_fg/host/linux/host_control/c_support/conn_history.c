@@ -0,0 +1,72 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+
+#include "test.h"
+#include "conn_history.h"
+
+static conn_history_entry_t history[CONN_HISTORY_MAX_ENTRIES];
+/* Index of oldest entry and number of entries in ring */
+static int history_head;
+static int history_count;
+/* Monotonic time of last successful connect, to tell link uptime */
+static struct timespec link_up_ts;
+static bool link_up;
+static pthread_mutex_t history_lock = PTHREAD_MUTEX_INITIALIZER;
+
+static uint32_t elapsed_ms(const struct timespec *since)
+{
+	struct timespec now = {0};
+
+	clock_gettime(CLOCK_MONOTONIC, &now);
+	return (now.tv_sec - since->tv_sec) * 1000 +
+		(now.tv_nsec - since->tv_nsec) / 1000000;
+}
+
+void conn_history_add(conn_history_entry_t *entry)
+{
+	if (!entry)
+		return;
+
+	if (!entry->time)
+		entry->time = time(NULL);
+	entry->mono_ms = get_mono_ms();
+
+	pthread_mutex_lock(&history_lock);
+	if (entry->type == CONN_HISTORY_CONNECT && entry->status == SUCCESS) {
+		clock_gettime(CLOCK_MONOTONIC, &link_up_ts);
+		link_up = true;
+	} else if (entry->type == CONN_HISTORY_DISCONNECT) {
+		entry->duration_ms = link_up ? elapsed_ms(&link_up_ts) : 0;
+		link_up = false;
+	}
+
+	history[(history_head + history_count) % CONN_HISTORY_MAX_ENTRIES] = *entry;
+	if (history_count < CONN_HISTORY_MAX_ENTRIES)
+		history_count++;
+	else
+		history_head = (history_head + 1) % CONN_HISTORY_MAX_ENTRIES;
+	pthread_mutex_unlock(&history_lock);
+}
+
+int conn_history_get(conn_history_entry_t *entries, int max)
+{
+	int n = 0;
+
+	if (!entries || max <= 0)
+		return 0;
+
+	pthread_mutex_lock(&history_lock);
+	/* Skip oldest ones if output is smaller than history */
+	int skip = history_count > max ? history_count - max : 0;
+
+	for (int i = skip; i < history_count; i++)
+		entries[n++] = history[(history_head + i) % CONN_HISTORY_MAX_ENTRIES];
+	pthread_mutex_unlock(&history_lock);
+
+	return n;
+}
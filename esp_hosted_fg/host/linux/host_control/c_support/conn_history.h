@@ -0,0 +1,56 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef CONN_HISTORY_H
+#define CONN_HISTORY_H
+
+#include <stdint.h>
+#include <time.h>
+#include "ctrl_api.h"
+
+#define CONN_HISTORY_MAX_ENTRIES         32
+/* ESP reports RSSI as negative dBm, so 0 is never a real value */
+#define CONN_HISTORY_RSSI_UNKNOWN        0
+/* Wall clock and monotonic deltas differing more than this means host clock was set */
+#define CONN_HISTORY_CLOCK_SKEW_SEC      2
+
+typedef enum {
+	CONN_HISTORY_CONNECT,
+	CONN_HISTORY_DISCONNECT,
+} conn_history_type_e;
+
+typedef struct {
+	int type;
+	time_t time;
+	/* get_mono_ms() at time of entry, to order entries across host clock changes */
+	uint64_t mono_ms;
+	/* Connect: request to response. Disconnect: time link was up, 0 if not known */
+	uint32_t duration_ms;
+	char ssid[SSID_LENGTH];
+	char bssid[BSSID_STR_SIZE];
+	int rssi;
+	/* Connect: SUCCESS or CTRL_ERR_*. Disconnect: 802.11 reason code from ESP */
+	int status;
+} conn_history_entry_t;
+
+/**
+ * @brief Append entry, overwriting oldest once CONN_HISTORY_MAX_ENTRIES
+ * are kept
+ *
+ * For disconnect entries, duration_ms is filled in from last successful
+ * connect entry
+ *
+ * @param entry Entry to add, time is set to now if 0. mono_ms is always set
+ */
+void conn_history_add(conn_history_entry_t *entry);
+
+/**
+ * @brief Copy entries, oldest first
+ *
+ * @param entries Output array
+ * @param max Size of entries
+ *
+ * @return Number of entries copied
+ */
+int conn_history_get(conn_history_entry_t *entries, int max);
+
+#endif
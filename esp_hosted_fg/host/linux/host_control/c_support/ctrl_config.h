@@ -66,6 +66,9 @@
 #define CUSTOM_RPC_DEMO2                   "send_packed_data__echo_back_as_response"
 #define CUSTOM_RPC_DEMO3                   "send_packed_data__echo_back_as_event"
 
+/* Print events until interrupted, "--json" prints one JSON object per line */
+#define EVENTS                             "events"
+
 #ifndef SSID_LENGTH
 #define SSID_LENGTH                         33
 #endif
@@ -125,4 +128,27 @@
 
 #define TEST_DEBUG_PRINTS                   1
 
+/* Oldest ESP firmware ("major_1.major_2.minor") host app works with.
+ * Checked once RPC is up. If ESP firmware is older, only a warning is
+ * printed, unless MIN_FIRMWARE_VERSION_ENFORCE is 1, then host app stops */
+#define MIN_FIRMWARE_VERSION                "1.0.0"
+#define MIN_FIRMWARE_VERSION_ENFORCE        0
+
+/* hosted_shell appends state changing commands with time, caller and
+ * result to AUDIT_LOG_FILE ("" disables), and to syslog (LOG_AUTH) if
+ * AUDIT_LOG_SYSLOG is 1 */
+#define AUDIT_LOG_FILE                      "/var/log/esp_hosted_audit.log"
+#define AUDIT_LOG_SYSLOG                    0
+
+/* Defaults of hosted_shell link_recovery: act after this many missed
+ * heartbeats or request timeouts in a row, waiting LINK_RECOVERY_COOLDOWN_SEC
+ * between escalation steps */
+#define LINK_RECOVERY_MISSED_HEARTBEATS     3
+#define LINK_RECOVERY_TIMEOUTS              2
+#define LINK_RECOVERY_COOLDOWN_SEC          60
+
+/* Defaults of hosted_shell guest_ap */
+#define GUEST_AP_SSID                       "ESPWifi-Guest"
+#define GUEST_AP_DURATION_SEC               3600
+
 #endif
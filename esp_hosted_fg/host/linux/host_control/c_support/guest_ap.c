@@ -0,0 +1,227 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+
+#include "test.h"
+#include "guest_ap.h"
+
+/* Letters and digits, without look-alikes (0/O, 1/l/I) for reading aloud */
+static const char pwd_chars[] = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789";
+
+static long expire_sec;
+static bool rpc_ready;
+
+static pthread_t guest_thread;
+static bool guest_thread_created;
+static bool guest_running;
+static pthread_mutex_t guest_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t guest_cond = PTHREAD_COND_INITIALIZER;
+
+static long mono_sec(void)
+{
+	struct timespec ts = {0};
+
+	clock_gettime(CLOCK_MONOTONIC, &ts);
+	return ts.tv_sec;
+}
+
+static int generate_password(char *pwd, size_t len)
+{
+	unsigned char byte = 0;
+	size_t i = 0;
+	FILE *f = fopen("/dev/urandom", "r");
+
+	if (!f) {
+		printf("Failed to open /dev/urandom\n");
+		return FAILURE;
+	}
+
+	while (i < len) {
+		if (fread(&byte, 1, 1, f) != 1) {
+			fclose(f);
+			printf("Failed to read /dev/urandom\n");
+			return FAILURE;
+		}
+		/* Drop bytes past last full multiple of charset size, to keep
+		 * every character equally likely */
+		if (byte >= 256 - 256 % (sizeof(pwd_chars) - 1))
+			continue;
+		pwd[i++] = pwd_chars[byte % (sizeof(pwd_chars) - 1)];
+	}
+	pwd[i] = '\0';
+
+	fclose(f);
+	return SUCCESS;
+}
+
+static void wait_sec(long sec)
+{
+	struct timespec deadline = {0};
+
+	clock_gettime(CLOCK_REALTIME, &deadline);
+	deadline.tv_sec += sec;
+	while (guest_running &&
+	       pthread_cond_timedwait(&guest_cond, &guest_lock, &deadline) != ETIMEDOUT)
+		;
+}
+
+static void *guest_thread_handler(void *arg)
+{
+	pthread_mutex_lock(&guest_lock);
+	while (guest_running) {
+		long left = expire_sec - mono_sec();
+		int ret = FAILURE;
+
+		if (left > 0) {
+			wait_sec(left);
+			continue;
+		}
+
+		/* Stop once control path is back, e.g. after link recovery */
+		if (!rpc_ready) {
+			wait_sec(GUEST_AP_RETRY_SEC);
+			continue;
+		}
+
+		pthread_mutex_unlock(&guest_lock);
+		ret = test_softap_mode_stop();
+		pthread_mutex_lock(&guest_lock);
+		if (ret == SUCCESS) {
+			printf("Guest AP expired, SoftAP stopped\n");
+			guest_running = false;
+			break;
+		}
+		printf("Guest AP expired, failed to stop SoftAP, retrying in %ds\n", GUEST_AP_RETRY_SEC);
+		wait_sec(GUEST_AP_RETRY_SEC);
+	}
+	pthread_mutex_unlock(&guest_lock);
+
+	return NULL;
+}
+
+/* Returns if guest AP was still running */
+static bool stop_timer(void)
+{
+	bool was_running = false;
+
+	pthread_mutex_lock(&guest_lock);
+	was_running = guest_running;
+	guest_running = false;
+	pthread_cond_signal(&guest_cond);
+	pthread_mutex_unlock(&guest_lock);
+
+	if (guest_thread_created) {
+		pthread_join(guest_thread, NULL);
+		guest_thread_created = false;
+	}
+	return was_running;
+}
+
+int guest_ap_start(const char *ssid, const char *pwd, int duration_sec,
+		char *pwd_out, size_t pwd_out_size)
+{
+	char gen_pwd[GUEST_AP_PASSWORD_LEN + 1] = {0};
+
+	if (!ssid || !*ssid || !pwd_out) {
+		printf("Invalid guest AP config\n");
+		return FAILURE;
+	}
+
+	if (duration_sec < GUEST_AP_MIN_DURATION_SEC || duration_sec > GUEST_AP_MAX_DURATION_SEC) {
+		printf("Guest AP duration must be %d to %d seconds\n",
+				GUEST_AP_MIN_DURATION_SEC, GUEST_AP_MAX_DURATION_SEC);
+		return FAILURE;
+	}
+
+	/* WPA2 passphrase is 8 to 63 characters */
+	if (pwd && (strlen(pwd) < 8 || strlen(pwd) > 63)) {
+		printf("Guest AP password must be 8 to 63 characters\n");
+		return FAILURE;
+	}
+
+	if (!pwd) {
+		if (generate_password(gen_pwd, GUEST_AP_PASSWORD_LEN) != SUCCESS)
+			return FAILURE;
+		pwd = gen_pwd;
+	}
+
+	if (strlen(pwd) >= pwd_out_size) {
+		printf("Guest AP password buffer too small\n");
+		return FAILURE;
+	}
+
+	/* Replaced SoftAP config takes over, only the timer goes */
+	stop_timer();
+
+	if (test_softap_mode_start_with_params(ssid, pwd, 0, "wpa2_psk", 0,
+				false, 0, 0) != SUCCESS) {
+		printf("Failed to start guest AP\n");
+		return FAILURE;
+	}
+
+	pthread_mutex_lock(&guest_lock);
+	expire_sec = mono_sec() + duration_sec;
+	guest_running = true;
+	pthread_mutex_unlock(&guest_lock);
+
+	if (pthread_create(&guest_thread, NULL, guest_thread_handler, NULL) != 0) {
+		printf("Failed to create guest AP thread, stopping SoftAP\n");
+		guest_running = false;
+		test_softap_mode_stop();
+		return FAILURE;
+	}
+	guest_thread_created = true;
+
+	strcpy(pwd_out, pwd);
+	return SUCCESS;
+}
+
+int guest_ap_remaining_sec(void)
+{
+	int left = -1;
+
+	pthread_mutex_lock(&guest_lock);
+	if (guest_running) {
+		left = expire_sec - mono_sec();
+		if (left < 0)
+			left = 0;
+	}
+	pthread_mutex_unlock(&guest_lock);
+
+	return left;
+}
+
+void guest_ap_rpc_ready(void)
+{
+	pthread_mutex_lock(&guest_lock);
+	rpc_ready = true;
+	pthread_mutex_unlock(&guest_lock);
+}
+
+void guest_ap_rpc_down(void)
+{
+	pthread_mutex_lock(&guest_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&guest_lock);
+}
+
+void guest_ap_stop(void)
+{
+	bool stop_softap = false;
+
+	if (!stop_timer())
+		return;
+
+	pthread_mutex_lock(&guest_lock);
+	stop_softap = rpc_ready;
+	pthread_mutex_unlock(&guest_lock);
+
+	if (stop_softap && test_softap_mode_stop() == SUCCESS)
+		printf("Guest AP stopped\n");
+}
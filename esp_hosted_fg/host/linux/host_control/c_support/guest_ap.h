@@ -0,0 +1,53 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef GUEST_AP_H
+#define GUEST_AP_H
+
+#include <stddef.h>
+
+#define GUEST_AP_PASSWORD_LEN            12
+#define GUEST_AP_MIN_DURATION_SEC        60
+#define GUEST_AP_MAX_DURATION_SEC        (24 * 60 * 60)
+/* Retry interval when SoftAP could not be stopped on expiry */
+#define GUEST_AP_RETRY_SEC               10
+
+/**
+ * @brief Start SoftAP for a limited time, e.g. for service access
+ *
+ * SoftAP uses WPA2 and ctrl_config.h defaults for the rest. It is stopped
+ * once duration_sec passes, or by guest_ap_stop(). Starting again
+ * replaces running guest AP and its timer
+ *
+ * @param ssid SSID of guest AP
+ * @param pwd Password, NULL to generate random one of GUEST_AP_PASSWORD_LEN
+ * @param duration_sec Seconds to keep SoftAP up
+ * @param pwd_out Filled with password in use, at least GUEST_AP_PASSWORD_LEN + 1
+ * @param pwd_out_size Size of pwd_out
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int guest_ap_start(const char *ssid, const char *pwd, int duration_sec,
+		char *pwd_out, size_t pwd_out_size);
+
+/**
+ * @brief Seconds left until guest AP is stopped, -1 if none is running
+ */
+int guest_ap_remaining_sec(void);
+
+/**
+ * @brief Tell guest AP that control path is usable, so SoftAP can be stopped
+ */
+void guest_ap_rpc_ready(void);
+
+/**
+ * @brief Tell guest AP that control path is down. Timer keeps running,
+ * SoftAP is stopped once control path is back
+ */
+void guest_ap_rpc_down(void);
+
+/**
+ * @brief Stop guest AP timer, and SoftAP too if control path is up
+ */
+void guest_ap_stop(void);
+
+#endif
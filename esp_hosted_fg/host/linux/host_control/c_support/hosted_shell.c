@@ -19,15 +19,35 @@
 #include <time.h>
 #include <replxx.h>
 #include <stdbool.h>
+#include <arpa/inet.h>
 #include "nw_helper_func.h"
 #include "esp_hosted_custom_rpc.h"
 #include "app_custom_rpc.h"
+#include "rogue_ap_watch.h"
+#include "webhook_notify.h"
+#include "wifi_schedule.h"
+#include "scan_export.h"
+#include "scan_cache.h"
+#include "audit_log.h"
+#include "conn_history.h"
+#include "link_recovery.h"
+#include "support_bundle.h"
+#include "redact.h"
+#include "guest_ap.h"
+#include "reconnect_test.h"
+#include "link_quality.h"
+#include "status_led.h"
+#include "udp_echo.h"
 #include <stdint.h>
 
 
 #define MAC_ADDR_LENGTH 18
 #define NETWORK_CHECK_INTERVAL_MS 100
+/* IP change check for webhook, in NETWORK_CHECK_INTERVAL_MS ticks */
+#define WEBHOOK_IP_CHECK_TICKS    20
 #define RPC_RETRY_INTERVAL_MS     1000
+/* How long to wait for replies to ARP probe */
+#define IP_CONFLICT_PROBE_MS      1000
 
 /* Define WiFi band mode constants */
 #define WIFI_BAND_MODE_AUTO 3
@@ -132,6 +152,15 @@ static const char *get_arg_value(int argc, char **argv, const cmd_arg_t *args, i
 	return NULL;
 }
 
+/* Helper function to get index of choice argument value */
+static int get_choice_index(const char **choices, const char *value) {
+	for (int i = 0; choices[i]; i++) {
+		if (strcasecmp(choices[i], value) == 0)
+			return i;
+	}
+	return -1;
+}
+
 /* Helper function to parse and validate arguments */
 static bool parse_arguments(int argc, char **argv, const cmd_arg_t *args, int arg_count) {
 	bool result = true;
@@ -248,7 +277,23 @@ static const char *wifi_mode_choices[] = {"station", "softap", "station+softap",
 static const char *wifi_powersave_choices[] = {"none", "min", "max", NULL};
 static const char *wifi_interface_choices[] = {"station", "softap", NULL};
 static const char *wifi_band_mode_choices[] = {"2.4G", "5G", "auto", NULL};
+/* Same strings as wifi_channel_to_band() */
+static const char *scan_band_choices[] = {"2.4G", "5G", NULL};
 static const char *wifi_sec_prot_choices[] = {"open", "wpa_psk", "wpa2_psk", "wpa_wpa2_psk", NULL};
+static const char *wifi_bandwidth_choices[] = {"20", "40", NULL};
+static const char *vendor_ie_type_choices[] = {"beacon", "probe_req", "probe_resp", "assoc_req", "assoc_resp", NULL};
+/* In order of CUSTOM_RPC_ADC_ATTEN_DB_* */
+static const char *adc_atten_choices[] = {"0", "2.5", "6", "12", NULL};
+/* In order of CUSTOM_RPC_FILTER_ACTION_* */
+static const char *filter_action_choices[] = {"forward", "drop", "wake", NULL};
+/* In order of CUSTOM_RPC_SOFTAP_ACL_* */
+static const char *softap_acl_choices[] = {"off", "allow", "deny", NULL};
+/* In order of CUSTOM_RPC_LOG_* */
+static const char *log_level_choices[] = {"none", "error", "warn", "info", "debug", "verbose", NULL};
+/* In order of ctrl_event_overflow_e, from CTRL_EVENT_OVERFLOW_DROP_OLDEST */
+static const char *event_overflow_choices[] = {"drop_oldest", "drop_newest", "block", NULL};
+/* In order of status_led_pattern_e */
+static const char *led_pattern_choices[] = {"off", "on", "slow_blink", "fast_blink", "double_blink", NULL};
 
 /* Define command arguments */
 static const cmd_arg_t wifi_set_mode_args[] = {
@@ -264,7 +309,7 @@ static const cmd_arg_t connect_ap_args[] = {
 	{"--ssid", "SSID of AP", ARG_TYPE_STRING, true, NULL},
 	{"--password", "Password of AP", ARG_TYPE_STRING, true, NULL},
 	{"--bssid", "MAC address of AP", ARG_TYPE_STRING, false, NULL},
-	{"--use_wpa3", "Use WPA3 security protocol", ARG_TYPE_BOOL, false, NULL},
+	{"--use_wpa3", "Use WPA3 (SAE), also for WPA2/WPA3 transition APs", ARG_TYPE_BOOL, false, NULL},
 	{"--listen_interval", "Number of AP beacons station will sleep", ARG_TYPE_INT, false, NULL},
 	{"--run_dhcp_client", "Request DHCP", ARG_TYPE_BOOL, false, NULL},
 	{"--band_mode", "Connect on 2.4G or 5G band", ARG_TYPE_CHOICE, false, wifi_band_mode_choices}
@@ -276,7 +321,150 @@ static const cmd_arg_t disconnect_ap_args[] = {
 
 static const cmd_arg_t softap_vendor_ie_args[] = {
 	{"--enable", "Set or Reset Vendor IE", ARG_TYPE_BOOL, true, NULL},
-	{"--data", "String to set in softap Wi-Fi broadcast beacon", ARG_TYPE_STRING, false, NULL}
+	{"--data", "String to set in softap Wi-Fi broadcast beacon", ARG_TYPE_STRING, false, NULL},
+	{"--type", "Frame to add IE to (default: beacon)", ARG_TYPE_CHOICE, false, vendor_ie_type_choices},
+	{"--idx", "Vendor IE index [0|1]", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t probe_req_monitor_args[] = {
+	{"--enable", "Enable or disable probe request reporting", ARG_TYPE_BOOL, true, NULL},
+	{"--interval", "Seconds between reports [1-255] (default: 10)", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t set_pmf_args[] = {
+	{"--mode", "Interface [station, softap]", ARG_TYPE_CHOICE, true, wifi_interface_choices},
+	{"--capable", "Advertise PMF support", ARG_TYPE_BOOL, false, NULL},
+	{"--required", "Refuse peers without PMF", ARG_TYPE_BOOL, false, NULL}
+};
+
+static const cmd_arg_t set_scan_config_args[] = {
+	{"--background", "Short dwell per channel while station is connected", ARG_TYPE_BOOL, true, NULL},
+	{"--dwell", "Max dwell per channel in ms [10-120], default 60", ARG_TYPE_INT, false, NULL},
+	{"--home_dwell", "Time on home channel between channels in ms [30-150], default 30", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t read_flash_args[] = {
+	{"--partition", "Partition label, e.g. nvs. Without it offset is flash address", ARG_TYPE_STRING, false, NULL},
+	{"--offset", "Offset to read from, decimal or 0x hex (default: 0)", ARG_TYPE_STRING, false, NULL},
+	{"--len", "Number of bytes to read, decimal or 0x hex", ARG_TYPE_STRING, true, NULL},
+	{"--file", "Save to file instead of printing hexdump", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t set_esp_log_level_args[] = {
+	{"--level", "Log level [none, error, warn, info, debug, verbose]", ARG_TYPE_CHOICE, true, log_level_choices},
+	{"--tag", "Log tag, e.g. wifi (default: all tags)", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t read_adc_args[] = {
+	{"--channel", "ADC1 channel number", ARG_TYPE_INT, true, NULL},
+	{"--atten", "Attenuation in dB [0, 2.5, 6, 12] (default: 12, widest range)", ARG_TYPE_CHOICE, false, adc_atten_choices}
+};
+
+static const cmd_arg_t get_available_ap_args[] = {
+	{"--group", "One entry per SSID with best AP on each band", ARG_TYPE_BOOL, false, NULL},
+	{"--band", "Only APs on band [2.4G, 5G]", ARG_TYPE_CHOICE, false, scan_band_choices}
+};
+
+static const cmd_arg_t scan_cache_args[] = {
+	{"--enable", "Enable or disable background scans", ARG_TYPE_BOOL, true, NULL},
+	{"--interval", "Seconds between scans (default: 60, min: 30)", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t site_survey_args[] = {
+	{"--file", "CSV file to append samples to", ARG_TYPE_STRING, true, NULL},
+	{"--location", "Label of current location, e.g. hall-east", ARG_TYPE_STRING, true, NULL},
+	{"--samples", "Number of scans at this location [1-100] (default: 5)", ARG_TYPE_INT, false, NULL},
+	{"--ssid", "Only sample APs of this SSID", ARG_TYPE_STRING, false, NULL},
+	{"--lat", "Latitude of this location in degrees, e.g. from GPS", ARG_TYPE_STRING, false, NULL},
+	{"--lon", "Longitude of this location in degrees", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t export_scan_args[] = {
+	{"--file", "Output JSON file, '-' for console", ARG_TYPE_STRING, true, NULL},
+	{"--lat", "Latitude of this location in degrees, e.g. from GPS", ARG_TYPE_STRING, false, NULL},
+	{"--lon", "Longitude of this location in degrees", ARG_TYPE_STRING, false, NULL},
+	{"--accuracy", "Position accuracy radius in meters", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t set_traffic_filter_args[] = {
+	{"--default", "Action for frames matching no rule [forward, drop, wake]", ARG_TYPE_CHOICE, true, filter_action_choices},
+	{"--rules", "action:proto[:port][:mcast],... e.g. wake:tcp:22,drop:udp:0:mcast", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t webhook_args[] = {
+	{"--url", "URL to POST link event JSON to, 'none' to disable", ARG_TYPE_STRING, true, NULL},
+	{"--interface", "Send through this interface instead of default route", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t rogue_ap_watch_args[] = {
+	{"--enable", "Enable or disable rogue AP watcher", ARG_TYPE_BOOL, true, NULL},
+	{"--ssid", "SSID to protect", ARG_TYPE_STRING, false, NULL},
+	{"--interval", "Seconds between scans (default: 60, min: 30)", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t guest_ap_args[] = {
+	{"--enable", "Start or stop guest AP", ARG_TYPE_BOOL, true, NULL},
+	{"--duration", "Seconds to keep guest AP up (default: 3600)", ARG_TYPE_INT, false, NULL},
+	{"--ssid", "SSID of guest AP", ARG_TYPE_STRING, false, NULL},
+	{"--password", "Password, random if not given", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t status_led_args[] = {
+	{"--enable", "Enable or disable link state on host LED", ARG_TYPE_BOOL, true, NULL},
+	{"--led", "LED name under /sys/class/leds", ARG_TYPE_STRING, false, NULL},
+	{"--connected", "Pattern when connected with IP (default: on)", ARG_TYPE_CHOICE, false, led_pattern_choices},
+	{"--no_ip", "Pattern when connected without IP (default: double_blink)", ARG_TYPE_CHOICE, false, led_pattern_choices},
+	{"--wifi_down", "Pattern when station not connected (default: slow_blink)", ARG_TYPE_CHOICE, false, led_pattern_choices},
+	{"--esp_down", "Pattern when ESP not responding (default: fast_blink)", ARG_TYPE_CHOICE, false, led_pattern_choices}
+};
+
+static const cmd_arg_t udp_echo_args[] = {
+	{"--enable", "Answer UDP latency probes from other units", ARG_TYPE_BOOL, true, NULL},
+	{"--port", "UDP port (default: 7007)", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t udp_probe_args[] = {
+	{"--host", "IPv4 address of unit running udp_echo", ARG_TYPE_STRING, true, NULL},
+	{"--port", "UDP port (default: 7007)", ARG_TYPE_INT, false, NULL},
+	{"--count", "Probes to send (default: 20, max: 1000)", ARG_TYPE_INT, false, NULL},
+	{"--interval", "Milliseconds between probes (default: 100, min: 10)", ARG_TYPE_INT, false, NULL},
+	{"--timeout", "Milliseconds to wait for replies after last probe (default: 1000)", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t link_recovery_args[] = {
+	{"--enable", "Enable or disable recovery of unresponsive ESP", ARG_TYPE_BOOL, true, NULL},
+	{"--missed_heartbeats", "Missed heartbeats in a row to act on, 0 to ignore (default: 3)", ARG_TYPE_INT, false, NULL},
+	{"--timeouts", "Request timeouts in a row to act on, 0 to ignore (default: 2)", ARG_TYPE_INT, false, NULL},
+	{"--cooldown", "Seconds between escalation steps (default: 60)", ARG_TYPE_INT, false, NULL},
+	{"--reset_cmd", "Shell command resetting ESP, e.g. reloading driver", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t support_bundle_args[] = {
+	{"--file", "Bundle file to write", ARG_TYPE_STRING, true, NULL}
+};
+
+static const cmd_arg_t wifi_schedule_args[] = {
+	{"--enable", "Enable or disable Wi-Fi schedule", ARG_TYPE_BOOL, true, NULL},
+	{"--windows", "Daily local time windows, e.g. 06:00-06:15,18:00-18:30", ARG_TYPE_STRING, false, NULL},
+	{"--ssid", "SSID to connect in window", ARG_TYPE_STRING, false, NULL},
+	{"--password", "Password to connect in window", ARG_TYPE_STRING, false, NULL}
+};
+
+static const cmd_arg_t reconnect_test_args[] = {
+	{"--enable", "Enable or disable daily reconnect test", ARG_TYPE_BOOL, true, NULL},
+	{"--windows", "Daily local time windows to test in, e.g. 03:00-04:00", ARG_TYPE_STRING, false, NULL},
+	{"--ssid", "SSID to reconnect to", ARG_TYPE_STRING, false, NULL},
+	{"--password", "Password of AP", ARG_TYPE_STRING, false, NULL},
+	{"--run_now", "Also run test right away", ARG_TYPE_BOOL, false, NULL}
+};
+
+static const cmd_arg_t wifi_wake_args[] = {
+	{"--duration", "Seconds to keep Wi-Fi on from now", ARG_TYPE_INT, true, NULL}
+};
+
+static const cmd_arg_t vendor_ie_monitor_args[] = {
+	{"--enable", "Enable or disable reporting of received vendor IEs", ARG_TYPE_BOOL, true, NULL},
+	{"--oui", "Vendor OUI to report, e.g. 01:02:03", ARG_TYPE_STRING, false, NULL}
 };
 
 static const cmd_arg_t start_softap_args[] = {
@@ -303,6 +491,31 @@ static const cmd_arg_t ota_update_args[] = {
 	{"--url", "URL of ESP firmware binary", ARG_TYPE_STRING, true, NULL}
 };
 
+static const cmd_arg_t check_ip_conflict_args[] = {
+	{"--interface", "Interface to probe on (default: ethsta0)", ARG_TYPE_STRING, false, NULL},
+	{"--ip", "IPv4 address to check (default: address of interface)", ARG_TYPE_STRING, false, NULL},
+	{"--auto", "Also check station address each time it changes", ARG_TYPE_BOOL, false, NULL}
+};
+
+static const cmd_arg_t dry_run_args[] = {
+	{"--enable", "Only check and print state changing commands", ARG_TYPE_BOOL, true, NULL}
+};
+
+static const cmd_arg_t event_queue_args[] = {
+	{"--depth", "Events to hold for callbacks, set once (1-256)", ARG_TYPE_INT, true, NULL},
+	{"--overflow", "When full [drop_oldest, drop_newest, block]", ARG_TYPE_CHOICE, false, event_overflow_choices}
+};
+
+static const cmd_arg_t ctrl_rate_limit_args[] = {
+	{"--rate", "Requests per second to ESP, 0 to remove limit", ARG_TYPE_INT, true, NULL},
+	{"--burst", "Requests allowed back to back (default: 5)", ARG_TYPE_INT, false, NULL},
+	{"--max_delay", "Longest a request waits in ms, else fails (default: 1000)", ARG_TYPE_INT, false, NULL}
+};
+
+static const cmd_arg_t strict_mode_args[] = {
+	{"--enable", "Report each message from ESP dropped as unexpected", ARG_TYPE_BOOL, true, NULL}
+};
+
 static const cmd_arg_t heartbeat_args[] = {
 	{"--enable", "Enable or disable heartbeat", ARG_TYPE_BOOL, false, NULL},
 	{"--duration", "Heartbeat duration in seconds", ARG_TYPE_INT, false, NULL}
@@ -336,6 +549,39 @@ static const cmd_arg_t set_country_code_args[] = {
 	{"--code", "Country code (e.g. US, IN, CN)", ARG_TYPE_STRING, true, NULL}
 };
 
+static const cmd_arg_t softap_kick_sta_args[] = {
+	{"--mac", "MAC address of station to disconnect", ARG_TYPE_STRING, true, NULL}
+};
+
+static const cmd_arg_t softap_acl_args[] = {
+	{"--mode", "Stations that may join [off, allow, deny]", ARG_TYPE_CHOICE, true, softap_acl_choices},
+	{"--macs", "Comma separated MACs to allow or deny", ARG_TYPE_STRING, false, NULL},
+	{"--isolate", "Drop frames between SoftAP stations", ARG_TYPE_BOOL, false, NULL}
+};
+
+static const cmd_arg_t get_wifi_protocol_args[] = {
+	{"--mode", "Interface [station, softap]", ARG_TYPE_CHOICE, true, wifi_interface_choices}
+};
+
+static const cmd_arg_t set_wifi_long_range_args[] = {
+	{"--mode", "Interface [station, softap]", ARG_TYPE_CHOICE, true, wifi_interface_choices},
+	{"--enable", "Enable or disable Espressif long range (LR) mode", ARG_TYPE_BOOL, true, NULL}
+};
+
+static const cmd_arg_t set_wifi_protocol_args[] = {
+	{"--mode", "Interface [station, softap]", ARG_TYPE_CHOICE, true, wifi_interface_choices},
+	{"--protocols", "Comma separated protocols from b, g, n, lr (e.g. b,g,n)", ARG_TYPE_STRING, true, NULL}
+};
+
+static const cmd_arg_t set_wifi_bandwidth_args[] = {
+	{"--mode", "Interface [station, softap]", ARG_TYPE_CHOICE, true, wifi_interface_choices},
+	{"--bw", "Bandwidth in MHz [20 (HT20), 40 (HT40)]", ARG_TYPE_CHOICE, true, wifi_bandwidth_choices}
+};
+
+static const cmd_arg_t set_dns_args[] = {
+	{"--servers", "Comma separated IPv4/IPv6 DNS servers (e.g. 8.8.8.8,2001:4860:4860::8888). Applied via resolvectl/resolvconf if present, else /etc/resolv.conf, which DHCP renew may overwrite", ARG_TYPE_STRING, true, NULL}
+};
+
 /* Forward declarations for command handlers */
 static int handle_exit(int argc, char **argv);
 static int handle_help(int argc, char **argv);
@@ -352,6 +598,10 @@ static int handle_start_softap(int argc, char **argv);
 static int handle_get_softap_info(int argc, char **argv);
 static int handle_softap_connected_clients_info(int argc, char **argv);
 static int handle_stop_softap(int argc, char **argv);
+static int handle_guest_ap(int argc, char **argv);
+static int handle_status_led(int argc, char **argv);
+static int handle_udp_echo(int argc, char **argv);
+static int handle_udp_probe(int argc, char **argv);
 static int handle_set_wifi_power_save(int argc, char **argv);
 static int handle_get_wifi_power_save(int argc, char **argv);
 static int handle_set_wifi_max_tx_power(int argc, char **argv);
@@ -361,6 +611,16 @@ static int handle_disable_wifi(int argc, char **argv);
 static int handle_enable_bt(int argc, char **argv);
 static int handle_disable_bt(int argc, char **argv);
 static int handle_get_fw_version(int argc, char **argv);
+static int handle_strict_mode(int argc, char **argv);
+static int handle_get_ctrl_rx_stats(int argc, char **argv);
+static int handle_get_link_health(int argc, char **argv);
+static int handle_support_bundle(int argc, char **argv);
+static int handle_event_queue(int argc, char **argv);
+static int handle_dry_run(int argc, char **argv);
+static int handle_get_event_queue_stats(int argc, char **argv);
+static int handle_ctrl_rate_limit(int argc, char **argv);
+static int handle_get_ctrl_rate_stats(int argc, char **argv);
+static int handle_link_recovery(int argc, char **argv);
 static int handle_ota_update(int argc, char **argv);
 static int handle_heartbeat(int argc, char **argv);
 static int handle_subscribe_event(int argc, char **argv);
@@ -369,34 +629,148 @@ static int handle_custom_demo_rpc_request(int argc, char **argv);
 static int handle_set_country_code(int argc, char **argv);
 static int handle_set_country_code_with_ieee80211d_on(int argc, char **argv);
 static int handle_get_country_code(int argc, char **argv);
+static int handle_get_dns(int argc, char **argv);
+static int handle_get_wifi_protocol(int argc, char **argv);
+static int handle_set_wifi_long_range(int argc, char **argv);
+static int handle_set_wifi_protocol(int argc, char **argv);
+static int handle_get_wifi_bandwidth(int argc, char **argv);
+static int handle_set_wifi_bandwidth(int argc, char **argv);
+static int handle_softap_sta_details(int argc, char **argv);
+static int handle_softap_kick_sta(int argc, char **argv);
+static int handle_softap_acl(int argc, char **argv);
+static int handle_get_softap_acl(int argc, char **argv);
+static int handle_set_dns(int argc, char **argv);
+static int handle_vendor_ie_monitor(int argc, char **argv);
+static int handle_probe_req_monitor(int argc, char **argv);
+static int handle_rogue_ap_watch(int argc, char **argv);
+static int handle_webhook(int argc, char **argv);
+static int handle_wifi_schedule(int argc, char **argv);
+static int handle_wifi_wake(int argc, char **argv);
+static int handle_reconnect_test(int argc, char **argv);
+static int handle_get_reconnect_test(int argc, char **argv);
+static int handle_get_pmf(int argc, char **argv);
+static int handle_set_pmf(int argc, char **argv);
+static int handle_get_scan_config(int argc, char **argv);
+static int handle_set_scan_config(int argc, char **argv);
+static int handle_get_partition_table(int argc, char **argv);
+static int handle_read_flash(int argc, char **argv);
+static int handle_set_esp_log_level(int argc, char **argv);
+static int handle_read_adc(int argc, char **argv);
+static int handle_get_chip_temp(int argc, char **argv);
+static int handle_set_traffic_filter(int argc, char **argv);
+static int handle_get_traffic_filter(int argc, char **argv);
+static int handle_get_esp_event_log(int argc, char **argv);
+static int handle_clear_esp_event_log(int argc, char **argv);
+static int handle_export_scan(int argc, char **argv);
+static int handle_site_survey(int argc, char **argv);
+static int handle_scan_cache(int argc, char **argv);
+static int handle_get_scan_cache(int argc, char **argv);
+static int handle_get_neighbors(int argc, char **argv);
+static int handle_check_ip_conflict(int argc, char **argv);
+static int handle_get_conn_history(int argc, char **argv);
+static int handle_get_link_quality(int argc, char **argv);
+
+
+/* Commands changing ESP or host network state, recorded in audit log.
+ * read_flash too, as nvs partition holds credentials */
+static const char *audited_commands[] = {
+	"set_wifi_mode", "set_wifi_mac", "connect_ap", "disconnect_ap", "softap_vendor_ie",
+	"webhook", "wifi_schedule", "wifi_wake", "start_softap", "softap_kick_sta", "softap_acl", "stop_softap",
+	"set_wifi_power_save", "set_wifi_max_tx_power", "set_wifi_long_range", "set_wifi_protocol",
+	"set_wifi_bandwidth", "set_pmf", "set_scan_config", "set_traffic_filter", "clear_esp_event_log", "enable_wifi", "disable_wifi",
+	"enable_bt", "disable_bt", "read_flash", "set_esp_log_level", "ota_update", "heartbeat",
+	"set_country_code", "set_country_code_with_ieee80211d_on", "set_dns", "link_recovery",
+	"guest_ap", "reconnect_test", "udp_echo", NULL
+};
 
+/* Audited commands are only checked and printed while set */
+static bool dry_run_mode = false;
 
+static bool is_audited_command(const char *name) {
+	for (int i = 0; audited_commands[i]; i++) {
+		if (strcmp(audited_commands[i], name) == 0)
+			return true;
+	}
+	return false;
+}
 
 /* Command table */
 static const shell_command_t commands[] = {
 	{"help", "Show this help message", handle_help, NULL, 0},
+	{"dry_run", "Check and print state changing commands instead of running them", handle_dry_run, dry_run_args, sizeof(dry_run_args)/sizeof(cmd_arg_t)},
 	{"get_wifi_mode", "Get Wi-Fi mode", handle_wifi_get_mode, NULL, 0},
 	{"set_wifi_mode", "Set Wi-Fi mode", handle_wifi_set_mode, wifi_set_mode_args, sizeof(wifi_set_mode_args)/sizeof(cmd_arg_t)},
 	{"get_wifi_mac", "Get MAC address", handle_get_mac, NULL, 0},
 	{"set_wifi_mac", "Set MAC address", handle_wifi_set_mac, wifi_set_mac_args, sizeof(wifi_set_mac_args)/sizeof(cmd_arg_t)},
-	{"get_available_ap", "Scan for available networks", handle_get_available_ap, NULL, 0},
+	{"get_available_ap", "Scan for available networks", handle_get_available_ap, get_available_ap_args, sizeof(get_available_ap_args)/sizeof(cmd_arg_t)},
+	{"scan_cache", "Periodically scan in background and cache visible APs", handle_scan_cache, scan_cache_args, sizeof(scan_cache_args)/sizeof(cmd_arg_t)},
+	{"get_scan_cache", "Show APs cached by background scans", handle_get_scan_cache, NULL, 0},
+	{"site_survey", "Sample RSSI at a location into CSV for coverage survey", handle_site_survey, site_survey_args, sizeof(site_survey_args)/sizeof(cmd_arg_t)},
+	{"export_scan", "Scan and save APs as Wi-Fi geolocation JSON", handle_export_scan, export_scan_args, sizeof(export_scan_args)/sizeof(cmd_arg_t)},
 	{"connect_ap", "Connect to a network", handle_connect, connect_ap_args, sizeof(connect_ap_args)/sizeof(cmd_arg_t)},
+	{"get_neighbors", "Get devices on LAN learnt through ARP on ESP interfaces", handle_get_neighbors, NULL, 0},
+	{"check_ip_conflict", "ARP probe if another device on LAN uses our IPv4 address", handle_check_ip_conflict, check_ip_conflict_args, sizeof(check_ip_conflict_args)/sizeof(cmd_arg_t)},
 	{"get_connected_ap_info", "Get info about connected AP", handle_get_connected_ap_info, NULL, 0},
+	{"get_conn_history", "Get recent connect attempts and disconnects with result and RSSI", handle_get_conn_history, NULL, 0},
+	{"get_link_quality", "Get 0-100 station link score from RSSI, disconnects, IP, DNS and gateway", handle_get_link_quality, NULL, 0},
 	{"disconnect_ap", "Disconnect from network", handle_disconnect_ap, disconnect_ap_args, sizeof(disconnect_ap_args)/sizeof(cmd_arg_t)},
-	{"softap_vendor_ie", "Set vendor specific IE in softap beacon", handle_softap_vendor_ie, softap_vendor_ie_args, sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t)},
+	{"softap_vendor_ie", "Set vendor specific IE in beacon, probe or assoc frames", handle_softap_vendor_ie, softap_vendor_ie_args, sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t)},
+	{"webhook", "POST JSON to URL on connect, connection loss, IP change and ESP restart", handle_webhook, webhook_args, sizeof(webhook_args)/sizeof(cmd_arg_t)},
+	{"wifi_schedule", "Keep Wi-Fi off except in daily windows", handle_wifi_schedule, wifi_schedule_args, sizeof(wifi_schedule_args)/sizeof(cmd_arg_t)},
+	{"wifi_wake", "Turn on Wi-Fi now for a while, outside scheduled windows", handle_wifi_wake, wifi_wake_args, sizeof(wifi_wake_args)/sizeof(cmd_arg_t)},
+	{"reconnect_test", "Disconnect and reconnect daily in maintenance window to catch credential or AP drift", handle_reconnect_test, reconnect_test_args, sizeof(reconnect_test_args)/sizeof(cmd_arg_t)},
+	{"get_reconnect_test", "Get results of recent reconnect tests", handle_get_reconnect_test, NULL, 0},
+	{"rogue_ap_watch", "Periodically scan and flag APs impersonating given SSID", handle_rogue_ap_watch, rogue_ap_watch_args, sizeof(rogue_ap_watch_args)/sizeof(cmd_arg_t)},
+	{"probe_req_monitor", "Periodically report nearby devices sending probe requests", handle_probe_req_monitor, probe_req_monitor_args, sizeof(probe_req_monitor_args)/sizeof(cmd_arg_t)},
+	{"vendor_ie_monitor", "Report vendor IEs of given OUI seen in received frames", handle_vendor_ie_monitor, vendor_ie_monitor_args, sizeof(vendor_ie_monitor_args)/sizeof(cmd_arg_t)},
 	{"start_softap", "Start SoftAP", handle_start_softap, start_softap_args, sizeof(start_softap_args)/sizeof(cmd_arg_t)},
 	{"get_softap_info", "Get SoftAP configuration", handle_get_softap_info, NULL, 0},
 	{"softap_connected_clients_info", "Get clients connected to SoftAP", handle_softap_connected_clients_info, NULL, 0},
+	{"softap_sta_details", "Get RSSI, connected time and traffic of SoftAP clients", handle_softap_sta_details, NULL, 0},
+	{"softap_kick_sta", "Disconnect a client from SoftAP", handle_softap_kick_sta, softap_kick_sta_args, sizeof(softap_kick_sta_args)/sizeof(cmd_arg_t)},
+	{"softap_acl", "Set which stations may join SoftAP, and client isolation", handle_softap_acl, softap_acl_args, sizeof(softap_acl_args)/sizeof(cmd_arg_t)},
+	{"get_softap_acl", "Get SoftAP access control and rejected count", handle_get_softap_acl, NULL, 0},
 	{"stop_softap", "Stop SoftAP", handle_stop_softap, NULL, 0},
+	{"guest_ap", "Start SoftAP with random password, stopped after a while", handle_guest_ap, guest_ap_args, sizeof(guest_ap_args)/sizeof(cmd_arg_t)},
+	{"status_led", "Show link state on host LED with blink patterns", handle_status_led, status_led_args, sizeof(status_led_args)/sizeof(cmd_arg_t)},
+	{"udp_echo", "Answer UDP latency probes from other units", handle_udp_echo, udp_echo_args, sizeof(udp_echo_args)/sizeof(cmd_arg_t)},
+	{"udp_probe", "Measure UDP round trip, one way latency and jitter to udp_echo responder", handle_udp_probe, udp_probe_args, sizeof(udp_probe_args)/sizeof(cmd_arg_t)},
 	{"set_wifi_power_save", "Set power save mode", handle_set_wifi_power_save, set_wifi_power_save_args, sizeof(set_wifi_power_save_args)/sizeof(cmd_arg_t)},
 	{"get_wifi_power_save", "Get power save mode", handle_get_wifi_power_save, NULL, 0},
 	{"set_wifi_max_tx_power", "Set maximum TX power", handle_set_wifi_max_tx_power, set_wifi_max_tx_power_args, sizeof(set_wifi_max_tx_power_args)/sizeof(cmd_arg_t)},
 	{"get_wifi_curr_tx_power", "Get current TX power", handle_get_wifi_curr_tx_power, NULL, 0},
+	{"get_wifi_protocol", "Get 802.11 protocols enabled on interface", handle_get_wifi_protocol, get_wifi_protocol_args, sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t)},
+	{"set_wifi_long_range", "Enable or disable long range (LR) mode on interface", handle_set_wifi_long_range, set_wifi_long_range_args, sizeof(set_wifi_long_range_args)/sizeof(cmd_arg_t)},
+	{"set_wifi_protocol", "Set 802.11 protocols enabled on interface", handle_set_wifi_protocol, set_wifi_protocol_args, sizeof(set_wifi_protocol_args)/sizeof(cmd_arg_t)},
+	{"get_wifi_bandwidth", "Get channel bandwidth of interface", handle_get_wifi_bandwidth, get_wifi_protocol_args, sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t)},
+	{"set_wifi_bandwidth", "Set channel bandwidth of interface", handle_set_wifi_bandwidth, set_wifi_bandwidth_args, sizeof(set_wifi_bandwidth_args)/sizeof(cmd_arg_t)},
+	{"get_pmf", "Get Protected Management Frames setting of interface", handle_get_pmf, get_wifi_protocol_args, sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t)},
+	{"set_pmf", "Set Protected Management Frames, effective on next connect/start", handle_set_pmf, set_pmf_args, sizeof(set_pmf_args)/sizeof(cmd_arg_t)},
+	{"get_scan_config", "Get background scan setting and scan capabilities of ESP", handle_get_scan_config, NULL, 0},
+	{"set_scan_config", "Set how ESP scans while station is connected", handle_set_scan_config, set_scan_config_args, sizeof(set_scan_config_args)/sizeof(cmd_arg_t)},
+	{"set_traffic_filter", "Set which received frames ESP forwards, drops or wakes host for", handle_set_traffic_filter, set_traffic_filter_args, sizeof(set_traffic_filter_args)/sizeof(cmd_arg_t)},
+	{"get_traffic_filter", "Get traffic filter and dropped frame count", handle_get_traffic_filter, NULL, 0},
+	{"get_esp_event_log", "Get ESP resets and Wi-Fi failures kept in ESP flash", handle_get_esp_event_log, NULL, 0},
+	{"clear_esp_event_log", "Clear ESP event log", handle_clear_esp_event_log, NULL, 0},
 	{"enable_wifi", "Enable Wi-Fi", handle_enable_wifi, NULL, 0},
 	{"disable_wifi", "Disable Wi-Fi", handle_disable_wifi, NULL, 0},
 	{"enable_bt", "Enable Bluetooth", handle_enable_bt, NULL, 0},
 	{"disable_bt", "Disable Bluetooth", handle_disable_bt, NULL, 0},
 	{"get_fw_version", "Get firmware version", handle_get_fw_version, NULL, 0},
+	{"strict_mode", "Report messages from ESP that host cannot handle, e.g. version mismatch", handle_strict_mode, strict_mode_args, sizeof(strict_mode_args)/sizeof(cmd_arg_t)},
+	{"get_ctrl_rx_stats", "Get count of messages from ESP dropped as unexpected", handle_get_ctrl_rx_stats, NULL, 0},
+	{"event_queue", "Run event callbacks from queue, so slow ones do not stall responses", handle_event_queue, event_queue_args, sizeof(event_queue_args)/sizeof(cmd_arg_t)},
+	{"get_event_queue_stats", "Get queued, peak and dropped event counts", handle_get_event_queue_stats, NULL, 0},
+	{"ctrl_rate_limit", "Limit control requests per second, so polling does not hog the bus", handle_ctrl_rate_limit, ctrl_rate_limit_args, sizeof(ctrl_rate_limit_args)/sizeof(cmd_arg_t)},
+	{"get_ctrl_rate_stats", "Get control request rate limit with delayed and dropped counts", handle_get_ctrl_rate_stats, NULL, 0},
+	{"get_link_health", "Tell if ESP is unresponsive or only Wi-Fi is down", handle_get_link_health, NULL, 0},
+	{"support_bundle", "Collect diagnostics into one file for support tickets, secrets masked", handle_support_bundle, support_bundle_args, sizeof(support_bundle_args)/sizeof(cmd_arg_t)},
+	{"link_recovery", "Reopen control path, reset ESP and alert when ESP stops responding", handle_link_recovery, link_recovery_args, sizeof(link_recovery_args)/sizeof(cmd_arg_t)},
+	{"get_partition_table", "Get partition table of ESP flash", handle_get_partition_table, NULL, 0},
+	{"read_flash", "Dump ESP flash region, e.g. nvs or otadata partition", handle_read_flash, read_flash_args, sizeof(read_flash_args)/sizeof(cmd_arg_t)},
+	{"set_esp_log_level", "Set runtime log level of ESP firmware tag", handle_set_esp_log_level, set_esp_log_level_args, sizeof(set_esp_log_level_args)/sizeof(cmd_arg_t)},
+	{"read_adc", "Read voltage on ESP ADC1 channel", handle_read_adc, read_adc_args, sizeof(read_adc_args)/sizeof(cmd_arg_t)},
+	{"get_chip_temp", "Read ESP internal temperature sensor", handle_get_chip_temp, NULL, 0},
 	{"ota_update", "Update firmware via OTA", handle_ota_update, ota_update_args, sizeof(ota_update_args)/sizeof(cmd_arg_t)},
 	{"heartbeat", "Configure heartbeat", handle_heartbeat, heartbeat_args, sizeof(heartbeat_args)/sizeof(cmd_arg_t)},
 	{"subscribe_event", "Subscribe to events", handle_subscribe_event, subscribe_event_args, sizeof(subscribe_event_args)/sizeof(cmd_arg_t)},
@@ -408,6 +782,8 @@ static const shell_command_t commands[] = {
 	{"set_country_code", "Set Wi-Fi country code", handle_set_country_code, set_country_code_args, sizeof(set_country_code_args)/sizeof(cmd_arg_t)},
 	{"set_country_code_with_ieee80211d_on", "Set Wi-Fi country code with ieee80211d enabled", handle_set_country_code_with_ieee80211d_on, NULL, 0},
 	{"get_country_code", "Get Wi-Fi country code", handle_get_country_code, NULL, 0},
+	{"get_dns", "Get DNS servers used by host", handle_get_dns, NULL, 0},
+	{"set_dns", "Set DNS servers used by host", handle_set_dns, set_dns_args, sizeof(set_dns_args)/sizeof(cmd_arg_t)},
 	{NULL, NULL, NULL, NULL, 0}
 };
 
@@ -606,9 +982,442 @@ static int handle_wifi_set_mode(int argc, char **argv) {
 	return test_set_wifi_mode(mode);
 }
 
+/* Best AP of one SSID on each band, index as in scan_band_choices */
+typedef struct {
+	const wifi_scanlist_t *best[2];
+	int num_ap;
+} scan_group_t;
+
+static void print_scan_groups(const wifi_scanlist_t *list, int count, const char *band) {
+	scan_group_t *groups = calloc(count, sizeof(scan_group_t));
+	int num_groups = 0;
+	int hidden = 0;
+
+	if (!groups) {
+		printf("Failed to allocate memory\n");
+		return;
+	}
+
+	for (int i = 0; i < count; i++) {
+		const char *ap_band = wifi_channel_to_band(list[i].channel);
+		int b = strcmp(ap_band, scan_band_choices[0]) ? 1 : 0;
+		int g = 0;
+
+		if (band && strcasecmp(band, ap_band))
+			continue;
+		/* Hidden APs of different networks can not be told apart */
+		if (!list[i].ssid[0]) {
+			hidden++;
+			continue;
+		}
+		for (g = 0; g < num_groups; g++) {
+			const wifi_scanlist_t *first = groups[g].best[0] ? groups[g].best[0] : groups[g].best[1];
+
+			if (!strcmp((const char *)first->ssid, (const char *)list[i].ssid))
+				break;
+		}
+		if (g == num_groups)
+			num_groups++;
+		groups[g].num_ap++;
+		if (!groups[g].best[b] || list[i].rssi > groups[g].best[b]->rssi)
+			groups[g].best[b] = &list[i];
+	}
+
+	printf("Number of available networks is %d\n", num_groups);
+	for (int g = 0; g < num_groups; g++) {
+		const wifi_scanlist_t *first = groups[g].best[0] ? groups[g].best[0] : groups[g].best[1];
+
+		printf("%d) ssid \"%s\" auth mode \"%s\" %d AP(s)%s\n", g + 1, first->ssid,
+				wifi_auth_mode_to_str(first->encryption_mode), groups[g].num_ap,
+				(groups[g].best[0] && groups[g].best[1]) ? " dual band" : "");
+		for (int b = 0; b < 2; b++) {
+			const wifi_scanlist_t *ap = groups[g].best[b];
+
+			if (ap)
+				printf("    %-4s best bssid \"%s\" rssi \"%d\" channel \"%d\"\n",
+						scan_band_choices[b], ap->bssid, ap->rssi, ap->channel);
+		}
+	}
+	if (hidden)
+		printf("%d AP(s) with hidden SSID not grouped\n", hidden);
+
+	free(groups);
+}
+
 static int handle_get_available_ap(int argc, char **argv) {
 	CHECK_RPC_ACTIVE();
-	return test_get_available_wifi();
+
+	if (!parse_arguments(argc, argv, get_available_ap_args, sizeof(get_available_ap_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *group = get_arg_value(argc, argv, get_available_ap_args,
+			sizeof(get_available_ap_args)/sizeof(cmd_arg_t),
+			"--group");
+	const char *band = get_arg_value(argc, argv, get_available_ap_args,
+			sizeof(get_available_ap_args)/sizeof(cmd_arg_t),
+			"--band");
+	wifi_scanlist_t *list = NULL;
+	int count = 0;
+	int shown = 0;
+
+	if (!is_arg_true(group) && !band) {
+		return test_get_available_wifi();
+	}
+
+	if (test_get_available_wifi_list(&list, &count) != SUCCESS) {
+		printf("Failed to get scanned AP list\n");
+		return FAILURE;
+	}
+
+	if (is_arg_true(group)) {
+		print_scan_groups(list, count, band);
+		free(list);
+		return SUCCESS;
+	}
+
+	for (int i = 0; i < count; i++) {
+		if (strcasecmp(band, wifi_channel_to_band(list[i].channel)))
+			continue;
+		printf("%d) ssid \"%s\" bssid \"%s\" rssi \"%d\" channel \"%d\" band \"%s\" auth mode \"%s\"\n",
+				shown++, list[i].ssid, list[i].bssid, list[i].rssi, list[i].channel,
+				wifi_channel_to_band(list[i].channel),
+				wifi_auth_mode_to_str(list[i].encryption_mode));
+	}
+	if (!shown)
+		printf("No AP found on %s band\n", band);
+
+	free(list);
+	return SUCCESS;
+}
+
+static int handle_scan_cache(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, scan_cache_args, sizeof(scan_cache_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, scan_cache_args,
+			sizeof(scan_cache_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *interval = get_arg_value(argc, argv, scan_cache_args,
+			sizeof(scan_cache_args)/sizeof(cmd_arg_t),
+			"--interval");
+
+	if (!is_arg_true(enable)) {
+		scan_cache_stop();
+		printf("Background scans stopped, cache kept\n");
+		return SUCCESS;
+	}
+
+	if (scan_cache_start(interval ? atoi(interval) : 60) != SUCCESS) {
+		return FAILURE;
+	}
+	printf("Background scans started\n");
+	return SUCCESS;
+}
+
+static int handle_get_scan_cache(int argc, char **argv) {
+	static scan_cache_entry_t entries[SCAN_CACHE_MAX_ENTRIES];
+	time_t now = time(NULL);
+	int num = scan_cache_get(entries, SCAN_CACHE_MAX_ENTRIES);
+
+	printf("%d cached AP(s)\n", num);
+	for (int i = 0; i < num; i++) {
+		scan_cache_entry_t *e = &entries[i];
+
+		printf("%3d) ssid \"%s\" bssid %s ch %d auth %s rssi best %d last %d first seen %lds ago last seen %lds ago\n",
+				i + 1, e->ssid, e->bssid, e->channel,
+				wifi_auth_mode_to_str(e->encryption_mode), e->best_rssi, e->last_rssi,
+				(long)(now - e->first_seen), (long)(now - e->last_seen));
+	}
+	return SUCCESS;
+}
+
+static const char *connect_status_str(int status) {
+	switch (status) {
+		case SUCCESS:                    return "success";
+		case CTRL_ERR_NO_AP_FOUND:       return "ap not found";
+		case CTRL_ERR_INVALID_PASSWORD:  return "invalid password";
+		case CTRL_ERR_ESP_NOT_SUPPORTED: return "security not supported";
+		case CTRL_ERR_REQUEST_TIMEOUT:   return "timeout";
+		default:                         return "failed";
+	}
+}
+
+static int handle_get_conn_history(int argc, char **argv) {
+	conn_history_entry_t entries[CONN_HISTORY_MAX_ENTRIES];
+	char ts[32] = {0};
+	int num = conn_history_get(entries, CONN_HISTORY_MAX_ENTRIES);
+
+	printf("%d entr%s, oldest first\n", num, num == 1 ? "y" : "ies");
+	for (int i = 0; i < num; i++) {
+		conn_history_entry_t *e = &entries[i];
+
+		/* Entries are in monotonic order. Host clock change between two
+		 * entries shows as gap between wall clock and monotonic deltas */
+		if (i > 0) {
+			long long skew = (long long)(e->time - entries[i - 1].time) -
+				(long long)(e->mono_ms - entries[i - 1].mono_ms) / 1000;
+
+			if (skew > CONN_HISTORY_CLOCK_SKEW_SEC || skew < -CONN_HISTORY_CLOCK_SKEW_SEC)
+				printf("-- host clock changed by %+llds --\n", skew);
+		}
+		strftime(ts, sizeof(ts), "%Y-%m-%d %H:%M:%S", localtime(&e->time));
+		printf("%s %-10s ssid \"%s\" bssid %s rssi ", ts,
+				e->type == CONN_HISTORY_CONNECT ? "connect" : "disconnect",
+				e->ssid, e->bssid[0] ? e->bssid : "-");
+		if (e->rssi == CONN_HISTORY_RSSI_UNKNOWN)
+			printf("-");
+		else
+			printf("%d", e->rssi);
+
+		if (e->type == CONN_HISTORY_CONNECT) {
+			printf(" took %ums %s[%d]\n", e->duration_ms, connect_status_str(e->status), e->status);
+		} else if (e->duration_ms) {
+			printf(" after %us up, reason %d\n", e->duration_ms / 1000, e->status);
+		} else {
+			printf(" reason %d\n", e->status);
+		}
+	}
+	return SUCCESS;
+}
+
+static int handle_get_link_quality(int argc, char **argv) {
+	link_quality_t q = {0};
+	bool first = true;
+
+	CHECK_RPC_ACTIVE();
+
+	if (link_quality_get(STA_INTERFACE, &q) != SUCCESS) {
+		printf("Failed to get link quality\n");
+		return FAILURE;
+	}
+
+	printf("Link quality: %d/100 %s", q.score, link_quality_rating(q.score));
+	for (uint32_t bit = 1; bit && bit <= q.factors; bit <<= 1) {
+		if (!(q.factors & bit))
+			continue;
+		printf("%s%s", first ? " (" : ", ", link_quality_factor_str(bit));
+		first = false;
+	}
+	printf("%s\n", first ? "" : ")");
+
+	if (q.factors & LINK_QUALITY_NOT_CONNECTED)
+		return SUCCESS;
+
+	printf("RSSI: %d dBm\n", q.rssi);
+	printf("Disconnects in last %ds: %d\n", LINK_QUALITY_UNSTABLE_WINDOW_SEC, q.disconnects);
+	printf("ESP request timeouts: %u\n", q.esp_timeouts);
+	printf("IPv4 address: %s\n", q.has_ip ? "yes" : "no");
+	printf("DNS servers: %d\n", q.dns_servers);
+	if (q.gateway_rtt_ms >= 0)
+		printf("Gateway ARP round trip: %dms\n", q.gateway_rtt_ms);
+	else
+		printf("Gateway ARP round trip: -\n");
+	return SUCCESS;
+}
+
+static int parse_position(const char *lat, const char *lon, scan_export_position_t *pos) {
+	char *endptr = NULL;
+
+	if (!lat || !lon) {
+		printf("Both --lat and --lon are needed to tag position\n");
+		return FAILURE;
+	}
+
+	pos->latitude = strtod(lat, &endptr);
+	if (*endptr != '\0' || pos->latitude < -90 || pos->latitude > 90) {
+		printf("Invalid latitude: %s\n", lat);
+		return FAILURE;
+	}
+	pos->longitude = strtod(lon, &endptr);
+	if (*endptr != '\0' || pos->longitude < -180 || pos->longitude > 180) {
+		printf("Invalid longitude: %s\n", lon);
+		return FAILURE;
+	}
+	return SUCCESS;
+}
+
+static int handle_site_survey(int argc, char **argv) {
+	scan_export_position_t pos = {0};
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, site_survey_args, sizeof(site_survey_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *file = get_arg_value(argc, argv, site_survey_args,
+			sizeof(site_survey_args)/sizeof(cmd_arg_t),
+			"--file");
+	const char *location = get_arg_value(argc, argv, site_survey_args,
+			sizeof(site_survey_args)/sizeof(cmd_arg_t),
+			"--location");
+	const char *samples = get_arg_value(argc, argv, site_survey_args,
+			sizeof(site_survey_args)/sizeof(cmd_arg_t),
+			"--samples");
+	const char *ssid = get_arg_value(argc, argv, site_survey_args,
+			sizeof(site_survey_args)/sizeof(cmd_arg_t),
+			"--ssid");
+	const char *lat = get_arg_value(argc, argv, site_survey_args,
+			sizeof(site_survey_args)/sizeof(cmd_arg_t),
+			"--lat");
+	const char *lon = get_arg_value(argc, argv, site_survey_args,
+			sizeof(site_survey_args)/sizeof(cmd_arg_t),
+			"--lon");
+
+	if ((lat || lon) && parse_position(lat, lon, &pos) != SUCCESS) {
+		return FAILURE;
+	}
+
+	return scan_export_survey_csv(file, location, (lat || lon) ? &pos : NULL,
+			ssid, samples ? atoi(samples) : 5);
+}
+
+static int handle_export_scan(int argc, char **argv) {
+	scan_export_position_t pos = {0};
+	char *endptr = NULL;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, export_scan_args, sizeof(export_scan_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *file = get_arg_value(argc, argv, export_scan_args,
+			sizeof(export_scan_args)/sizeof(cmd_arg_t),
+			"--file");
+	const char *lat = get_arg_value(argc, argv, export_scan_args,
+			sizeof(export_scan_args)/sizeof(cmd_arg_t),
+			"--lat");
+	const char *lon = get_arg_value(argc, argv, export_scan_args,
+			sizeof(export_scan_args)/sizeof(cmd_arg_t),
+			"--lon");
+	const char *accuracy = get_arg_value(argc, argv, export_scan_args,
+			sizeof(export_scan_args)/sizeof(cmd_arg_t),
+			"--accuracy");
+
+	if (!lat && !lon) {
+		return scan_export_geolocation(file, NULL);
+	}
+
+	if (parse_position(lat, lon, &pos) != SUCCESS) {
+		return FAILURE;
+	}
+	if (accuracy) {
+		pos.accuracy = strtod(accuracy, &endptr);
+		if (*endptr != '\0' || pos.accuracy < 0) {
+			printf("Invalid accuracy: %s\n", accuracy);
+			return FAILURE;
+		}
+	}
+
+	return scan_export_geolocation(file, &pos);
+}
+
+static int handle_get_neighbors(int argc, char **argv) {
+	static neighbor_info_t neighbors[MAX_NEIGHBORS];
+	const char *ifaces[] = {STA_INTERFACE, AP_INTERFACE};
+	int count = 0;
+
+	for (int i = 0; i < 2; i++) {
+		if (get_neighbors(ifaces[i], neighbors, MAX_NEIGHBORS, &count) != SUCCESS) {
+			return FAILURE;
+		}
+
+		printf("%s: %d neighbor(s)\n", ifaces[i], count);
+		for (int j = 0; j < count; j++) {
+			if (neighbors[j].is_permanent)
+				printf("  %-15s %s permanent\n", neighbors[j].ip_addr, neighbors[j].mac_addr);
+			else if (neighbors[j].age_sec >= 0)
+				printf("  %-15s %s age %ds\n", neighbors[j].ip_addr, neighbors[j].mac_addr,
+						neighbors[j].age_sec);
+			else
+				printf("  %-15s %s\n", neighbors[j].ip_addr, neighbors[j].mac_addr);
+		}
+	}
+	return SUCCESS;
+}
+
+/* Station address last checked by auto IP conflict check, see
+ * check_station_ip_conflict() */
+static bool ip_conflict_auto = false;
+static char ip_conflict_last_ip[INET_ADDRSTRLEN];
+
+static int check_ip_conflict(const char *iface, const char *ip) {
+	char mac[MAC_ADDR_LENGTH] = {0};
+	char detail[96] = {0};
+
+	if (probe_ip_conflict(iface, ip, IP_CONFLICT_PROBE_MS, mac) != SUCCESS) {
+		printf("ARP probe on %s failed\n", iface);
+		return FAILURE;
+	}
+
+	if (!mac[0]) {
+		printf("No conflict: no other device answered for %s on %s\n", ip, iface);
+		return SUCCESS;
+	}
+
+	snprintf(detail, sizeof(detail), "%s on %s also used by %s", ip, iface, mac);
+	printf("IP CONFLICT: %s\n", detail);
+	webhook_notify(WEBHOOK_EVENT_IP_CONFLICT, detail);
+	return SUCCESS;
+}
+
+/* Polled from monitoring loop, probes once per new station address */
+static void check_station_ip_conflict(void) {
+	char ip[INET_ADDRSTRLEN] = {0};
+
+	if (!ip_conflict_auto)
+		return;
+
+	if (get_ipv4_addr(STA_INTERFACE, ip, sizeof(ip)) != SUCCESS ||
+	    !strcmp(ip, ip_conflict_last_ip))
+		return;
+
+	strncpy(ip_conflict_last_ip, ip, sizeof(ip_conflict_last_ip) - 1);
+	check_ip_conflict(STA_INTERFACE, ip);
+}
+
+static int handle_check_ip_conflict(int argc, char **argv) {
+	char own_ip[INET_ADDRSTRLEN] = {0};
+
+	if (!parse_arguments(argc, argv, check_ip_conflict_args, sizeof(check_ip_conflict_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *iface = get_arg_value(argc, argv, check_ip_conflict_args,
+			sizeof(check_ip_conflict_args)/sizeof(cmd_arg_t),
+			"--interface");
+	const char *ip = get_arg_value(argc, argv, check_ip_conflict_args,
+			sizeof(check_ip_conflict_args)/sizeof(cmd_arg_t),
+			"--ip");
+	const char *auto_check = get_arg_value(argc, argv, check_ip_conflict_args,
+			sizeof(check_ip_conflict_args)/sizeof(cmd_arg_t),
+			"--auto");
+
+	if (auto_check) {
+		ip_conflict_auto = is_arg_true(auto_check);
+		/* Probe current address on next poll too */
+		ip_conflict_last_ip[0] = '\0';
+		printf("Automatic IP conflict check %s\n", ip_conflict_auto ? "enabled" : "disabled");
+		return SUCCESS;
+	}
+
+	if (!iface)
+		iface = STA_INTERFACE;
+
+	if (!ip) {
+		if (get_ipv4_addr(iface, own_ip, sizeof(own_ip)) != SUCCESS) {
+			printf("%s has no IPv4 address, use --ip\n", iface);
+			return FAILURE;
+		}
+		ip = own_ip;
+	}
+
+	return check_ip_conflict(iface, ip);
 }
 
 static int handle_connect(int argc, char **argv) {
@@ -624,6 +1433,7 @@ static int handle_connect(int argc, char **argv) {
 	const char *pwd = get_arg_value(argc, argv, connect_ap_args,
 			sizeof(connect_ap_args)/sizeof(cmd_arg_t),
 			"--password");
+	redact_add_secret(pwd);
 	const char *bssid = get_arg_value(argc, argv, connect_ap_args,
 			sizeof(connect_ap_args)/sizeof(cmd_arg_t),
 			"--bssid");
@@ -679,207 +1489,1567 @@ static int handle_connect(int argc, char **argv) {
 			);
 }
 
-static int handle_get_connected_ap_info(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_station_mode_get_info();
+static int handle_get_connected_ap_info(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_station_mode_get_info();
+}
+
+static int handle_disconnect_ap(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, disconnect_ap_args, sizeof(disconnect_ap_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *reset_dhcp = get_arg_value(argc, argv, disconnect_ap_args,
+			sizeof(disconnect_ap_args)/sizeof(cmd_arg_t),
+			"--reset_dhcp");
+
+	return test_station_mode_disconnect_with_params(reset_dhcp ? is_arg_true(reset_dhcp) : false);
+}
+
+static int handle_softap_vendor_ie(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, softap_vendor_ie_args, sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, softap_vendor_ie_args,
+			sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *data = get_arg_value(argc, argv, softap_vendor_ie_args,
+			sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t),
+			"--data");
+	const char *type = get_arg_value(argc, argv, softap_vendor_ie_args,
+			sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t),
+			"--type");
+	const char *idx = get_arg_value(argc, argv, softap_vendor_ie_args,
+			sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t),
+			"--idx");
+
+	int ie_type = WIFI_VND_IE_TYPE_BEACON;
+	if (type) {
+		if (strcmp(type, "probe_req") == 0)
+			ie_type = WIFI_VND_IE_TYPE_PROBE_REQ;
+		else if (strcmp(type, "probe_resp") == 0)
+			ie_type = WIFI_VND_IE_TYPE_PROBE_RESP;
+		else if (strcmp(type, "assoc_req") == 0)
+			ie_type = WIFI_VND_IE_TYPE_ASSOC_REQ;
+		else if (strcmp(type, "assoc_resp") == 0)
+			ie_type = WIFI_VND_IE_TYPE_ASSOC_RESP;
+	}
+
+	int ie_idx = idx ? atoi(idx) : WIFI_VND_IE_ID_0;
+	if (ie_idx != WIFI_VND_IE_ID_0 && ie_idx != WIFI_VND_IE_ID_1) {
+		printf("Invalid vendor IE index %d, must be 0 or 1\n", ie_idx);
+		return FAILURE;
+	}
+
+	return test_set_vendor_specific_ie_with_params(enable ? is_arg_true(enable) : true,
+			ie_type, ie_idx, data ? data : "");
+}
+
+static int handle_vendor_ie_monitor(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, vendor_ie_monitor_args, sizeof(vendor_ie_monitor_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, vendor_ie_monitor_args,
+			sizeof(vendor_ie_monitor_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *oui = get_arg_value(argc, argv, vendor_ie_monitor_args,
+			sizeof(vendor_ie_monitor_args)/sizeof(cmd_arg_t),
+			"--oui");
+
+	if (is_arg_true(enable) && !oui) {
+		printf("--oui is required to enable vendor IE monitor\n");
+		return FAILURE;
+	}
+
+	return custom_rpc_vendor_ie_monitor(is_arg_true(enable), oui);
+}
+
+static int handle_probe_req_monitor(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, probe_req_monitor_args, sizeof(probe_req_monitor_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, probe_req_monitor_args,
+			sizeof(probe_req_monitor_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *interval = get_arg_value(argc, argv, probe_req_monitor_args,
+			sizeof(probe_req_monitor_args)/sizeof(cmd_arg_t),
+			"--interval");
+
+	int interval_sec = interval ? atoi(interval) : 0;
+	if (interval_sec < 0 || interval_sec > 255) {
+		printf("Invalid interval %d, must be 1-255 seconds\n", interval_sec);
+		return FAILURE;
+	}
+
+	return custom_rpc_probe_req_monitor(is_arg_true(enable), interval_sec);
+}
+
+static int handle_webhook(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, webhook_args, sizeof(webhook_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *url = get_arg_value(argc, argv, webhook_args,
+			sizeof(webhook_args)/sizeof(cmd_arg_t),
+			"--url");
+	const char *iface = get_arg_value(argc, argv, webhook_args,
+			sizeof(webhook_args)/sizeof(cmd_arg_t),
+			"--interface");
+
+	if (!url || strcmp(url, "none") == 0) {
+		webhook_notify_configure(NULL, NULL);
+		printf("Webhook notifications disabled\n");
+		return SUCCESS;
+	}
+
+	if (strncmp(url, "http://", 7) && strncmp(url, "https://", 8)) {
+		printf("Webhook URL must start with http:// or https://\n");
+		return FAILURE;
+	}
+
+	if (webhook_notify_configure(url, iface) != SUCCESS) {
+		return FAILURE;
+	}
+	printf("Webhook notifications to %s%s%s\n", url,
+			iface ? " via " : "", iface ? iface : "");
+	return SUCCESS;
+}
+
+static void rogue_ap_event_handler(const rogue_ap_event_t *event) {
+	char ts[32] = {0};
+	time_t now = time(NULL);
+	struct tm tm_now = {0};
+
+	localtime_r(&now, &tm_now);
+	strftime(ts, sizeof(ts), "%Y-%m-%d %H:%M:%S", &tm_now);
+	printf("\n[%s] SECURITY: possible rogue AP for SSID '%s' bssid %s channel %d auth mode %s rssi %d, differs in:%s%s%s\n",
+			ts, event->ssid, event->bssid, event->channel,
+			wifi_auth_mode_to_str(event->encryption_mode), event->rssi,
+			(event->reasons & ROGUE_AP_REASON_AUTH_MODE) ? " auth_mode" : "",
+			(event->reasons & ROGUE_AP_REASON_OUI) ? " oui" : "",
+			(event->reasons & ROGUE_AP_REASON_CHANNEL) ? " channel" : "");
+}
+
+static int handle_rogue_ap_watch(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, rogue_ap_watch_args, sizeof(rogue_ap_watch_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, rogue_ap_watch_args,
+			sizeof(rogue_ap_watch_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *ssid = get_arg_value(argc, argv, rogue_ap_watch_args,
+			sizeof(rogue_ap_watch_args)/sizeof(cmd_arg_t),
+			"--ssid");
+	const char *interval = get_arg_value(argc, argv, rogue_ap_watch_args,
+			sizeof(rogue_ap_watch_args)/sizeof(cmd_arg_t),
+			"--interval");
+
+	if (!is_arg_true(enable)) {
+		rogue_ap_watch_stop();
+		printf("Rogue AP watcher stopped\n");
+		return SUCCESS;
+	}
+
+	if (!ssid) {
+		printf("--ssid is required to enable rogue AP watcher\n");
+		return FAILURE;
+	}
+
+	if (rogue_ap_watch_start(ssid, interval ? atoi(interval) : 60, rogue_ap_event_handler) != SUCCESS) {
+		return FAILURE;
+	}
+	printf("Rogue AP watcher started for SSID '%s'\n", ssid);
+	return SUCCESS;
+}
+
+static int handle_wifi_schedule(int argc, char **argv) {
+	wifi_window_t windows[WIFI_SCHEDULE_MAX_WINDOWS] = {0};
+	int num = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, wifi_schedule_args, sizeof(wifi_schedule_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, wifi_schedule_args,
+			sizeof(wifi_schedule_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *windows_str = get_arg_value(argc, argv, wifi_schedule_args,
+			sizeof(wifi_schedule_args)/sizeof(cmd_arg_t),
+			"--windows");
+	const char *ssid = get_arg_value(argc, argv, wifi_schedule_args,
+			sizeof(wifi_schedule_args)/sizeof(cmd_arg_t),
+			"--ssid");
+	const char *pwd = get_arg_value(argc, argv, wifi_schedule_args,
+			sizeof(wifi_schedule_args)/sizeof(cmd_arg_t),
+			"--password");
+	redact_add_secret(pwd);
+
+	if (!is_arg_true(enable)) {
+		wifi_schedule_stop();
+		printf("Wi-Fi schedule stopped, Wi-Fi left as is\n");
+		return SUCCESS;
+	}
+
+	if (!windows_str || !ssid) {
+		printf("--windows and --ssid are required to enable Wi-Fi schedule\n");
+		return FAILURE;
+	}
+
+	num = wifi_schedule_parse_windows(windows_str, windows);
+	if (num < 0) {
+		printf("Invalid windows '%s', expected up to %d of HH:MM-HH:MM separated by ','\n",
+				windows_str, WIFI_SCHEDULE_MAX_WINDOWS);
+		return FAILURE;
+	}
+
+	if (wifi_schedule_start(windows, num, ssid, pwd) != SUCCESS) {
+		return FAILURE;
+	}
+	printf("Wi-Fi schedule started with %d window(s)\n", num);
+	return SUCCESS;
+}
+
+static int handle_reconnect_test(int argc, char **argv) {
+	wifi_window_t windows[WIFI_SCHEDULE_MAX_WINDOWS] = {0};
+	int num = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, reconnect_test_args, sizeof(reconnect_test_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, reconnect_test_args,
+			sizeof(reconnect_test_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *windows_str = get_arg_value(argc, argv, reconnect_test_args,
+			sizeof(reconnect_test_args)/sizeof(cmd_arg_t),
+			"--windows");
+	const char *ssid = get_arg_value(argc, argv, reconnect_test_args,
+			sizeof(reconnect_test_args)/sizeof(cmd_arg_t),
+			"--ssid");
+	const char *pwd = get_arg_value(argc, argv, reconnect_test_args,
+			sizeof(reconnect_test_args)/sizeof(cmd_arg_t),
+			"--password");
+	const char *run_now = get_arg_value(argc, argv, reconnect_test_args,
+			sizeof(reconnect_test_args)/sizeof(cmd_arg_t),
+			"--run_now");
+	redact_add_secret(pwd);
+
+	if (!is_arg_true(enable)) {
+		reconnect_test_stop();
+		printf("Reconnect test stopped, results kept\n");
+		return SUCCESS;
+	}
+
+	if (!windows_str || !ssid) {
+		printf("--windows and --ssid are required to enable reconnect test\n");
+		return FAILURE;
+	}
+
+	num = wifi_schedule_parse_windows(windows_str, windows);
+	if (num < 0) {
+		printf("Invalid windows '%s', expected up to %d of HH:MM-HH:MM separated by ','\n",
+				windows_str, WIFI_SCHEDULE_MAX_WINDOWS);
+		return FAILURE;
+	}
+
+	if (reconnect_test_start(windows, num, ssid, pwd) != SUCCESS) {
+		return FAILURE;
+	}
+	if (is_arg_true(run_now)) {
+		reconnect_test_run_now();
+	}
+	printf("Reconnect test scheduled daily in %d window(s)\n", num);
+	return SUCCESS;
+}
+
+static int handle_get_reconnect_test(int argc, char **argv) {
+	reconnect_test_result_t results[RECONNECT_TEST_MAX_RESULTS];
+	char ts[32] = {0};
+	int num = reconnect_test_get_results(results, RECONNECT_TEST_MAX_RESULTS);
+
+	printf("%d result(s), oldest first\n", num);
+	for (int i = 0; i < num; i++) {
+		reconnect_test_result_t *r = &results[i];
+
+		strftime(ts, sizeof(ts), "%Y-%m-%d %H:%M:%S", localtime(&r->time));
+		printf("%s %s after %d attempt(s), last took %ums\n", ts,
+				r->status == SUCCESS ? "reconnected" : "FAILED",
+				r->attempts, r->duration_ms);
+	}
+	return SUCCESS;
+}
+
+static int handle_wifi_wake(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, wifi_wake_args, sizeof(wifi_wake_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *duration = get_arg_value(argc, argv, wifi_wake_args,
+			sizeof(wifi_wake_args)/sizeof(cmd_arg_t),
+			"--duration");
+
+	int duration_sec = atoi(duration);
+	if (duration_sec <= 0) {
+		printf("Invalid duration %d\n", duration_sec);
+		return FAILURE;
+	}
+
+	return wifi_schedule_wake(duration_sec);
+}
+
+static int handle_start_softap(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, start_softap_args, sizeof(start_softap_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *ssid = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--ssid");
+	const char *password = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--password");
+	redact_add_secret(password);
+	const char *channel = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--channel");
+	const char *sec_prot = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--sec_prot");
+	const char *max_conn = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--max_conn");
+	const char *hide_ssid = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--hide_ssid");
+	const char *bw = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--bw");
+	const char *band_mode = get_arg_value(argc, argv, start_softap_args,
+			sizeof(start_softap_args)/sizeof(cmd_arg_t),
+			"--band_mode");
+
+	/* Use default values from ctrl_config.h if arguments are not provided */
+	if (!ssid) {
+		ssid = SOFTAP_MODE_SSID;
+		printf("Using default SSID: %s\n", ssid);
+	}
+
+	if (!password) {
+		password = SOFTAP_MODE_PWD;
+		printf("Using default password\n");
+	}
+
+	int channel_value = channel ? atoi(channel) : SOFTAP_MODE_CHANNEL;
+	const char *encryption_mode = sec_prot ? sec_prot : "wpa2_psk"; // Default to WPA2
+	int max_conn_value = max_conn ? atoi(max_conn) : SOFTAP_MODE_MAX_ALLOWED_CLIENTS;
+	bool hide_ssid_value = hide_ssid ? is_arg_true(hide_ssid) : SOFTAP_MODE_SSID_HIDDEN;
+	int bw_value = bw ? atoi(bw) : SOFTAP_MODE_BANDWIDTH;
+
+	int band_mode_value = SOFTAP_BAND_MODE;
+	if (band_mode) {
+		if (strcmp(band_mode, "2.4G") == 0) {
+			band_mode_value = WIFI_BAND_MODE_24G;
+		} else if (strcmp(band_mode, "5G") == 0) {
+			band_mode_value = WIFI_BAND_MODE_5G;
+		} else if (strcmp(band_mode, "auto") == 0) {
+			band_mode_value = WIFI_BAND_MODE_AUTO;
+		}
+	}
+
+	return test_softap_mode_start_with_params(
+			ssid,
+			password,
+			channel_value,
+			encryption_mode,
+			max_conn_value,
+			hide_ssid_value,
+			bw_value,
+			band_mode_value
+			);
+}
+
+static int handle_get_softap_info(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_softap_mode_get_info();
+}
+
+static int handle_softap_connected_clients_info(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_softap_mode_connected_clients_info();
+}
+
+static int handle_softap_sta_details(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return custom_rpc_softap_get_sta_details();
+}
+
+static int handle_softap_kick_sta(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, softap_kick_sta_args, sizeof(softap_kick_sta_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mac = get_arg_value(argc, argv, softap_kick_sta_args,
+			sizeof(softap_kick_sta_args)/sizeof(cmd_arg_t),
+			"--mac");
+
+	return custom_rpc_softap_kick_sta(mac);
+}
+
+static int handle_softap_acl(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, softap_acl_args, sizeof(softap_acl_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, softap_acl_args,
+			sizeof(softap_acl_args)/sizeof(cmd_arg_t),
+			"--mode");
+	const char *macs = get_arg_value(argc, argv, softap_acl_args,
+			sizeof(softap_acl_args)/sizeof(cmd_arg_t),
+			"--macs");
+	const char *isolate = get_arg_value(argc, argv, softap_acl_args,
+			sizeof(softap_acl_args)/sizeof(cmd_arg_t),
+			"--isolate");
+
+	bool isolate_value = isolate ? is_arg_true(isolate) : false;
+
+	if (custom_rpc_set_softap_acl(get_choice_index(softap_acl_choices, mode), isolate_value, macs) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("SoftAP access control: %s, client isolation: %s\n", mode, isolate_value ? "on" : "off");
+	return SUCCESS;
+}
+
+static int handle_get_softap_acl(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return custom_rpc_get_softap_acl();
+}
+
+static int handle_stop_softap(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_softap_mode_stop();
+}
+
+static int handle_guest_ap(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, guest_ap_args, sizeof(guest_ap_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, guest_ap_args,
+			sizeof(guest_ap_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *duration = get_arg_value(argc, argv, guest_ap_args,
+			sizeof(guest_ap_args)/sizeof(cmd_arg_t),
+			"--duration");
+	const char *ssid = get_arg_value(argc, argv, guest_ap_args,
+			sizeof(guest_ap_args)/sizeof(cmd_arg_t),
+			"--ssid");
+	const char *password = get_arg_value(argc, argv, guest_ap_args,
+			sizeof(guest_ap_args)/sizeof(cmd_arg_t),
+			"--password");
+	char pwd[PASSWORD_LENGTH] = {0};
+	int duration_sec = duration ? atoi(duration) : GUEST_AP_DURATION_SEC;
+
+	if (!is_arg_true(enable)) {
+		if (guest_ap_remaining_sec() < 0) {
+			printf("No guest AP running\n");
+			return SUCCESS;
+		}
+		guest_ap_stop();
+		return SUCCESS;
+	}
+
+	if (!ssid) {
+		ssid = GUEST_AP_SSID;
+	}
+
+	if (guest_ap_start(ssid, password, duration_sec, pwd, sizeof(pwd)) != SUCCESS) {
+		return FAILURE;
+	}
+	redact_add_secret(pwd);
+	printf("Guest AP \"%s\" up for %ds\n", ssid, duration_sec);
+	printf("Password: %s\n", pwd);
+	return SUCCESS;
+}
+
+static int handle_status_led(int argc, char **argv) {
+	int patterns[STATUS_LED_MAX_STATE] = {
+		[STATUS_LED_STATE_CONNECTED] = STATUS_LED_ON,
+		[STATUS_LED_STATE_NO_IP] = STATUS_LED_DOUBLE_BLINK,
+		[STATUS_LED_STATE_WIFI_DOWN] = STATUS_LED_SLOW_BLINK,
+		[STATUS_LED_STATE_ESP_DOWN] = STATUS_LED_FAST_BLINK,
+	};
+	const char *state_args[STATUS_LED_MAX_STATE] = {
+		[STATUS_LED_STATE_CONNECTED] = "--connected",
+		[STATUS_LED_STATE_NO_IP] = "--no_ip",
+		[STATUS_LED_STATE_WIFI_DOWN] = "--wifi_down",
+		[STATUS_LED_STATE_ESP_DOWN] = "--esp_down",
+	};
+
+	if (!parse_arguments(argc, argv, status_led_args, sizeof(status_led_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, status_led_args,
+			sizeof(status_led_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *led = get_arg_value(argc, argv, status_led_args,
+			sizeof(status_led_args)/sizeof(cmd_arg_t),
+			"--led");
+
+	if (!is_arg_true(enable)) {
+		status_led_stop();
+		printf("Status LED stopped\n");
+		return SUCCESS;
+	}
+
+	if (!led) {
+		printf("--led is required to enable status LED\n");
+		return FAILURE;
+	}
+
+	for (int i = 0; i < STATUS_LED_MAX_STATE; i++) {
+		const char *pattern = get_arg_value(argc, argv, status_led_args,
+				sizeof(status_led_args)/sizeof(cmd_arg_t),
+				state_args[i]);
+
+		if (pattern) {
+			patterns[i] = status_led_pattern_from_str(pattern);
+		}
+	}
+
+	if (status_led_start(led, patterns) != SUCCESS) {
+		return FAILURE;
+	}
+	printf("Status LED '%s' started:", led);
+	for (int i = 0; i < STATUS_LED_MAX_STATE; i++) {
+		printf(" %s=%s", status_led_state_str(i), status_led_pattern_str(patterns[i]));
+	}
+	printf("\n");
+	return SUCCESS;
+}
+
+static int handle_udp_echo(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, udp_echo_args, sizeof(udp_echo_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, udp_echo_args,
+			sizeof(udp_echo_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *port = get_arg_value(argc, argv, udp_echo_args,
+			sizeof(udp_echo_args)/sizeof(cmd_arg_t),
+			"--port");
+	int port_value = port ? atoi(port) : UDP_ECHO_DEFAULT_PORT;
+
+	if (!is_arg_true(enable)) {
+		udp_echo_stop();
+		printf("UDP echo stopped\n");
+		return SUCCESS;
+	}
+
+	if (udp_echo_start(port_value) != SUCCESS) {
+		return FAILURE;
+	}
+	printf("UDP echo answering on port %d\n", port_value);
+	return SUCCESS;
+}
+
+static int handle_udp_probe(int argc, char **argv) {
+	udp_probe_result_t r = {0};
+
+	if (!parse_arguments(argc, argv, udp_probe_args, sizeof(udp_probe_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *host = get_arg_value(argc, argv, udp_probe_args,
+			sizeof(udp_probe_args)/sizeof(cmd_arg_t),
+			"--host");
+	const char *port = get_arg_value(argc, argv, udp_probe_args,
+			sizeof(udp_probe_args)/sizeof(cmd_arg_t),
+			"--port");
+	const char *count = get_arg_value(argc, argv, udp_probe_args,
+			sizeof(udp_probe_args)/sizeof(cmd_arg_t),
+			"--count");
+	const char *interval = get_arg_value(argc, argv, udp_probe_args,
+			sizeof(udp_probe_args)/sizeof(cmd_arg_t),
+			"--interval");
+	const char *timeout = get_arg_value(argc, argv, udp_probe_args,
+			sizeof(udp_probe_args)/sizeof(cmd_arg_t),
+			"--timeout");
+
+	if (udp_probe_run(host, port ? atoi(port) : UDP_ECHO_DEFAULT_PORT,
+				count ? atoi(count) : 20, interval ? atoi(interval) : 100,
+				timeout ? atoi(timeout) : 1000, &r) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("%d sent, %d received, %d%% loss\n", r.sent, r.received,
+			(r.sent - r.received) * 100 / r.sent);
+	if (!r.received) {
+		return SUCCESS;
+	}
+	printf("Round trip min/avg/max: %.2f/%.2f/%.2f ms\n", r.rtt_min_us / 1000.0,
+			r.rtt_avg_us / 1000.0, r.rtt_max_us / 1000.0);
+	printf("Jitter: %.2f ms\n", r.jitter_us / 1000.0);
+	printf("One way out/back: %.2f/%.2f ms (valid only with synced clocks)\n",
+			r.out_avg_us / 1000.0, r.back_avg_us / 1000.0);
+	return SUCCESS;
+}
+
+static int handle_set_wifi_power_save(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_wifi_power_save_args, sizeof(set_wifi_power_save_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, set_wifi_power_save_args,
+			sizeof(set_wifi_power_save_args)/sizeof(cmd_arg_t),
+			"--mode");
+
+	if (strcmp(mode, "none") == 0) {
+		return test_set_wifi_power_save_mode(WIFI_POWER_SAVE_MODE_NONE);
+	} else if (strcmp(mode, "min") == 0) {
+		return test_set_wifi_power_save_mode(WIFI_POWER_SAVE_MODE_MIN);
+	} else if (strcmp(mode, "max") == 0) {
+		return test_set_wifi_power_save_mode(WIFI_POWER_SAVE_MODE_MAX);
+	}
+
+	return FAILURE;
+}
+
+static int handle_get_wifi_power_save(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_get_wifi_power_save_mode();
+}
+
+static int handle_set_wifi_max_tx_power(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_wifi_max_tx_power_args, sizeof(set_wifi_max_tx_power_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *map_val = get_arg_value(argc, argv, set_wifi_max_tx_power_args,
+			sizeof(set_wifi_max_tx_power_args)/sizeof(cmd_arg_t),
+			"--map_val");
+
+	int map_value = atoi(map_val);
+	return test_wifi_set_max_tx_power(map_value);
+}
+
+static int handle_get_wifi_curr_tx_power(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_wifi_get_curr_tx_power();
+}
+
+static uint8_t get_custom_rpc_iface(const char *mode) {
+	return (strcmp(mode, "softap") == 0) ? CUSTOM_RPC_WIFI_IF_AP : CUSTOM_RPC_WIFI_IF_STA;
+}
+
+static int handle_get_wifi_protocol(int argc, char **argv) {
+	uint8_t protocol = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, get_wifi_protocol_args, sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, get_wifi_protocol_args,
+			sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t),
+			"--mode");
+
+	if (custom_rpc_get_wifi_protocol(get_custom_rpc_iface(mode), &protocol) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("%s protocols:%s%s%s%s\n", mode,
+			(protocol & CUSTOM_RPC_WIFI_PROTOCOL_11B) ? " 11b" : "",
+			(protocol & CUSTOM_RPC_WIFI_PROTOCOL_11G) ? " 11g" : "",
+			(protocol & CUSTOM_RPC_WIFI_PROTOCOL_11N) ? " 11n" : "",
+			(protocol & CUSTOM_RPC_WIFI_PROTOCOL_LR) ? " LR" : "");
+	return SUCCESS;
+}
+
+static int handle_set_wifi_long_range(int argc, char **argv) {
+	uint8_t protocol = 0;
+	uint8_t iface = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_wifi_long_range_args, sizeof(set_wifi_long_range_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, set_wifi_long_range_args,
+			sizeof(set_wifi_long_range_args)/sizeof(cmd_arg_t),
+			"--mode");
+	const char *enable = get_arg_value(argc, argv, set_wifi_long_range_args,
+			sizeof(set_wifi_long_range_args)/sizeof(cmd_arg_t),
+			"--enable");
+
+	iface = get_custom_rpc_iface(mode);
+	if (custom_rpc_get_wifi_protocol(iface, &protocol) != SUCCESS) {
+		return FAILURE;
+	}
+
+	/* Keep b/g/n as is, so non-ESP peers can still associate */
+	if (is_arg_true(enable)) {
+		protocol |= CUSTOM_RPC_WIFI_PROTOCOL_LR;
+	} else {
+		protocol &= ~CUSTOM_RPC_WIFI_PROTOCOL_LR;
+	}
+
+	if (custom_rpc_set_wifi_protocol(iface, protocol) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("Long range mode %s on %s\n", is_arg_true(enable) ? "enabled" : "disabled", mode);
+	return SUCCESS;
+}
+
+static int handle_set_wifi_protocol(int argc, char **argv) {
+	char list[32] = {0};
+	char *saveptr = NULL;
+	char *token = NULL;
+	uint8_t protocol = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_wifi_protocol_args, sizeof(set_wifi_protocol_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, set_wifi_protocol_args,
+			sizeof(set_wifi_protocol_args)/sizeof(cmd_arg_t),
+			"--mode");
+	const char *protocols = get_arg_value(argc, argv, set_wifi_protocol_args,
+			sizeof(set_wifi_protocol_args)/sizeof(cmd_arg_t),
+			"--protocols");
+
+	strncpy(list, protocols, sizeof(list) - 1);
+	for (token = strtok_r(list, ",", &saveptr); token; token = strtok_r(NULL, ",", &saveptr)) {
+		if (strcasecmp(token, "b") == 0) {
+			protocol |= CUSTOM_RPC_WIFI_PROTOCOL_11B;
+		} else if (strcasecmp(token, "g") == 0) {
+			protocol |= CUSTOM_RPC_WIFI_PROTOCOL_11G;
+		} else if (strcasecmp(token, "n") == 0) {
+			protocol |= CUSTOM_RPC_WIFI_PROTOCOL_11N;
+		} else if (strcasecmp(token, "lr") == 0) {
+			protocol |= CUSTOM_RPC_WIFI_PROTOCOL_LR;
+		} else {
+			printf("Unknown protocol '%s', use b, g, n or lr\n", token);
+			return FAILURE;
+		}
+	}
+
+	if (!protocol) {
+		printf("At least one protocol is needed\n");
+		return FAILURE;
+	}
+
+	return custom_rpc_set_wifi_protocol(get_custom_rpc_iface(mode), protocol);
+}
+
+static int handle_get_wifi_bandwidth(int argc, char **argv) {
+	uint8_t bandwidth = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, get_wifi_protocol_args, sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, get_wifi_protocol_args,
+			sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t),
+			"--mode");
+
+	if (custom_rpc_get_wifi_bandwidth(get_custom_rpc_iface(mode), &bandwidth) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("%s bandwidth: %s\n", mode, bandwidth == CUSTOM_RPC_WIFI_BW_HT40 ? "HT40" : "HT20");
+	return SUCCESS;
+}
+
+static int handle_set_wifi_bandwidth(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_wifi_bandwidth_args, sizeof(set_wifi_bandwidth_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, set_wifi_bandwidth_args,
+			sizeof(set_wifi_bandwidth_args)/sizeof(cmd_arg_t),
+			"--mode");
+	const char *bw = get_arg_value(argc, argv, set_wifi_bandwidth_args,
+			sizeof(set_wifi_bandwidth_args)/sizeof(cmd_arg_t),
+			"--bw");
+
+	return custom_rpc_set_wifi_bandwidth(get_custom_rpc_iface(mode),
+			strcmp(bw, "40") == 0 ? CUSTOM_RPC_WIFI_BW_HT40 : CUSTOM_RPC_WIFI_BW_HT20);
+}
+
+static int handle_get_pmf(int argc, char **argv) {
+	bool capable = false, required = false;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, get_wifi_protocol_args, sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, get_wifi_protocol_args,
+			sizeof(get_wifi_protocol_args)/sizeof(cmd_arg_t),
+			"--mode");
+
+	if (custom_rpc_get_pmf_config(get_custom_rpc_iface(mode), &capable, &required) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("%s PMF capable: %s, required: %s\n", mode,
+			capable ? "true" : "false", required ? "true" : "false");
+	return SUCCESS;
+}
+
+static int handle_set_pmf(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_pmf_args, sizeof(set_pmf_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *mode = get_arg_value(argc, argv, set_pmf_args,
+			sizeof(set_pmf_args)/sizeof(cmd_arg_t),
+			"--mode");
+	const char *capable = get_arg_value(argc, argv, set_pmf_args,
+			sizeof(set_pmf_args)/sizeof(cmd_arg_t),
+			"--capable");
+	const char *required = get_arg_value(argc, argv, set_pmf_args,
+			sizeof(set_pmf_args)/sizeof(cmd_arg_t),
+			"--required");
+
+	bool required_value = required ? is_arg_true(required) : false;
+	bool capable_value = capable ? is_arg_true(capable) : true;
+
+	if (required_value && !capable_value) {
+		printf("PMF required needs PMF capable\n");
+		return FAILURE;
+	}
+
+	if (custom_rpc_set_pmf_config(get_custom_rpc_iface(mode), capable_value, required_value) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("%s PMF capable: %s, required: %s (effective on next %s)\n", mode,
+			capable_value ? "true" : "false", required_value ? "true" : "false",
+			strcmp(mode, "softap") == 0 ? "start_softap" : "connect_ap");
+	return SUCCESS;
+}
+
+static int handle_get_scan_config(int argc, char **argv) {
+	custom_rpc_scan_config_t cfg = {0};
+
+	CHECK_RPC_ACTIVE();
+
+	if (custom_rpc_get_scan_config(&cfg) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("Background scan: %s, dwell %u ms per channel\n",
+			cfg.background ? "on" : "off", cfg.dwell_ms);
+	if (cfg.caps & CUSTOM_RPC_SCAN_CAP_HOME_DWELL) {
+		printf("Home channel dwell between channels: %u ms\n", cfg.home_dwell_ms);
+	} else {
+		printf("Home channel dwell: not supported by ESP firmware, scan leaves home channel for whole scan\n");
+	}
+	return SUCCESS;
+}
+
+static int handle_set_scan_config(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_scan_config_args, sizeof(set_scan_config_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *background = get_arg_value(argc, argv, set_scan_config_args,
+			sizeof(set_scan_config_args)/sizeof(cmd_arg_t),
+			"--background");
+	const char *dwell = get_arg_value(argc, argv, set_scan_config_args,
+			sizeof(set_scan_config_args)/sizeof(cmd_arg_t),
+			"--dwell");
+	const char *home_dwell = get_arg_value(argc, argv, set_scan_config_args,
+			sizeof(set_scan_config_args)/sizeof(cmd_arg_t),
+			"--home_dwell");
+
+	int dwell_ms = dwell ? atoi(dwell) : 0;
+	int home_dwell_ms = home_dwell ? atoi(home_dwell) : 0;
+
+	if (dwell && (dwell_ms < CUSTOM_RPC_SCAN_DWELL_MIN_MS || dwell_ms > CUSTOM_RPC_SCAN_DWELL_MAX_MS)) {
+		printf("Dwell must be %u to %u ms\n", CUSTOM_RPC_SCAN_DWELL_MIN_MS, CUSTOM_RPC_SCAN_DWELL_MAX_MS);
+		return FAILURE;
+	}
+	if (home_dwell && (home_dwell_ms < CUSTOM_RPC_SCAN_HOME_DWELL_MIN_MS ||
+	                   home_dwell_ms > CUSTOM_RPC_SCAN_HOME_DWELL_MAX_MS)) {
+		printf("Home dwell must be %u to %u ms\n", CUSTOM_RPC_SCAN_HOME_DWELL_MIN_MS,
+				CUSTOM_RPC_SCAN_HOME_DWELL_MAX_MS);
+		return FAILURE;
+	}
+
+	if (custom_rpc_set_scan_config(is_arg_true(background), dwell_ms, home_dwell_ms) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("Background scan %s, effective on next scan\n", is_arg_true(background) ? "on" : "off");
+	return SUCCESS;
+}
+
+static int handle_get_partition_table(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return custom_rpc_get_partition_table();
+}
+
+static int handle_read_flash(int argc, char **argv) {
+	unsigned long offset_value = 0, len_value = 0;
+	char *endptr = NULL;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, read_flash_args, sizeof(read_flash_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *partition = get_arg_value(argc, argv, read_flash_args,
+			sizeof(read_flash_args)/sizeof(cmd_arg_t),
+			"--partition");
+	const char *offset = get_arg_value(argc, argv, read_flash_args,
+			sizeof(read_flash_args)/sizeof(cmd_arg_t),
+			"--offset");
+	const char *len = get_arg_value(argc, argv, read_flash_args,
+			sizeof(read_flash_args)/sizeof(cmd_arg_t),
+			"--len");
+	const char *file = get_arg_value(argc, argv, read_flash_args,
+			sizeof(read_flash_args)/sizeof(cmd_arg_t),
+			"--file");
+
+	if (offset) {
+		offset_value = strtoul(offset, &endptr, 0);
+		if (*endptr != '\0' || offset_value > UINT32_MAX) {
+			printf("Invalid offset: %s\n", offset);
+			return FAILURE;
+		}
+	}
+
+	len_value = strtoul(len, &endptr, 0);
+	if (*endptr != '\0' || !len_value || len_value > UINT32_MAX) {
+		printf("Invalid length: %s\n", len);
+		return FAILURE;
+	}
+
+	return custom_rpc_read_flash(partition, offset_value, len_value, file);
+}
+
+static int handle_set_esp_log_level(int argc, char **argv) {
+	uint8_t level_value = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_esp_log_level_args, sizeof(set_esp_log_level_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *level = get_arg_value(argc, argv, set_esp_log_level_args,
+			sizeof(set_esp_log_level_args)/sizeof(cmd_arg_t),
+			"--level");
+	const char *tag = get_arg_value(argc, argv, set_esp_log_level_args,
+			sizeof(set_esp_log_level_args)/sizeof(cmd_arg_t),
+			"--tag");
+
+	while (log_level_choices[level_value] && strcmp(level, log_level_choices[level_value]) != 0)
+		level_value++;
+
+	if (custom_rpc_set_log_level(tag, level_value) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("ESP log level of %s set to %s\n", tag ? tag : "all tags", level);
+	return SUCCESS;
+}
+
+static int handle_read_adc(int argc, char **argv) {
+	uint8_t atten_value = CUSTOM_RPC_ADC_ATTEN_DB_12;
+	uint16_t raw = 0, mv = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, read_adc_args, sizeof(read_adc_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *channel = get_arg_value(argc, argv, read_adc_args,
+			sizeof(read_adc_args)/sizeof(cmd_arg_t),
+			"--channel");
+	const char *atten = get_arg_value(argc, argv, read_adc_args,
+			sizeof(read_adc_args)/sizeof(cmd_arg_t),
+			"--atten");
+
+	int channel_value = atoi(channel);
+	if (channel_value < 0 || channel_value > 255) {
+		printf("Invalid channel %d\n", channel_value);
+		return FAILURE;
+	}
+
+	if (atten) {
+		atten_value = 0;
+		while (adc_atten_choices[atten_value] && strcmp(atten, adc_atten_choices[atten_value]) != 0)
+			atten_value++;
+	}
+
+	if (custom_rpc_read_adc(channel_value, atten_value, &raw, &mv) != SUCCESS) {
+		return FAILURE;
+	}
+
+	if (mv == CUSTOM_RPC_ADC_MV_UNKNOWN) {
+		printf("ADC1 channel %d: raw %u (not calibrated)\n", channel_value, raw);
+	} else {
+		printf("ADC1 channel %d: raw %u, %u mV\n", channel_value, raw, mv);
+	}
+	return SUCCESS;
+}
+
+static int handle_get_chip_temp(int argc, char **argv) {
+	float celsius = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (custom_rpc_read_chip_temp(&celsius) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("ESP chip temperature: %.1f C\n", celsius);
+	return SUCCESS;
+}
+
+/* Parses one "action:proto[:port][:mcast]" rule */
+static int parse_filter_rule(char *str, custom_rpc_filter_rule_t *rule) {
+	char *saveptr = NULL;
+	char *action = strtok_r(str, ":", &saveptr);
+	char *proto = strtok_r(NULL, ":", &saveptr);
+	char *field = NULL;
+	char *endptr = NULL;
+	int action_value = action ? get_choice_index(filter_action_choices, action) : -1;
+
+	if (action_value < 0 || !proto) {
+		return FAILURE;
+	}
+	rule->action = action_value;
+
+	if (strcasecmp(proto, "any") == 0)
+		rule->proto = CUSTOM_RPC_FILTER_PROTO_ANY;
+	else if (strcasecmp(proto, "tcp") == 0)
+		rule->proto = CUSTOM_RPC_FILTER_PROTO_TCP;
+	else if (strcasecmp(proto, "udp") == 0)
+		rule->proto = CUSTOM_RPC_FILTER_PROTO_UDP;
+	else if (strcasecmp(proto, "icmp") == 0)
+		rule->proto = CUSTOM_RPC_FILTER_PROTO_ICMP;
+	else if (strcasecmp(proto, "icmpv6") == 0)
+		rule->proto = CUSTOM_RPC_FILTER_PROTO_ICMPV6;
+	else
+		return FAILURE;
+
+	while ((field = strtok_r(NULL, ":", &saveptr))) {
+		if (strcasecmp(field, "mcast") == 0) {
+			rule->multicast = 1;
+			continue;
+		}
+		unsigned long port = strtoul(field, &endptr, 10);
+		if (*endptr != '\0' || port > 65535)
+			return FAILURE;
+		rule->port = port;
+	}
+	return SUCCESS;
+}
+
+static int handle_set_traffic_filter(int argc, char **argv) {
+	custom_rpc_filter_rule_t rules[CUSTOM_RPC_FILTER_MAX_RULES] = {0};
+	char buf[512] = {0};
+	char *saveptr = NULL;
+	char *tok = NULL;
+	int num = 0;
+
+	CHECK_RPC_ACTIVE();
+
+	if (!parse_arguments(argc, argv, set_traffic_filter_args, sizeof(set_traffic_filter_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *default_action = get_arg_value(argc, argv, set_traffic_filter_args,
+			sizeof(set_traffic_filter_args)/sizeof(cmd_arg_t),
+			"--default");
+	const char *rules_str = get_arg_value(argc, argv, set_traffic_filter_args,
+			sizeof(set_traffic_filter_args)/sizeof(cmd_arg_t),
+			"--rules");
+
+	if (rules_str) {
+		if (strlen(rules_str) >= sizeof(buf)) {
+			printf("Rules too long\n");
+			return FAILURE;
+		}
+		strncpy(buf, rules_str, sizeof(buf) - 1);
+
+		for (tok = strtok_r(buf, ",", &saveptr); tok; tok = strtok_r(NULL, ",", &saveptr)) {
+			if (num >= CUSTOM_RPC_FILTER_MAX_RULES) {
+				printf("At most %d rules supported\n", CUSTOM_RPC_FILTER_MAX_RULES);
+				return FAILURE;
+			}
+			if (parse_filter_rule(tok, &rules[num]) != SUCCESS) {
+				printf("Invalid rule '%s', expected action:proto[:port][:mcast]\n", tok);
+				return FAILURE;
+			}
+			num++;
+		}
+	}
+
+	if (custom_rpc_set_traffic_filter(get_choice_index(filter_action_choices, default_action),
+				rules, num) != SUCCESS) {
+		return FAILURE;
+	}
+
+	printf("Traffic filter set with %d rule(s), default %s\n", num, default_action);
+	return SUCCESS;
+}
+
+static int handle_get_traffic_filter(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return custom_rpc_get_traffic_filter();
+}
+
+static int handle_get_esp_event_log(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return custom_rpc_get_event_log();
+}
+
+static int handle_clear_esp_event_log(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return custom_rpc_clear_event_log();
+}
+
+static int handle_enable_wifi(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_enable_wifi();
+}
+
+static int handle_disable_wifi(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_disable_wifi();
+}
+
+static int handle_enable_bt(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_enable_bt();
+}
+
+static int handle_disable_bt(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	return test_disable_bt();
+}
+
+static int handle_get_fw_version(int argc, char **argv) {
+	CHECK_RPC_ACTIVE();
+	char version[100];
+	uint16_t version_size = sizeof(version);
+	int ret = 0;
+	ret = test_get_fw_version_with_params(version, version_size);
+	if (ret != SUCCESS) {
+		printf("Failed to get firmware version\n");
+		return FAILURE;
+	}
+	printf("Firmware version: %s\n", version);
+	return SUCCESS;
+}
+
+static const char *ctrl_rx_unexpected_str(int reason) {
+	switch (reason) {
+		case CTRL_RX_UNEXPECTED_MALFORMED:  return "malformed";
+		case CTRL_RX_UNEXPECTED_MSG_TYPE:   return "unknown msg type";
+		case CTRL_RX_UNEXPECTED_MSG_ID:     return "unknown msg id";
+		case CTRL_RX_UNEXPECTED_STALE_RESP: return "stale response";
+		default:                            return "unknown";
+	}
+}
+
+/* Runs in control path rx thread */
+static void strict_mode_handler(int reason, uint32_t msg_id) {
+	printf("PROTOCOL: dropped %s message from ESP, msg_id[%u]\n",
+			ctrl_rx_unexpected_str(reason), msg_id);
 }
 
-static int handle_disconnect_ap(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-
-	if (!parse_arguments(argc, argv, disconnect_ap_args, sizeof(disconnect_ap_args)/sizeof(cmd_arg_t))) {
+static int handle_strict_mode(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, strict_mode_args, sizeof(strict_mode_args)/sizeof(cmd_arg_t))) {
 		return FAILURE;
 	}
 
-	const char *reset_dhcp = get_arg_value(argc, argv, disconnect_ap_args,
-			sizeof(disconnect_ap_args)/sizeof(cmd_arg_t),
-			"--reset_dhcp");
+	const char *enable = get_arg_value(argc, argv, strict_mode_args,
+			sizeof(strict_mode_args)/sizeof(cmd_arg_t),
+			"--enable");
 
-	return test_station_mode_disconnect_with_params(reset_dhcp ? is_arg_true(reset_dhcp) : false);
+	if (is_arg_true(enable)) {
+		set_ctrl_rx_unexpected_callback(strict_mode_handler);
+		printf("Strict mode enabled\n");
+	} else {
+		set_ctrl_rx_unexpected_callback(NULL);
+		printf("Strict mode disabled\n");
+	}
+	return SUCCESS;
 }
 
-static int handle_softap_vendor_ie(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
+static int handle_get_ctrl_rx_stats(int argc, char **argv) {
+	ctrl_rx_stats_t stats = {0};
 
-	if (!parse_arguments(argc, argv, softap_vendor_ie_args, sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t))) {
+	if (get_ctrl_rx_stats(&stats) != SUCCESS) {
+		printf("Failed to get control rx stats\n");
 		return FAILURE;
 	}
 
-	const char *enable = get_arg_value(argc, argv, softap_vendor_ie_args,
-			sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t),
-			"--enable");
-	const char *data = get_arg_value(argc, argv, softap_vendor_ie_args,
-			sizeof(softap_vendor_ie_args)/sizeof(cmd_arg_t),
-			"--data");
+	printf("Dropped messages from ESP:\n");
+	printf("  malformed:        %u\n", stats.malformed);
+	printf("  unknown msg type: %u\n", stats.unknown_msg_type);
+	printf("  unknown msg id:   %u\n", stats.unknown_msg_id);
+	printf("  stale response:   %u\n", stats.stale_resp);
+	return SUCCESS;
+}
 
-	return test_softap_mode_set_vendor_ie(enable ? is_arg_true(enable) : true, data ? data : "");
+static const char *link_health_str(int health) {
+	switch (health) {
+		case CTRL_LINK_HEALTH_OK:
+			return "ok";
+		case CTRL_LINK_HEALTH_WIFI_DOWN:
+			return "ESP alive, Wi-Fi down";
+		case CTRL_LINK_HEALTH_ESP_UNRESPONSIVE:
+			return "ESP unresponsive";
+		default:
+			return "unknown";
+	}
 }
 
-static int handle_start_softap(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
+static int handle_get_link_health(int argc, char **argv) {
+	ctrl_link_status_t status = {0};
+	int rssi = 0;
 
-	if (!parse_arguments(argc, argv, start_softap_args, sizeof(start_softap_args)/sizeof(cmd_arg_t))) {
+	if (get_ctrl_link_status(&status) != SUCCESS) {
+		printf("Failed to get control link status\n");
 		return FAILURE;
 	}
 
-	const char *ssid = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--ssid");
-	const char *password = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--password");
-	const char *channel = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--channel");
-	const char *sec_prot = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--sec_prot");
-	const char *max_conn = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--max_conn");
-	const char *hide_ssid = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--hide_ssid");
-	const char *bw = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--bw");
-	const char *band_mode = get_arg_value(argc, argv, start_softap_args,
-			sizeof(start_softap_args)/sizeof(cmd_arg_t),
-			"--band_mode");
+	/* Station state is only followed from events after start, so ask ESP
+	 * in case it was already connected before this host came up */
+	if (is_rpc_active() && status.health != CTRL_LINK_HEALTH_ESP_UNRESPONSIVE) {
+		test_station_mode_get_rssi(&rssi);
+		get_ctrl_link_status(&status);
+	}
 
-	/* Use default values from ctrl_config.h if arguments are not provided */
-	if (!ssid) {
-		ssid = SOFTAP_MODE_SSID;
-		printf("Using default SSID: %s\n", ssid);
+	printf("Link health: %s\n", link_health_str(status.health));
+	printf("  consecutive timeouts: %u\n", status.consecutive_timeouts);
+	if (status.last_rx_age_sec < 0)
+		printf("  last rx from ESP:     never\n");
+	else
+		printf("  last rx from ESP:     %ds ago\n", status.last_rx_age_sec);
+	if (status.heartbeat_duration)
+		printf("  heartbeat interval:   %us\n", status.heartbeat_duration);
+	else
+		printf("  heartbeat interval:   off\n");
+	printf("  station connected:    %s\n", status.sta_connected ? "yes" : "no");
+	return SUCCESS;
+}
+
+/* Read-only commands whose output goes into support bundle */
+static const char *support_bundle_commands[] = {
+	"get_fw_version", "get_wifi_mode", "get_wifi_mac", "get_connected_ap_info",
+	"get_softap_info", "softap_sta_details", "get_country_code", "get_wifi_power_save",
+	"get_wifi_curr_tx_power", "get_link_health", "get_ctrl_rx_stats", "get_event_queue_stats",
+	"get_ctrl_rate_stats", "get_esp_event_log", "get_scan_config", "get_softap_acl",
+	"get_conn_history", "get_link_quality", "get_reconnect_test", "get_scan_cache", "get_dns", NULL
+};
+
+static const char *support_bundle_host_commands[] = {
+	"ip addr", "ip route", "cat /etc/resolv.conf", "lsmod | grep -i esp",
+	"dmesg | grep -i esp | tail -n 200", NULL
+};
+
+static int handle_support_bundle(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, support_bundle_args, sizeof(support_bundle_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
 	}
 
-	if (!password) {
-		password = SOFTAP_MODE_PWD;
-		printf("Using default password\n");
+	const char *file = get_arg_value(argc, argv, support_bundle_args,
+			sizeof(support_bundle_args)/sizeof(cmd_arg_t),
+			"--file");
+	char tail_cmd[SUPPORT_BUNDLE_LINE_LEN] = {0};
+	FILE *out = support_bundle_open(file);
+
+	if (!out) {
+		return FAILURE;
 	}
 
-	int channel_value = channel ? atoi(channel) : SOFTAP_MODE_CHANNEL;
-	const char *encryption_mode = sec_prot ? sec_prot : "wpa2_psk"; // Default to WPA2
-	int max_conn_value = max_conn ? atoi(max_conn) : SOFTAP_MODE_MAX_ALLOWED_CLIENTS;
-	bool hide_ssid_value = hide_ssid ? is_arg_true(hide_ssid) : SOFTAP_MODE_SSID_HIDDEN;
-	int bw_value = bw ? atoi(bw) : SOFTAP_MODE_BANDWIDTH;
+	for (int i = 0; support_bundle_commands[i]; i++) {
+		const shell_command_t *cmd = NULL;
+		char *cmd_argv[] = {(char *)support_bundle_commands[i], NULL};
 
-	int band_mode_value = SOFTAP_BAND_MODE;
-	if (band_mode) {
-		if (strcmp(band_mode, "2.4G") == 0) {
-			band_mode_value = WIFI_BAND_MODE_24G;
-		} else if (strcmp(band_mode, "5G") == 0) {
-			band_mode_value = WIFI_BAND_MODE_5G;
-		} else if (strcmp(band_mode, "auto") == 0) {
-			band_mode_value = WIFI_BAND_MODE_AUTO;
+		for (cmd = commands; cmd->name; cmd++) {
+			if (strcmp(cmd->name, support_bundle_commands[i]) == 0)
+				break;
 		}
+		if (!cmd->name)
+			continue;
+		support_bundle_section(out, cmd->name);
+		support_bundle_capture(out, cmd->handler, 1, cmd_argv);
 	}
 
-	return test_softap_mode_start_with_params(
-			ssid,
-			password,
-			channel_value,
-			encryption_mode,
-			max_conn_value,
-			hide_ssid_value,
-			bw_value,
-			band_mode_value
-			);
-}
+	for (int i = 0; support_bundle_host_commands[i]; i++) {
+		support_bundle_section(out, support_bundle_host_commands[i]);
+		support_bundle_add_cmd(out, support_bundle_host_commands[i]);
+	}
 
-static int handle_get_softap_info(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_softap_mode_get_info();
-}
+	if (AUDIT_LOG_FILE[0]) {
+		snprintf(tail_cmd, sizeof(tail_cmd), "tail -n 200 %s", AUDIT_LOG_FILE);
+		support_bundle_section(out, tail_cmd);
+		support_bundle_add_cmd(out, tail_cmd);
+	}
 
-static int handle_softap_connected_clients_info(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_softap_mode_connected_clients_info();
+	if (support_bundle_close(out) != SUCCESS) {
+		printf("Failed to write support bundle %s\n", file);
+		return FAILURE;
+	}
+	printf("Support bundle written to %s\n", file);
+	return SUCCESS;
 }
 
-static int handle_stop_softap(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_softap_mode_stop();
+static int handle_dry_run(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, dry_run_args, sizeof(dry_run_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *enable = get_arg_value(argc, argv, dry_run_args,
+			sizeof(dry_run_args)/sizeof(cmd_arg_t),
+			"--enable");
+
+	dry_run_mode = is_arg_true(enable);
+	if (dry_run_mode)
+		printf("Dry run enabled, state changing commands are only checked and printed\n");
+	else
+		printf("Dry run disabled\n");
+	return SUCCESS;
 }
 
-static int handle_set_wifi_power_save(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
+/* Argument check is the one done for every command by parse_arguments().
+ * Checks inside handlers, e.g. value ranges, are not covered */
+static void dry_run_command(const shell_command_t *cmd, int argc, char **argv) {
+	char cmdline[256] = {0};
 
-	if (!parse_arguments(argc, argv, set_wifi_power_save_args, sizeof(set_wifi_power_save_args)/sizeof(cmd_arg_t))) {
+	if (cmd->args && !parse_arguments(argc, argv, cmd->args, cmd->arg_count)) {
+		printf("dry run: %s would fail argument check\n", cmd->name);
+		return;
+	}
+
+	audit_log_format_cmdline(cmdline, sizeof(cmdline), argc, argv);
+	printf("dry run: would run: %s\n", cmdline);
+}
+
+static int handle_event_queue(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, event_queue_args, sizeof(event_queue_args)/sizeof(cmd_arg_t))) {
 		return FAILURE;
 	}
 
-	const char *mode = get_arg_value(argc, argv, set_wifi_power_save_args,
-			sizeof(set_wifi_power_save_args)/sizeof(cmd_arg_t),
-			"--mode");
+	const char *depth = get_arg_value(argc, argv, event_queue_args,
+			sizeof(event_queue_args)/sizeof(cmd_arg_t),
+			"--depth");
+	const char *overflow = get_arg_value(argc, argv, event_queue_args,
+			sizeof(event_queue_args)/sizeof(cmd_arg_t),
+			"--overflow");
+	int depth_value = atoi(depth);
+	int policy = overflow ? get_choice_index(event_overflow_choices, overflow) : 0;
+
+	if (policy < 0) {
+		printf("Invalid overflow policy: %s\n", overflow);
+		return FAILURE;
+	}
 
-	if (strcmp(mode, "none") == 0) {
-		return test_set_wifi_power_save_mode(WIFI_POWER_SAVE_MODE_NONE);
-	} else if (strcmp(mode, "min") == 0) {
-		return test_set_wifi_power_save_mode(WIFI_POWER_SAVE_MODE_MIN);
-	} else if (strcmp(mode, "max") == 0) {
-		return test_set_wifi_power_save_mode(WIFI_POWER_SAVE_MODE_MAX);
+	if (depth_value <= 0 || depth_value > CTRL_EVENT_QUEUE_MAX_DEPTH) {
+		printf("Depth must be 1 to %d\n", CTRL_EVENT_QUEUE_MAX_DEPTH);
+		return FAILURE;
 	}
 
-	return FAILURE;
+	if (set_ctrl_event_queue(depth_value, CTRL_EVENT_OVERFLOW_DROP_OLDEST + policy) != SUCCESS) {
+		printf("Failed to set event queue\n");
+		return FAILURE;
+	}
+	printf("Event queue of %d, %s on overflow\n", depth_value, event_overflow_choices[policy]);
+	return SUCCESS;
 }
 
-static int handle_get_wifi_power_save(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_get_wifi_power_save_mode();
+static int handle_get_event_queue_stats(int argc, char **argv) {
+	ctrl_event_queue_stats_t stats = {0};
+
+	if (get_ctrl_event_queue_stats(&stats) != SUCCESS) {
+		printf("Failed to get event queue stats\n");
+		return FAILURE;
+	}
+
+	if (!stats.depth) {
+		printf("Event queue not set, callbacks run in rx thread\n");
+		return SUCCESS;
+	}
+	printf("Event queue depth %u:\n", stats.depth);
+	printf("  queued:         %u\n", stats.queued);
+	printf("  high watermark: %u\n", stats.high_watermark);
+	printf("  dropped:        %u\n", stats.dropped);
+	return SUCCESS;
 }
 
-static int handle_set_wifi_max_tx_power(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
+static int handle_ctrl_rate_limit(int argc, char **argv) {
+	if (!parse_arguments(argc, argv, ctrl_rate_limit_args, sizeof(ctrl_rate_limit_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
 
-	if (!parse_arguments(argc, argv, set_wifi_max_tx_power_args, sizeof(set_wifi_max_tx_power_args)/sizeof(cmd_arg_t))) {
+	const char *rate = get_arg_value(argc, argv, ctrl_rate_limit_args,
+			sizeof(ctrl_rate_limit_args)/sizeof(cmd_arg_t),
+			"--rate");
+	const char *burst = get_arg_value(argc, argv, ctrl_rate_limit_args,
+			sizeof(ctrl_rate_limit_args)/sizeof(cmd_arg_t),
+			"--burst");
+	const char *max_delay = get_arg_value(argc, argv, ctrl_rate_limit_args,
+			sizeof(ctrl_rate_limit_args)/sizeof(cmd_arg_t),
+			"--max_delay");
+	int rate_value = atoi(rate);
+	int burst_value = burst ? atoi(burst) : 5;
+	int max_delay_value = max_delay ? atoi(max_delay) : 1000;
+
+	if (rate_value < 0 || rate_value > UINT16_MAX ||
+	    burst_value < 1 || burst_value > UINT16_MAX || max_delay_value < 0) {
+		printf("Invalid rate limit\n");
 		return FAILURE;
 	}
 
-	const char *map_val = get_arg_value(argc, argv, set_wifi_max_tx_power_args,
-			sizeof(set_wifi_max_tx_power_args)/sizeof(cmd_arg_t),
-			"--map_val");
+	if (set_ctrl_req_rate_limit(rate_value, burst_value, max_delay_value) != SUCCESS) {
+		printf("Failed to set rate limit\n");
+		return FAILURE;
+	}
 
-	int map_value = atoi(map_val);
-	return test_wifi_set_max_tx_power(map_value);
+	if (!rate_value) {
+		printf("Control request rate limit removed\n");
+		return SUCCESS;
+	}
+	printf("Control requests limited to %d/s, burst %d, waiting up to %dms\n",
+			rate_value, burst_value, max_delay_value);
+	return SUCCESS;
 }
 
-static int handle_get_wifi_curr_tx_power(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_wifi_get_curr_tx_power();
-}
+static int handle_get_ctrl_rate_stats(int argc, char **argv) {
+	ctrl_req_rate_stats_t stats = {0};
 
-static int handle_enable_wifi(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_enable_wifi();
-}
+	if (get_ctrl_req_rate_stats(&stats) != SUCCESS) {
+		printf("Failed to get rate limit stats\n");
+		return FAILURE;
+	}
 
-static int handle_disable_wifi(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_disable_wifi();
+	if (!stats.rate_per_sec) {
+		printf("Control requests not rate limited\n");
+		return SUCCESS;
+	}
+	printf("Control request rate limit %u/s, burst %u, max delay %ums:\n",
+			stats.rate_per_sec, stats.burst, stats.max_delay_ms);
+	printf("  delayed: %u\n", stats.delayed);
+	printf("  dropped: %u\n", stats.dropped);
+	return SUCCESS;
 }
 
-static int handle_enable_bt(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_enable_bt();
+/* Monitoring loop in auto_ip_restore_thread_handler sees this, tears
+ * down control path and sets it up again */
+static void link_recovery_reinit_rpc(void) {
+	rpc_state = RPC_STATE_INACTIVE;
 }
 
-static int handle_disable_bt(int argc, char **argv) {
-	CHECK_RPC_ACTIVE();
-	return test_disable_bt();
-}
+static int handle_link_recovery(int argc, char **argv) {
+	link_recovery_config_t cfg = {0};
 
-static int handle_get_fw_version(int argc, char **argv) {
 	CHECK_RPC_ACTIVE();
-	char version[100];
-	uint16_t version_size = sizeof(version);
-	int ret = 0;
-	ret = test_get_fw_version_with_params(version, version_size);
-	if (ret != SUCCESS) {
-		printf("Failed to get firmware version\n");
+
+	if (!parse_arguments(argc, argv, link_recovery_args, sizeof(link_recovery_args)/sizeof(cmd_arg_t))) {
 		return FAILURE;
 	}
-	printf("Firmware version: %s\n", version);
+
+	const char *enable = get_arg_value(argc, argv, link_recovery_args,
+			sizeof(link_recovery_args)/sizeof(cmd_arg_t),
+			"--enable");
+	const char *missed = get_arg_value(argc, argv, link_recovery_args,
+			sizeof(link_recovery_args)/sizeof(cmd_arg_t),
+			"--missed_heartbeats");
+	const char *timeouts = get_arg_value(argc, argv, link_recovery_args,
+			sizeof(link_recovery_args)/sizeof(cmd_arg_t),
+			"--timeouts");
+	const char *cooldown = get_arg_value(argc, argv, link_recovery_args,
+			sizeof(link_recovery_args)/sizeof(cmd_arg_t),
+			"--cooldown");
+	const char *reset_cmd = get_arg_value(argc, argv, link_recovery_args,
+			sizeof(link_recovery_args)/sizeof(cmd_arg_t),
+			"--reset_cmd");
+
+	if (!is_arg_true(enable)) {
+		link_recovery_stop();
+		printf("Link recovery stopped\n");
+		return SUCCESS;
+	}
+
+	cfg.max_missed_heartbeats = missed ? atoi(missed) : LINK_RECOVERY_MISSED_HEARTBEATS;
+	cfg.max_timeouts = timeouts ? atoi(timeouts) : LINK_RECOVERY_TIMEOUTS;
+	cfg.cooldown_sec = cooldown ? atoi(cooldown) : LINK_RECOVERY_COOLDOWN_SEC;
+	if (reset_cmd) {
+		if (strlen(reset_cmd) >= sizeof(cfg.reset_cmd)) {
+			printf("Reset command too long, max %zu characters\n", sizeof(cfg.reset_cmd) - 1);
+			return FAILURE;
+		}
+		strncpy(cfg.reset_cmd, reset_cmd, sizeof(cfg.reset_cmd) - 1);
+	}
+	cfg.reinit_rpc = link_recovery_reinit_rpc;
+
+	if (link_recovery_start(&cfg) != SUCCESS) {
+		return FAILURE;
+	}
+	link_recovery_rpc_ready();
+	printf("Link recovery started: %d missed heartbeats, %d timeouts, %ds cooldown, reset command %s\n",
+			cfg.max_missed_heartbeats, cfg.max_timeouts, cfg.cooldown_sec,
+			cfg.reset_cmd[0] ? cfg.reset_cmd : "none");
 	return SUCCESS;
 }
 
@@ -1053,8 +3223,25 @@ static void *auto_ip_restore_thread_handler(void *arg) {
 			printf("Subscribed to all events\n");
 		}
 
+		if (test_check_min_fw_version(MIN_FIRMWARE_VERSION,
+					MIN_FIRMWARE_VERSION_ENFORCE) != SUCCESS) {
+			/* Retrying would not help until ESP is reflashed */
+			printf("Stopping RPC, update ESP firmware and restart hosted_shell\n");
+			unregister_event_callbacks();
+			deinit_hosted_control_lib();
+			rpc_initialized = 0;
+			rpc_state = RPC_STATE_INACTIVE;
+			break;
+		}
+
 		rpc_state = RPC_STATE_ACTIVE;
 		printf("RPC at host is ready\n");
+		link_recovery_rpc_ready();
+		guest_ap_rpc_ready();
+		status_led_rpc_ready();
+		rogue_ap_watch_rpc_ready();
+		wifi_schedule_rpc_ready();
+		reconnect_test_rpc_ready();
 
 		/* Initialize the network structure fields */
 		memset(&sta_network, 0, sizeof(network_info_t));
@@ -1070,11 +3257,31 @@ static void *auto_ip_restore_thread_handler(void *arg) {
 		}
 
         /* Main monitoring loop */
+        int ticks = 0;
         while (!exit_thread_auto_ip_restore && rpc_state == RPC_STATE_ACTIVE) {
             usleep(NETWORK_CHECK_INTERVAL_MS * 1000);
+            if (++ticks >= WEBHOOK_IP_CHECK_TICKS) {
+                webhook_notify_check_ip(STA_INTERFACE);
+                check_station_ip_conflict();
+                ticks = 0;
+            }
         }
 
 		/* Clean up before potential reinitialization */
+		/* Do not leave guest network open once shell exits. Background
+		 * jobs only pause for link recovery reinit */
+		if (exit_thread_auto_ip_restore) {
+			guest_ap_stop();
+			rogue_ap_watch_stop();
+			wifi_schedule_stop();
+			reconnect_test_stop();
+		}
+		guest_ap_rpc_down();
+		link_recovery_rpc_down();
+		status_led_rpc_down();
+		rogue_ap_watch_rpc_down();
+		wifi_schedule_rpc_down();
+		reconnect_test_rpc_down();
 		unregister_event_callbacks();
 		deinit_hosted_control_lib();
 		rpc_state = RPC_STATE_INACTIVE;
@@ -1130,6 +3337,15 @@ static void stop_rpc_auto_ip_restore(void) {
 		auto_ip_restore_thread = 0;
 	}
 
+	link_recovery_stop();
+	guest_ap_stop();
+	rogue_ap_watch_stop();
+	wifi_schedule_stop();
+	reconnect_test_stop();
+	scan_cache_stop();
+	status_led_stop();
+	udp_echo_stop();
+
 	// Clean up resources
 	unregister_event_callbacks();
 
@@ -1201,7 +3417,16 @@ static int shell_run(shell_context_t *ctx) {
 			/* Find and execute command */
 			for (cmd = commands; cmd->name; cmd++) {
 				if (strcmp(cmd->name, args[0]) == 0) {
-					cmd->handler(argc, args);
+					if (dry_run_mode && is_audited_command(cmd->name)) {
+						dry_run_command(cmd, argc, args);
+						break;
+					}
+
+					int cmd_ret = cmd->handler(argc, args);
+
+					if (is_audited_command(cmd->name)) {
+						audit_log_record(argc, args, cmd_ret);
+					}
 					break;
 				}
 			}
@@ -1281,6 +3506,8 @@ int main(int argc, char *argv[]) {
 	/* Store context for signal handler */
 	set_shell_context(&ctx);
 
+	audit_log_configure(AUDIT_LOG_FILE, AUDIT_LOG_SYSLOG);
+
 	/* Initialize RPC first - this creates the app thread */
 	if (start_rpc_auto_ip_restore() != 0) {
 		printf("Failed to initialize RPC\n");
@@ -1625,4 +3852,66 @@ static int handle_set_country_code_with_ieee80211d_on(int argc, char **argv) {
 static int handle_get_country_code(int argc, char **argv) {
 	CHECK_RPC_ACTIVE();
 	return test_get_country_code();
-}
\ No newline at end of file
+}
+
+static int handle_get_dns(int argc, char **argv) {
+	char servers[MAX_DNS_SERVERS][DNS_ADDR_LENGTH];
+	int count = 0;
+
+	if (get_dns_servers(servers, MAX_DNS_SERVERS, &count) != SUCCESS) {
+		printf("Failed to read DNS servers\n");
+		return FAILURE;
+	}
+
+	if (!count) {
+		printf("No DNS servers configured\n");
+		return SUCCESS;
+	}
+
+	for (int i = 0; i < count; i++) {
+		printf("DNS server %d: %s\n", i + 1, servers[i]);
+	}
+	return SUCCESS;
+}
+
+static int handle_set_dns(int argc, char **argv) {
+	char list[MAX_DNS_SERVERS * DNS_ADDR_LENGTH] = {0};
+	const char *servers[MAX_DNS_SERVERS] = {0};
+	char *saveptr = NULL;
+	char *token = NULL;
+	int count = 0;
+
+	if (!parse_arguments(argc, argv, set_dns_args, sizeof(set_dns_args)/sizeof(cmd_arg_t))) {
+		return FAILURE;
+	}
+
+	const char *value = get_arg_value(argc, argv, set_dns_args,
+			sizeof(set_dns_args)/sizeof(cmd_arg_t),
+			"--servers");
+	if (!value) {
+		return FAILURE;
+	}
+
+	/* Cut list could end in a different valid address */
+	if (strlen(value) >= sizeof(list)) {
+		printf("DNS server list too long, max %zu characters\n", sizeof(list) - 1);
+		return FAILURE;
+	}
+
+	strncpy(list, value, sizeof(list) - 1);
+	for (token = strtok_r(list, ",", &saveptr); token; token = strtok_r(NULL, ",", &saveptr)) {
+		if (count == MAX_DNS_SERVERS) {
+			printf("At most %d DNS servers are supported\n", MAX_DNS_SERVERS);
+			return FAILURE;
+		}
+		servers[count++] = token;
+	}
+
+	if (set_dns_servers(servers, count) != SUCCESS) {
+		printf("Failed to set DNS servers\n");
+		return FAILURE;
+	}
+
+	printf("DNS servers updated\n");
+	return SUCCESS;
+}
@@ -0,0 +1,143 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+
+#include "test.h"
+#include "nw_helper_func.h"
+#include "conn_history.h"
+#include "link_quality.h"
+
+/* Score lost per factor */
+#define PENALTY_PER_DISCONNECT           10
+#define PENALTY_MAX_DISCONNECTS          30
+#define PENALTY_ESP_TIMEOUTS             20
+#define PENALTY_NO_DNS                   15
+#define PENALTY_NO_GATEWAY               30
+#define PENALTY_SLOW_GATEWAY             15
+/* Without IP, link is of no use whatever the signal */
+#define SCORE_MAX_NO_IP                  10
+
+static int rssi_score(int rssi)
+{
+	if (rssi <= LINK_QUALITY_RSSI_MIN)
+		return 0;
+	if (rssi >= LINK_QUALITY_RSSI_MAX)
+		return 100;
+	return (rssi - LINK_QUALITY_RSSI_MIN) * 100 /
+		(LINK_QUALITY_RSSI_MAX - LINK_QUALITY_RSSI_MIN);
+}
+
+static int recent_disconnects(void)
+{
+	conn_history_entry_t entries[CONN_HISTORY_MAX_ENTRIES];
+	uint64_t now = get_mono_ms();
+	int num = conn_history_get(entries, CONN_HISTORY_MAX_ENTRIES);
+	int count = 0;
+
+	for (int i = 0; i < num; i++) {
+		if (entries[i].type == CONN_HISTORY_DISCONNECT &&
+		    now - entries[i].mono_ms < LINK_QUALITY_UNSTABLE_WINDOW_SEC * 1000ULL)
+			count++;
+	}
+	return count;
+}
+
+int link_quality_get(const char *iface, link_quality_t *q)
+{
+	char ip[INET_ADDRSTRLEN] = {0};
+	char gateway[INET_ADDRSTRLEN] = {0};
+	char dns[MAX_DNS_SERVERS][DNS_ADDR_LENGTH];
+	ctrl_link_status_t st = {0};
+	int penalty = 0;
+
+	if (!iface || !q) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	memset(q, 0, sizeof(*q));
+	q->gateway_rtt_ms = -1;
+
+	if (get_ctrl_link_status(&st) != SUCCESS)
+		return FAILURE;
+	q->esp_timeouts = st.consecutive_timeouts;
+
+	if (!st.sta_connected || test_station_mode_get_rssi(&q->rssi) != SUCCESS) {
+		q->factors = LINK_QUALITY_NOT_CONNECTED;
+		return SUCCESS;
+	}
+
+	q->score = rssi_score(q->rssi);
+	if (q->rssi < LINK_QUALITY_WEAK_RSSI)
+		q->factors |= LINK_QUALITY_WEAK_SIGNAL;
+
+	q->disconnects = recent_disconnects();
+	if (q->disconnects) {
+		q->factors |= LINK_QUALITY_UNSTABLE;
+		penalty += q->disconnects * PENALTY_PER_DISCONNECT > PENALTY_MAX_DISCONNECTS ?
+			PENALTY_MAX_DISCONNECTS : q->disconnects * PENALTY_PER_DISCONNECT;
+	}
+
+	if (q->esp_timeouts) {
+		q->factors |= LINK_QUALITY_ESP_TIMEOUTS;
+		penalty += PENALTY_ESP_TIMEOUTS;
+	}
+
+	if (get_dns_servers(dns, MAX_DNS_SERVERS, &q->dns_servers) != SUCCESS || !q->dns_servers) {
+		q->factors |= LINK_QUALITY_NO_DNS;
+		penalty += PENALTY_NO_DNS;
+	}
+
+	q->has_ip = get_ipv4_addr(iface, ip, sizeof(ip)) == SUCCESS && ip[0];
+	if (!q->has_ip) {
+		/* Gateway can not be probed without own address either */
+		q->factors |= LINK_QUALITY_NO_IP;
+	} else if (get_default_gateway(iface, gateway, sizeof(gateway)) != SUCCESS ||
+		   arp_ping(iface, gateway, LINK_QUALITY_PROBE_MS, &q->gateway_rtt_ms) != SUCCESS ||
+		   q->gateway_rtt_ms < 0) {
+		q->factors |= LINK_QUALITY_NO_GATEWAY;
+		penalty += PENALTY_NO_GATEWAY;
+	} else if (q->gateway_rtt_ms > LINK_QUALITY_SLOW_RTT_MS) {
+		q->factors |= LINK_QUALITY_SLOW_GATEWAY;
+		penalty += PENALTY_SLOW_GATEWAY;
+	}
+
+	q->score -= penalty;
+	if (!q->has_ip && q->score > SCORE_MAX_NO_IP)
+		q->score = SCORE_MAX_NO_IP;
+	/* Connected link is never rated as no link */
+	if (q->score < 1)
+		q->score = 1;
+	return SUCCESS;
+}
+
+const char *link_quality_rating(int score)
+{
+	if (score >= 80)
+		return "excellent";
+	if (score >= 60)
+		return "good";
+	if (score >= 40)
+		return "fair";
+	if (score > 0)
+		return "poor";
+	return "none";
+}
+
+const char *link_quality_factor_str(uint32_t factor)
+{
+	switch (factor) {
+		case LINK_QUALITY_NOT_CONNECTED: return "not connected";
+		case LINK_QUALITY_WEAK_SIGNAL:   return "weak signal";
+		case LINK_QUALITY_UNSTABLE:      return "unstable";
+		case LINK_QUALITY_ESP_TIMEOUTS:  return "ESP not responding";
+		case LINK_QUALITY_NO_IP:         return "no IP address";
+		case LINK_QUALITY_NO_DNS:        return "no DNS";
+		case LINK_QUALITY_NO_GATEWAY:    return "gateway unreachable";
+		case LINK_QUALITY_SLOW_GATEWAY:  return "slow gateway";
+		default:                         return "unknown";
+	}
+}
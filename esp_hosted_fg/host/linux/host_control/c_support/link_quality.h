@@ -0,0 +1,66 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef LINK_QUALITY_H
+#define LINK_QUALITY_H
+
+#include <stdint.h>
+#include <stdbool.h>
+
+/* RSSI mapped linearly to 0..100 between these */
+#define LINK_QUALITY_RSSI_MIN            -90
+#define LINK_QUALITY_RSSI_MAX            -50
+#define LINK_QUALITY_WEAK_RSSI           -75
+#define LINK_QUALITY_UNSTABLE_WINDOW_SEC 3600
+#define LINK_QUALITY_PROBE_MS            1000
+#define LINK_QUALITY_SLOW_RTT_MS         100
+
+/* Factors lowering score, bits of link_quality_t.factors */
+#define LINK_QUALITY_NOT_CONNECTED       (1 << 0)
+#define LINK_QUALITY_WEAK_SIGNAL         (1 << 1)
+#define LINK_QUALITY_UNSTABLE            (1 << 2) /* Disconnects in last hour */
+#define LINK_QUALITY_ESP_TIMEOUTS        (1 << 3) /* Control requests timing out */
+#define LINK_QUALITY_NO_IP               (1 << 4) /* No IPv4 address, e.g. DHCP failed */
+#define LINK_QUALITY_NO_DNS              (1 << 5) /* No DNS server configured */
+#define LINK_QUALITY_NO_GATEWAY          (1 << 6) /* Gateway missing or not answering */
+#define LINK_QUALITY_SLOW_GATEWAY        (1 << 7) /* Gateway answered slower than LINK_QUALITY_SLOW_RTT_MS */
+
+typedef struct {
+	/* 0 (no link) to 100 */
+	int score;
+	/* LINK_QUALITY_* factors */
+	uint32_t factors;
+	int rssi;
+	int disconnects;
+	uint32_t esp_timeouts;
+	bool has_ip;
+	int dns_servers;
+	/* ARP round trip to default gateway, -1 if no reply or no gateway */
+	int gateway_rtt_ms;
+} link_quality_t;
+
+/**
+ * @brief Rate station link on 0..100 with contributing factors
+ *
+ * Score starts from RSSI, and is lowered by recent disconnects, control
+ * request timeouts, missing IPv4 address or DNS servers, and missing,
+ * unreachable or slow gateway. Gateway is ARP probed, which takes up to
+ * LINK_QUALITY_PROBE_MS and needs root
+ *
+ * @param iface Station interface, e.g. "ethsta0"
+ * @param q Filled with score and inputs
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int link_quality_get(const char *iface, link_quality_t *q);
+
+/**
+ * @brief Word for score: "excellent", "good", "fair", "poor" or "none"
+ */
+const char *link_quality_rating(int score);
+
+/**
+ * @brief Short description of factor bit, e.g. "weak signal"
+ */
+const char *link_quality_factor_str(uint32_t factor);
+
+#endif
@@ -0,0 +1,215 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+
+#include "test.h"
+#include "webhook_notify.h"
+#include "link_recovery.h"
+
+static link_recovery_config_t policy;
+
+static bool rpc_ready;
+static bool heartbeat_needed;
+static long ready_sec;
+/* Last step taken in current outage, 0 if none */
+static int step;
+static long action_sec;
+
+static pthread_t recovery_thread;
+static bool recovery_running;
+static pthread_mutex_t recovery_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t recovery_cond = PTHREAD_COND_INITIALIZER;
+
+static long mono_sec(void)
+{
+	struct timespec ts = {0};
+
+	clock_gettime(CLOCK_MONOTONIC, &ts);
+	return ts.tv_sec;
+}
+
+static void reinit_rpc(void)
+{
+	pthread_mutex_lock(&recovery_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&recovery_lock);
+
+	if (policy.reinit_rpc)
+		policy.reinit_rpc();
+}
+
+static void take_next_step(const ctrl_link_status_t *st, long rx_age)
+{
+	char detail[128] = {0};
+	int ret = 0;
+
+	step++;
+	if (step == LINK_RECOVERY_RESET_CMD && !policy.reset_cmd[0])
+		step++;
+	if (step > LINK_RECOVERY_ALERT) {
+		/* Alert already sent for this outage */
+		step = LINK_RECOVERY_ALERT;
+		return;
+	}
+
+	switch (step) {
+		case LINK_RECOVERY_REINIT_RPC:
+			printf("link recovery: ESP unresponsive (%u timeouts, last rx %lds ago), reopening control path\n",
+					st->consecutive_timeouts, rx_age);
+			reinit_rpc();
+			break;
+		case LINK_RECOVERY_RESET_CMD:
+			printf("link recovery: ESP still unresponsive, running: %s\n", policy.reset_cmd);
+			ret = system(policy.reset_cmd);
+			if (ret)
+				printf("link recovery: reset command exited with %d\n", ret);
+			reinit_rpc();
+			break;
+		case LINK_RECOVERY_ALERT:
+			snprintf(detail, sizeof(detail),
+					"ESP unresponsive after recovery attempts, last rx %lds ago", rx_age);
+			printf("link recovery: %s\n", detail);
+			webhook_notify(WEBHOOK_EVENT_ESP_UNRESPONSIVE, detail);
+			break;
+	}
+	action_sec = mono_sec();
+}
+
+static void check_link(void)
+{
+	ctrl_link_status_t st = {0};
+	bool unresponsive = false;
+	bool enable_heartbeat = false;
+	long since_ready = 0;
+	long rx_age = 0;
+
+	pthread_mutex_lock(&recovery_lock);
+	if (!rpc_ready) {
+		pthread_mutex_unlock(&recovery_lock);
+		return;
+	}
+	enable_heartbeat = heartbeat_needed;
+	heartbeat_needed = false;
+	since_ready = mono_sec() - ready_sec;
+	pthread_mutex_unlock(&recovery_lock);
+
+	/* A timeout here is counted like any other request */
+	if (enable_heartbeat && test_config_heartbeat() != SUCCESS)
+		printf("link recovery: failed to enable heartbeat\n");
+
+	if (get_ctrl_link_status(&st) != SUCCESS)
+		return;
+
+	/* Nothing heard since control path came up, count from then */
+	rx_age = st.last_rx_age_sec >= 0 ? st.last_rx_age_sec : since_ready;
+
+	if (policy.max_timeouts &&
+	    st.consecutive_timeouts >= (uint32_t)policy.max_timeouts)
+		unresponsive = true;
+	if (policy.max_missed_heartbeats && st.heartbeat_duration &&
+	    rx_age > (long)st.heartbeat_duration * policy.max_missed_heartbeats)
+		unresponsive = true;
+
+	if (!unresponsive) {
+		/* Only a message from ESP proves it is back */
+		if (step && st.last_rx_age_sec >= 0 && !st.consecutive_timeouts) {
+			printf("link recovery: ESP responsive again\n");
+			step = 0;
+		}
+		return;
+	}
+
+	if (step && mono_sec() - action_sec < policy.cooldown_sec)
+		return;
+
+	take_next_step(&st, rx_age);
+}
+
+static void *recovery_thread_handler(void *arg)
+{
+	struct timespec deadline = {0};
+
+	pthread_mutex_lock(&recovery_lock);
+	while (recovery_running) {
+		pthread_mutex_unlock(&recovery_lock);
+
+		check_link();
+
+		pthread_mutex_lock(&recovery_lock);
+		clock_gettime(CLOCK_REALTIME, &deadline);
+		deadline.tv_sec += LINK_RECOVERY_POLL_SEC;
+		while (recovery_running &&
+		       pthread_cond_timedwait(&recovery_cond, &recovery_lock, &deadline) != ETIMEDOUT)
+			;
+	}
+	pthread_mutex_unlock(&recovery_lock);
+
+	return NULL;
+}
+
+int link_recovery_start(const link_recovery_config_t *cfg)
+{
+	if (!cfg || cfg->max_missed_heartbeats < 0 || cfg->max_timeouts < 0 ||
+	    cfg->cooldown_sec < 0) {
+		printf("Invalid link recovery policy\n");
+		return FAILURE;
+	}
+
+	if (!cfg->max_missed_heartbeats && !cfg->max_timeouts) {
+		printf("Either missed heartbeats or timeouts need to be watched\n");
+		return FAILURE;
+	}
+
+	link_recovery_stop();
+
+	memcpy(&policy, cfg, sizeof(policy));
+	policy.reset_cmd[sizeof(policy.reset_cmd) - 1] = '\0';
+	rpc_ready = false;
+	step = 0;
+	recovery_running = true;
+
+	if (pthread_create(&recovery_thread, NULL, recovery_thread_handler, NULL) != 0) {
+		printf("Failed to create link recovery thread\n");
+		recovery_running = false;
+		return FAILURE;
+	}
+
+	return SUCCESS;
+}
+
+void link_recovery_rpc_ready(void)
+{
+	pthread_mutex_lock(&recovery_lock);
+	rpc_ready = true;
+	ready_sec = mono_sec();
+	/* Heartbeat interval is forgotten by host at control path init */
+	heartbeat_needed = policy.max_missed_heartbeats > 0;
+	pthread_mutex_unlock(&recovery_lock);
+}
+
+void link_recovery_rpc_down(void)
+{
+	pthread_mutex_lock(&recovery_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&recovery_lock);
+}
+
+void link_recovery_stop(void)
+{
+	pthread_mutex_lock(&recovery_lock);
+	if (!recovery_running) {
+		pthread_mutex_unlock(&recovery_lock);
+		return;
+	}
+	recovery_running = false;
+	pthread_cond_signal(&recovery_cond);
+	pthread_mutex_unlock(&recovery_lock);
+
+	pthread_join(recovery_thread, NULL);
+}
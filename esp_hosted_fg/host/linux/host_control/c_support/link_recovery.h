@@ -0,0 +1,61 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef LINK_RECOVERY_H
+#define LINK_RECOVERY_H
+
+#define LINK_RECOVERY_POLL_SEC           5
+#define LINK_RECOVERY_RESET_CMD_LEN      256
+
+/* Escalation steps, tried in this order while ESP stays unresponsive */
+typedef enum {
+	LINK_RECOVERY_REINIT_RPC = 1, /* Reopen control path to ESP */
+	LINK_RECOVERY_RESET_CMD,      /* Run reset_cmd, e.g. reload driver, which resets ESP via resetpin */
+	LINK_RECOVERY_ALERT,          /* Print and notify webhook, once per outage */
+} link_recovery_action_e;
+
+typedef struct {
+	/* Heartbeats missed in a row to act on, 0 to not watch heartbeat */
+	int max_missed_heartbeats;
+	/* Request timeouts in a row to act on, 0 to not watch timeouts */
+	int max_timeouts;
+	/* Seconds to wait after each step before escalating */
+	int cooldown_sec;
+	/* Shell command resetting ESP, empty to skip LINK_RECOVERY_RESET_CMD */
+	char reset_cmd[LINK_RECOVERY_RESET_CMD_LEN];
+	/* Called for LINK_RECOVERY_REINIT_RPC and after reset_cmd. Expected
+	 * to tear down control path and have it set up again, after which
+	 * link_recovery_rpc_ready() is called */
+	void (*reinit_rpc)(void);
+} link_recovery_config_t;
+
+/**
+ * @brief Start background recovery policy for unresponsive ESP
+ *
+ * Every LINK_RECOVERY_POLL_SEC, control link status is checked. When
+ * ESP missed max_missed_heartbeats or max_timeouts requests timed out in
+ * a row, next step of link_recovery_action_e is taken, at most once per
+ * cooldown_sec. Steps start over once ESP is heard from again.
+ * With max_missed_heartbeats set, heartbeat is enabled on ESP
+ *
+ * @param cfg Policy, copied
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int link_recovery_start(const link_recovery_config_t *cfg);
+
+/**
+ * @brief Tell policy that control path is (re)initialized and usable
+ */
+void link_recovery_rpc_ready(void);
+
+/**
+ * @brief Tell policy that control path is down, e.g. before deinit
+ */
+void link_recovery_rpc_down(void);
+
+/**
+ * @brief Stop recovery policy
+ */
+void link_recovery_stop(void);
+
+#endif
@@ -10,6 +10,15 @@
 #include <sys/ioctl.h>
 #include <errno.h>
 #include <stdlib.h>
+#include <poll.h>
+#include <time.h>
+#include <linux/if_packet.h>
+#include <linux/if_ether.h>
+#include <limits.h>
+#include <stdbool.h>
+#include <linux/netlink.h>
+#include <linux/rtnetlink.h>
+#include <linux/neighbour.h>
 
 
 #include "nw_helper_func.h"
@@ -17,6 +26,12 @@
 #define ENABLE_DEBUG_LOGS 0
 #define ALLOW_ROUTE_UPDATE 1
 
+#define RESOLV_CONF "/etc/resolv.conf"
+/* resolvconf record name, <iface>.<program> */
+#define DNS_RESOLVCONF_RECORD STA_INTERFACE ".esp_hosted"
+/* Neighbor states with MAC known, same entries /proc/net/arp flags ATF_COM */
+#define NUD_RESOLVED (NUD_PERMANENT | NUD_REACHABLE | NUD_STALE | NUD_DELAY | NUD_PROBE)
+
 #if ENABLE_DEBUG_LOGS
   #define DEBUG_LOG_VERBOSE(fmt, ...) printf(fmt, ##__VA_ARGS__)
 #else
@@ -606,6 +621,512 @@ int remove_dns(const char *dns)
 }
 
 
+/* systemd-resolved owns resolv.conf when it links to its stub under /run */
+static int dns_managed_by_resolved(void)
+{
+	char target[PATH_MAX];
+	ssize_t len = readlink(RESOLV_CONF, target, sizeof(target) - 1);
+
+	if (len < 0)
+		return 0;
+	target[len] = '\0';
+
+	if (!strstr(target, "systemd/resolve"))
+		return 0;
+
+	return system("command -v resolvectl >/dev/null 2>&1") == 0;
+}
+
+/* Function reads DNS servers of STA_INTERFACE from systemd-resolved when it
+ * owns resolv.conf, else nameserver entries of resolv.conf */
+int get_dns_servers(char servers[][DNS_ADDR_LENGTH], int max_servers, int *count)
+{
+	FILE *resolv = NULL;
+	char line[256];
+	char addr[256];
+	char *pos = NULL, *token = NULL, *saveptr = NULL;
+
+	if (!servers || !count || max_servers <= 0) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	*count = 0;
+
+	if (dns_managed_by_resolved()) {
+		resolv = popen("resolvectl dns " STA_INTERFACE " 2>/dev/null", "r");
+		if (!resolv) {
+			perror("run resolvectl:");
+			return FAILURE;
+		}
+		/* Single line "Link <n> (<iface>): <server> <server>..." */
+		if (fgets(line, sizeof(line), resolv) && (pos = strstr(line, "):"))) {
+			for (token = strtok_r(pos + 2, " \n", &saveptr); token && *count < max_servers;
+					token = strtok_r(NULL, " \n", &saveptr)) {
+				strncpy(servers[*count], token, DNS_ADDR_LENGTH - 1);
+				servers[*count][DNS_ADDR_LENGTH - 1] = '\0';
+				(*count)++;
+			}
+		}
+		pclose(resolv);
+		return SUCCESS;
+	}
+
+	resolv = fopen(RESOLV_CONF, "r");
+	if (!resolv) {
+		perror("open resolv.conf:");
+		return FAILURE;
+	}
+
+	while (fgets(line, sizeof(line), resolv) && *count < max_servers) {
+		if (sscanf(line, "nameserver %255s", addr) == 1) {
+			strncpy(servers[*count], addr, DNS_ADDR_LENGTH - 1);
+			servers[*count][DNS_ADDR_LENGTH - 1] = '\0';
+			(*count)++;
+		}
+	}
+
+	fclose(resolv);
+	return SUCCESS;
+}
+
+/* Copies resolved IPv4 entry of RTM_NEWNEIGH msg into n.
+ * Returns false if msg is for another interface, unresolved or incomplete */
+static bool parse_neighbor(struct nlmsghdr *nlh, unsigned int ifindex, long hz, neighbor_info_t *n)
+{
+	struct ndmsg *ndm = NLMSG_DATA(nlh);
+	struct rtattr *rta = NULL;
+	int rta_len = 0;
+	bool has_ip = false, has_mac = false;
+	uint8_t *mac = NULL;
+
+	if (nlh->nlmsg_type != RTM_NEWNEIGH || ndm->ndm_family != AF_INET)
+		return false;
+	if (ifindex && ndm->ndm_ifindex != (int)ifindex)
+		return false;
+	if (!(ndm->ndm_state & NUD_RESOLVED))
+		return false;
+
+	memset(n, 0, sizeof(*n));
+	n->age_sec = -1;
+	rta = RTM_RTA(ndm);
+	rta_len = RTM_PAYLOAD(nlh);
+	for (; RTA_OK(rta, rta_len); rta = RTA_NEXT(rta, rta_len)) {
+		if (rta->rta_type == NDA_DST && RTA_PAYLOAD(rta) == sizeof(struct in_addr)) {
+			has_ip = !!inet_ntop(AF_INET, RTA_DATA(rta), n->ip_addr, sizeof(n->ip_addr));
+		} else if (rta->rta_type == NDA_LLADDR && RTA_PAYLOAD(rta) == ETH_ALEN) {
+			mac = RTA_DATA(rta);
+			snprintf(n->mac_addr, sizeof(n->mac_addr), "%02x:%02x:%02x:%02x:%02x:%02x",
+					mac[0], mac[1], mac[2], mac[3], mac[4], mac[5]);
+			has_mac = true;
+		} else if (rta->rta_type == NDA_CACHEINFO && RTA_PAYLOAD(rta) >= sizeof(struct nda_cacheinfo)) {
+			/* Clock ticks since neighbor last confirmed reachable */
+			n->age_sec = ((struct nda_cacheinfo *)RTA_DATA(rta))->ndm_confirmed / hz;
+		}
+	}
+
+	if (!has_ip || !has_mac)
+		return false;
+
+	if (!if_indextoname(ndm->ndm_ifindex, n->iface))
+		n->iface[0] = '\0';
+	n->is_permanent = !!(ndm->ndm_state & NUD_PERMANENT);
+	return true;
+}
+
+/* Function reads resolved IPv4 entries of kernel neighbor table over
+ * rtnetlink, learnt on iface, or on any interface if iface is NULL */
+int get_neighbors(const char *iface, neighbor_info_t *neighbors, int max_neighbors, int *count)
+{
+	struct {
+		struct nlmsghdr nlh;
+		struct ndmsg ndm;
+	} req = {0};
+	char buf[8192];
+	struct nlmsghdr *nlh = NULL;
+	unsigned int ifindex = 0;
+	long hz = sysconf(_SC_CLK_TCK);
+	int sock = -1, len = 0;
+	int ret = SUCCESS;
+	bool done = false;
+
+	if (!neighbors || !count || max_neighbors <= 0) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	*count = 0;
+
+	if (iface) {
+		ifindex = if_nametoindex(iface);
+		/* No interface, no neighbors */
+		if (!ifindex)
+			return SUCCESS;
+	}
+	if (hz <= 0)
+		hz = 100;
+
+	if (create_socket(AF_NETLINK, SOCK_RAW | SOCK_CLOEXEC, NETLINK_ROUTE, &sock) != SUCCESS)
+		return FAILURE;
+
+	req.nlh.nlmsg_len = sizeof(req);
+	req.nlh.nlmsg_type = RTM_GETNEIGH;
+	req.nlh.nlmsg_flags = NLM_F_REQUEST | NLM_F_DUMP;
+	req.nlh.nlmsg_seq = 1;
+	req.ndm.ndm_family = AF_INET;
+	req.ndm.ndm_ifindex = ifindex;
+
+	if (send(sock, &req, sizeof(req), 0) < 0) {
+		perror("send RTM_GETNEIGH:");
+		close_socket(sock);
+		return FAILURE;
+	}
+
+	while (!done && *count < max_neighbors) {
+		len = recv(sock, buf, sizeof(buf), 0);
+		if (len < 0) {
+			perror("recv RTM_GETNEIGH:");
+			ret = FAILURE;
+			break;
+		}
+
+		for (nlh = (struct nlmsghdr *)buf; NLMSG_OK(nlh, len); nlh = NLMSG_NEXT(nlh, len)) {
+			if (nlh->nlmsg_type == NLMSG_DONE) {
+				done = true;
+				break;
+			}
+			if (nlh->nlmsg_type == NLMSG_ERROR) {
+				printf("Neighbor table dump failed: %d\n",
+						((struct nlmsgerr *)NLMSG_DATA(nlh))->error);
+				ret = FAILURE;
+				done = true;
+				break;
+			}
+			if (*count < max_neighbors &&
+			    parse_neighbor(nlh, ifindex, hz, &neighbors[*count]))
+				(*count)++;
+		}
+	}
+
+	close_socket(sock);
+	return ret;
+}
+
+int get_ipv4_addr(const char *iface, char *ip, size_t ip_size)
+{
+	struct ifreq ifr = {0};
+	int sock = -1;
+	int ret = FAILURE;
+
+	if (!iface || !ip || ip_size < INET_ADDRSTRLEN) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	ip[0] = '\0';
+	sock = socket(AF_INET, SOCK_DGRAM, 0);
+	if (sock < 0)
+		return FAILURE;
+
+	strncpy(ifr.ifr_name, iface, IFNAMSIZ - 1);
+	if (ioctl(sock, SIOCGIFADDR, &ifr) == 0 &&
+	    inet_ntop(AF_INET, &((struct sockaddr_in *)&ifr.ifr_addr)->sin_addr, ip, ip_size))
+		ret = SUCCESS;
+	close(sock);
+
+	return ret;
+}
+
+/* ARP packet for Ethernet and IPv4 */
+struct arp_ipv4_pkt {
+	uint16_t htype;
+	uint16_t ptype;
+	uint8_t hlen;
+	uint8_t plen;
+	uint16_t oper;
+	uint8_t sha[ETH_ALEN];
+	uint8_t spa[4];
+	uint8_t tha[ETH_ALEN];
+	uint8_t tpa[4];
+} __attribute__((packed));
+
+/* Sends RFC 5227 ARP probe for ip (sender IP 0.0.0.0, neighbors' caches untouched)
+ * and waits for ARP from another MAC claiming ip, returned in reply_mac, rtt in rtt_ms if not NULL */
+static int send_arp_probe(const char *iface, const char *ip, int timeout_ms,
+		char *reply_mac, int *rtt_ms)
+{
+	struct ifreq ifr = {0};
+	struct sockaddr_ll addr = {0};
+	struct arp_ipv4_pkt probe = {0};
+	struct arp_ipv4_pkt rx = {0};
+	struct sockaddr_ll from = {0};
+	socklen_t from_len = sizeof(from);
+	struct pollfd pfd = {0};
+	struct in_addr target = {0};
+	struct timespec start = {0}, now = {0};
+	int sock = -1;
+	int elapsed_ms = 0;
+	int ret = FAILURE;
+
+	if (!iface || !ip || !reply_mac || timeout_ms <= 0 ||
+	    inet_pton(AF_INET, ip, &target) != 1) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+	reply_mac[0] = '\0';
+
+	sock = socket(AF_PACKET, SOCK_DGRAM, htons(ETH_P_ARP));
+	if (sock < 0) {
+		perror("ARP probe socket:");
+		return FAILURE;
+	}
+
+	strncpy(ifr.ifr_name, iface, IFNAMSIZ - 1);
+	if (ioctl(sock, SIOCGIFINDEX, &ifr) < 0) {
+		perror("SIOCGIFINDEX:");
+		goto close_sock;
+	}
+	addr.sll_ifindex = ifr.ifr_ifindex;
+	if (ioctl(sock, SIOCGIFHWADDR, &ifr) < 0) {
+		perror("SIOCGIFHWADDR:");
+		goto close_sock;
+	}
+
+	addr.sll_family = AF_PACKET;
+	addr.sll_protocol = htons(ETH_P_ARP);
+	addr.sll_halen = ETH_ALEN;
+	memset(addr.sll_addr, 0xff, ETH_ALEN);
+	if (bind(sock, (struct sockaddr *)&addr, sizeof(addr)) < 0) {
+		perror("ARP probe bind:");
+		goto close_sock;
+	}
+
+	probe.htype = htons(ARPHRD_ETHER);
+	probe.ptype = htons(ETH_P_IP);
+	probe.hlen = ETH_ALEN;
+	probe.plen = 4;
+	probe.oper = htons(ARPOP_REQUEST);
+	memcpy(probe.sha, ifr.ifr_hwaddr.sa_data, ETH_ALEN);
+	memcpy(probe.tpa, &target, 4);
+
+	if (sendto(sock, &probe, sizeof(probe), 0, (struct sockaddr *)&addr, sizeof(addr)) < 0) {
+		perror("ARP probe send:");
+		goto close_sock;
+	}
+
+	ret = SUCCESS;
+	pfd.fd = sock;
+	pfd.events = POLLIN;
+	clock_gettime(CLOCK_MONOTONIC, &start);
+	while (elapsed_ms < timeout_ms) {
+		if (poll(&pfd, 1, timeout_ms - elapsed_ms) <= 0)
+			break;
+
+		from_len = sizeof(from);
+		if (recvfrom(sock, &rx, sizeof(rx), 0, (struct sockaddr *)&from, &from_len) == sizeof(rx) &&
+		    from.sll_pkttype != PACKET_OUTGOING &&
+		    memcmp(rx.sha, probe.sha, ETH_ALEN) &&
+		    !memcmp(rx.spa, &target, 4)) {
+			snprintf(reply_mac, MAC_ADDR_LENGTH, "%02x:%02x:%02x:%02x:%02x:%02x",
+					rx.sha[0], rx.sha[1], rx.sha[2], rx.sha[3], rx.sha[4], rx.sha[5]);
+			clock_gettime(CLOCK_MONOTONIC, &now);
+			if (rtt_ms)
+				*rtt_ms = (now.tv_sec - start.tv_sec) * 1000 +
+					(now.tv_nsec - start.tv_nsec) / 1000000;
+			break;
+		}
+
+		clock_gettime(CLOCK_MONOTONIC, &now);
+		elapsed_ms = (now.tv_sec - start.tv_sec) * 1000 +
+			(now.tv_nsec - start.tv_nsec) / 1000000;
+	}
+
+close_sock:
+	close(sock);
+	return ret;
+}
+
+int probe_ip_conflict(const char *iface, const char *ip, int timeout_ms, char *conflict_mac)
+{
+	return send_arp_probe(iface, ip, timeout_ms, conflict_mac, NULL);
+}
+
+int arp_ping(const char *iface, const char *ip, int timeout_ms, int *rtt_ms)
+{
+	char mac[MAC_ADDR_LENGTH] = {0};
+
+	if (!rtt_ms) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+	*rtt_ms = -1;
+	return send_arp_probe(iface, ip, timeout_ms, mac, rtt_ms);
+}
+
+int get_default_gateway(const char *iface, char *gateway, size_t gateway_size)
+{
+	FILE *route = NULL;
+	char line[256];
+	char dev[IFNAMSIZ] = {0};
+	unsigned int dest = 0, gw = 0, flags = 0;
+	struct in_addr addr = {0};
+	int ret = FAILURE;
+
+	if (!iface || !gateway || !gateway_size) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+	gateway[0] = '\0';
+
+	route = fopen("/proc/net/route", "r");
+	if (!route) {
+		perror("open /proc/net/route:");
+		return FAILURE;
+	}
+
+	/* Columns: Iface Destination Gateway Flags ..., addresses in hex */
+	while (fgets(line, sizeof(line), route)) {
+		if (sscanf(line, "%15s %x %x %x", dev, &dest, &gw, &flags) != 4)
+			continue;
+		if (strcmp(dev, iface) || dest || !(flags & RTF_GATEWAY))
+			continue;
+		addr.s_addr = gw;
+		if (inet_ntop(AF_INET, &addr, gateway, gateway_size))
+			ret = SUCCESS;
+		break;
+	}
+
+	fclose(route);
+	return ret;
+}
+
+static int set_dns_resolved(const char *servers[], int count)
+{
+	char cmd[64 + MAX_DNS_SERVERS * DNS_ADDR_LENGTH];
+	size_t len = 0;
+	int i = 0;
+
+	len = snprintf(cmd, sizeof(cmd), "resolvectl dns " STA_INTERFACE);
+	for (i = 0; i < count; i++)
+		len += snprintf(cmd + len, sizeof(cmd) - len, " %s", servers[i]);
+	/* Empty server list clears the link's servers */
+	if (!count)
+		snprintf(cmd + len, sizeof(cmd) - len, " ''");
+
+	if (system(cmd)) {
+		printf("Failed to run: %s\n", cmd);
+		return FAILURE;
+	}
+	return SUCCESS;
+}
+
+static int set_dns_resolvconf(const char *servers[], int count)
+{
+	FILE *p = NULL;
+	int i = 0;
+
+	if (!count) {
+		if (system("resolvconf -d " DNS_RESOLVCONF_RECORD " 2>/dev/null")) {
+			printf("Failed to remove resolvconf record " DNS_RESOLVCONF_RECORD "\n");
+			return FAILURE;
+		}
+		return SUCCESS;
+	}
+
+	p = popen("resolvconf -a " DNS_RESOLVCONF_RECORD, "w");
+	if (!p) {
+		perror("run resolvconf:");
+		return FAILURE;
+	}
+	for (i = 0; i < count; i++)
+		fprintf(p, "nameserver %s\n", servers[i]);
+
+	if (pclose(p)) {
+		printf("Failed to add resolvconf record " DNS_RESOLVCONF_RECORD "\n");
+		return FAILURE;
+	}
+	return SUCCESS;
+}
+
+/* Rewritten in place, so a symlinked resolv.conf stays a symlink */
+static int set_dns_resolv_conf(const char *servers[], int count)
+{
+	FILE *resolv = NULL, *kept = NULL;
+	char line[256];
+	int i = 0;
+
+	kept = tmpfile();
+	if (!kept) {
+		perror("tmpfile:");
+		return FAILURE;
+	}
+
+	/* Keep all lines except existing nameserver entries */
+	resolv = fopen(RESOLV_CONF, "r");
+	if (resolv) {
+		while (fgets(line, sizeof(line), resolv)) {
+			if (strncmp(line, "nameserver", strlen("nameserver")) != 0)
+				fputs(line, kept);
+		}
+		fclose(resolv);
+	}
+
+	resolv = fopen(RESOLV_CONF, "w");
+	if (!resolv) {
+		perror("open resolv.conf:");
+		fclose(kept);
+		return FAILURE;
+	}
+
+	rewind(kept);
+	while (fgets(line, sizeof(line), kept))
+		fputs(line, resolv);
+	fclose(kept);
+
+	for (i = 0; i < count; i++)
+		fprintf(resolv, "nameserver %s\n", servers[i]);
+
+	if (fclose(resolv)) {
+		perror("write resolv.conf:");
+		return FAILURE;
+	}
+	return SUCCESS;
+}
+
+/* Function replaces DNS servers (IPv4 or IPv6) used by host. Zero count clears them
+ * - systemd-resolved: set per link on STA_INTERFACE with resolvectl
+ * - resolvconf installed: added as its own record, merged into resolv.conf
+ * - otherwise nameserver lines of resolv.conf are rewritten, other lines kept.
+ *   A DHCP client or NetworkManager may overwrite these on lease renew */
+int set_dns_servers(const char *servers[], int count)
+{
+	struct in6_addr addr;
+	int i = 0;
+
+	if ((count && !servers) || count < 0 || count > MAX_DNS_SERVERS) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+
+	/* Also guards the servers passed on to resolvectl command line */
+	for (i = 0; i < count; i++) {
+		if (!servers[i] || (inet_pton(AF_INET, servers[i], &addr) != 1 &&
+				inet_pton(AF_INET6, servers[i], &addr) != 1)) {
+			printf("Invalid DNS server address: %s\n", servers[i] ? servers[i] : "(null)");
+			return FAILURE;
+		}
+	}
+
+	if (dns_managed_by_resolved())
+		return set_dns_resolved(servers, count);
+
+	if (system("command -v resolvconf >/dev/null 2>&1") == 0)
+		return set_dns_resolvconf(servers, count);
+
+	return set_dns_resolv_conf(servers, count);
+}
+
+
  /* Function downs in given interface */
 int interface_down(int sockfd, const char* iface)
 {
@@ -3,9 +3,15 @@
 #ifndef NW_HELPER_FUNC_H
 #define NW_HELPER_FUNC_H
 
+#include <arpa/inet.h>
+#include <net/if.h>
+
 #define STA_INTERFACE     "ethsta0"
 #define AP_INTERFACE      "ethap0"
 #define MAC_ADDR_LENGTH   18
+#define MAX_DNS_SERVERS   3
+#define DNS_ADDR_LENGTH   INET6_ADDRSTRLEN
+#define MAX_NEIGHBORS     64
 
 #define SUCCESS                      0
 #define FAILURE                      -1
@@ -23,6 +29,16 @@ typedef struct {
 	uint8_t network_up;
 } network_info_t;
 
+/* IPv4 neighbor (ARP) entry learnt on ESP interfaces */
+typedef struct {
+	char ip_addr[INET_ADDRSTRLEN];
+	char mac_addr[MAC_ADDR_LENGTH];
+	char iface[IF_NAMESIZE];
+	uint8_t is_permanent;
+	/* Seconds since neighbor was last confirmed reachable, -1 if unknown */
+	int age_sec;
+} neighbor_info_t;
+
 int down_sta_netdev(const network_info_t *info);
 int up_sta_netdev(const network_info_t *info);
 int up_sta_netdev__with_static_ip_dns_route(const network_info_t *info);
@@ -34,6 +50,17 @@ int remove_default_gateway(const char *gateway);
 int add_default_gateway(const char *gateway);
 int remove_dns(const char *dns);
 int add_dns(const char *dns);
+/* servers are IPv4 or IPv6, see set_dns_servers() for where they are read and written */
+int get_dns_servers(char servers[][DNS_ADDR_LENGTH], int max_servers, int *count);
+int set_dns_servers(const char *servers[], int count);
+int get_neighbors(const char *iface, neighbor_info_t *neighbors, int max_neighbors, int *count);
+int get_ipv4_addr(const char *iface, char *ip, size_t ip_size);
+/* conflict_mac (MAC_ADDR_LENGTH) is empty if no other host answered for ip within timeout_ms */
+int probe_ip_conflict(const char *iface, const char *ip, int timeout_ms, char *conflict_mac);
+/* ARP probes ip, rtt_ms is -1 if there was no reply within timeout_ms */
+int arp_ping(const char *iface, const char *ip, int timeout_ms, int *rtt_ms);
+/* gateway of default route through iface, FAILURE if there is none */
+int get_default_gateway(const char *iface, char *gateway, size_t gateway_size);
 int set_network_static_ip(int sockfd, const char* iface, const char* ip, const char* netmask, const char* gateway);
 int create_socket(int domain, int type, int protocol, int *sock);
 int close_socket(int sock);
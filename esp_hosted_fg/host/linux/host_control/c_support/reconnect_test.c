@@ -0,0 +1,242 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+
+#include "test.h"
+#include "webhook_notify.h"
+#include "reconnect_test.h"
+
+static wifi_window_t test_windows[WIFI_SCHEDULE_MAX_WINDOWS];
+static int num_test_windows;
+static char test_ssid[SSID_LENGTH];
+static char test_pwd[PASSWORD_LENGTH];
+/* Day of last run, as tm_year * 1000 + tm_yday */
+static int last_run_day = -1;
+static bool run_now;
+
+static reconnect_test_result_t results[RECONNECT_TEST_MAX_RESULTS];
+static int num_results;
+static int next_result;
+
+static pthread_t test_thread;
+static bool test_running;
+static bool rpc_ready;
+static pthread_mutex_t test_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t test_cond = PTHREAD_COND_INITIALIZER;
+
+/* Called with test_lock held */
+static void wait_sec(int sec)
+{
+	struct timespec deadline = {0};
+
+	clock_gettime(CLOCK_REALTIME, &deadline);
+	deadline.tv_sec += sec;
+	while (test_running && !run_now &&
+	       pthread_cond_timedwait(&test_cond, &test_lock, &deadline) != ETIMEDOUT)
+		;
+}
+
+static void add_result(const reconnect_test_result_t *r)
+{
+	char detail[128] = {0};
+
+	pthread_mutex_lock(&test_lock);
+	results[next_result] = *r;
+	next_result = (next_result + 1) % RECONNECT_TEST_MAX_RESULTS;
+	if (num_results < RECONNECT_TEST_MAX_RESULTS)
+		num_results++;
+	pthread_mutex_unlock(&test_lock);
+
+	if (r->status == SUCCESS && r->attempts == 1) {
+		printf("Reconnect test: passed, reconnected in %ums\n", r->duration_ms);
+		return;
+	}
+
+	if (r->status == SUCCESS)
+		snprintf(detail, sizeof(detail), "reconnected to '%s' only after %d attempts",
+				test_ssid, r->attempts);
+	else
+		snprintf(detail, sizeof(detail), "could not reconnect to '%s' in %d attempts",
+				test_ssid, r->attempts);
+	printf("Reconnect test: %s\n", detail);
+	webhook_notify(WEBHOOK_EVENT_RECONNECT_TEST, detail);
+}
+
+/* Returns false if skipped, as station is not connected */
+static bool run_test(bool forced)
+{
+	reconnect_test_result_t r = {0};
+	ctrl_link_status_t st = {0};
+	uint64_t start = 0;
+
+	if (get_ctrl_link_status(&st) != SUCCESS || !st.sta_connected) {
+		if (forced)
+			printf("Reconnect test: skipped, station not connected\n");
+		return false;
+	}
+
+	printf("Reconnect test: disconnecting from '%s'\n", test_ssid);
+	r.time = time(NULL);
+	r.status = FAILURE;
+	if (test_station_mode_disconnect() != SUCCESS)
+		printf("Reconnect test: disconnect failed, connecting anyway\n");
+
+	pthread_mutex_lock(&test_lock);
+	run_now = false;
+	wait_sec(RECONNECT_TEST_SETTLE_SEC);
+	pthread_mutex_unlock(&test_lock);
+
+	/* Attempts go on even if stopped meanwhile, not to leave station
+	 * disconnected, only the waits are cut short */
+	while (r.attempts < RECONNECT_TEST_ATTEMPTS) {
+		r.attempts++;
+		start = get_mono_ms();
+		r.status = test_station_mode_connect_with_params(test_ssid, test_pwd,
+				STATION_MODE_BSSID, STATION_MODE_IS_WPA3_SUPPORTED,
+				STATION_MODE_LISTEN_INTERVAL, STATION_BAND_MODE);
+		r.duration_ms = (uint32_t)(get_mono_ms() - start);
+		if (r.status == SUCCESS || r.attempts == RECONNECT_TEST_ATTEMPTS)
+			break;
+
+		printf("Reconnect test: attempt %d failed, retrying in %ds\n",
+				r.attempts, RECONNECT_TEST_RETRY_SEC);
+		pthread_mutex_lock(&test_lock);
+		wait_sec(RECONNECT_TEST_RETRY_SEC);
+		pthread_mutex_unlock(&test_lock);
+	}
+
+	add_result(&r);
+	return true;
+}
+
+static void *test_thread_handler(void *arg)
+{
+	struct tm tm_now = {0};
+	time_t now = 0;
+	bool forced = false;
+	bool due = false;
+	bool ran = false;
+	int today = 0;
+
+	pthread_mutex_lock(&test_lock);
+	while (test_running) {
+		now = time(NULL);
+		localtime_r(&now, &tm_now);
+		today = tm_now.tm_year * 1000 + tm_now.tm_yday;
+		forced = run_now;
+		/* Due test waits while control path is reinitialised */
+		due = rpc_ready && (forced || (today != last_run_day &&
+				wifi_window_contains(test_windows, num_test_windows, &tm_now)));
+		if (due) {
+			run_now = false;
+			pthread_mutex_unlock(&test_lock);
+
+			/* Skipped test is tried again on next check in window */
+			ran = run_test(forced);
+
+			pthread_mutex_lock(&test_lock);
+			if (ran)
+				last_run_day = today;
+		}
+
+		wait_sec(RECONNECT_TEST_CHECK_SEC);
+	}
+	pthread_mutex_unlock(&test_lock);
+
+	return NULL;
+}
+
+int reconnect_test_start(const wifi_window_t *windows, int num, const char *ssid, const char *pwd)
+{
+	if (!windows || num <= 0 || num > WIFI_SCHEDULE_MAX_WINDOWS || !ssid || !*ssid) {
+		printf("Invalid reconnect test config\n");
+		return FAILURE;
+	}
+
+	if (strlen(ssid) >= SSID_LENGTH || (pwd && strlen(pwd) >= PASSWORD_LENGTH)) {
+		printf("SSID or password too long\n");
+		return FAILURE;
+	}
+
+	reconnect_test_stop();
+
+	memcpy(test_windows, windows, num * sizeof(wifi_window_t));
+	num_test_windows = num;
+	memset(test_ssid, 0, sizeof(test_ssid));
+	memset(test_pwd, 0, sizeof(test_pwd));
+	strncpy(test_ssid, ssid, sizeof(test_ssid) - 1);
+	strncpy(test_pwd, pwd ? pwd : STATION_MODE_PWD, sizeof(test_pwd) - 1);
+	run_now = false;
+	test_running = true;
+
+	if (pthread_create(&test_thread, NULL, test_thread_handler, NULL) != 0) {
+		printf("Failed to create reconnect test thread\n");
+		test_running = false;
+		return FAILURE;
+	}
+
+	return SUCCESS;
+}
+
+void reconnect_test_run_now(void)
+{
+	pthread_mutex_lock(&test_lock);
+	run_now = true;
+	pthread_cond_signal(&test_cond);
+	pthread_mutex_unlock(&test_lock);
+}
+
+int reconnect_test_get_results(reconnect_test_result_t *out, int max)
+{
+	int num = 0;
+	int idx = 0;
+
+	if (!out || max <= 0)
+		return 0;
+
+	pthread_mutex_lock(&test_lock);
+	num = num_results < max ? num_results : max;
+	/* Oldest kept result is at next_result once buffer wrapped */
+	idx = (next_result - num + RECONNECT_TEST_MAX_RESULTS) % RECONNECT_TEST_MAX_RESULTS;
+	for (int i = 0; i < num; i++) {
+		out[i] = results[idx];
+		idx = (idx + 1) % RECONNECT_TEST_MAX_RESULTS;
+	}
+	pthread_mutex_unlock(&test_lock);
+
+	return num;
+}
+
+void reconnect_test_stop(void)
+{
+	pthread_mutex_lock(&test_lock);
+	if (!test_running) {
+		pthread_mutex_unlock(&test_lock);
+		return;
+	}
+	test_running = false;
+	pthread_cond_signal(&test_cond);
+	pthread_mutex_unlock(&test_lock);
+
+	pthread_join(test_thread, NULL);
+}
+
+void reconnect_test_rpc_ready(void)
+{
+	pthread_mutex_lock(&test_lock);
+	rpc_ready = true;
+	pthread_mutex_unlock(&test_lock);
+}
+
+void reconnect_test_rpc_down(void)
+{
+	pthread_mutex_lock(&test_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&test_lock);
+}
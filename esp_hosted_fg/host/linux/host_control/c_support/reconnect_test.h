@@ -0,0 +1,74 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef RECONNECT_TEST_H
+#define RECONNECT_TEST_H
+
+#include <stdint.h>
+#include <time.h>
+#include "wifi_schedule.h"
+
+#define RECONNECT_TEST_MAX_RESULTS       8
+#define RECONNECT_TEST_CHECK_SEC         60
+/* Wait after disconnect, before connecting again */
+#define RECONNECT_TEST_SETTLE_SEC        2
+/* Connect attempts before test is failed, so unit does not stay offline
+ * because of a transient failure */
+#define RECONNECT_TEST_ATTEMPTS          3
+#define RECONNECT_TEST_RETRY_SEC         30
+
+typedef struct {
+	time_t time;
+	/* Connect attempts made, 1 if first one succeeded */
+	int attempts;
+	/* SUCCESS, or FAILURE if no attempt succeeded */
+	int status;
+	/* Time taken by successful attempt, or by last one */
+	uint32_t duration_ms;
+} reconnect_test_result_t;
+
+/**
+ * @brief Start scheduler disconnecting and reconnecting station once a
+ * day, in first check falling into any of windows
+ *
+ * Test is skipped while station is not connected. Each connect attempt is
+ * also recorded in connection history. Tests needing more than one attempt
+ * or failing are printed and sent to webhook as "reconnect_test"
+ *
+ * @param windows Daily maintenance windows, local time
+ * @param num Number of windows
+ * @param ssid AP to reconnect to
+ * @param pwd Password of AP, NULL for ctrl_config.h default
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int reconnect_test_start(const wifi_window_t *windows, int num, const char *ssid, const char *pwd);
+
+/**
+ * @brief Run test on next check, even outside windows or if already run today
+ */
+void reconnect_test_run_now(void);
+
+/**
+ * @brief Copy results, oldest first
+ *
+ * @return Number of results copied
+ */
+int reconnect_test_get_results(reconnect_test_result_t *results, int max);
+
+/**
+ * @brief Stop scheduler. Results are kept
+ */
+void reconnect_test_stop(void);
+
+/**
+ * @brief Tell scheduler that control path is usable, tests resume
+ */
+void reconnect_test_rpc_ready(void);
+
+/**
+ * @brief Tell scheduler that control path is down. Due test, or one
+ * requested by reconnect_test_run_now(), runs once control path is back
+ */
+void reconnect_test_rpc_down(void);
+
+#endif
@@ -0,0 +1,151 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <stdbool.h>
+#include <string.h>
+#include <strings.h>
+#include <stdlib.h>
+#include <ctype.h>
+#include <pthread.h>
+
+#include "test.h"
+#include "redact.h"
+
+/* Values following these words, as whole words, are masked */
+static const char *secret_words[] = {
+	"password", "passphrase", "passwd", "pwd", "psk", "secret", "token", NULL
+};
+
+#define SECRET_SEPARATORS   " \t=:\"'"
+#define VALUE_TERMINATORS   " \t\"',;\r\n"
+
+static char secrets[REDACT_MAX_SECRETS][PASSWORD_LENGTH];
+static int next_secret;
+static pthread_mutex_t redact_lock = PTHREAD_MUTEX_INITIALIZER;
+
+void redact_add_secret(const char *value)
+{
+	if (!value || strlen(value) < REDACT_MIN_SECRET_LEN)
+		return;
+
+	pthread_mutex_lock(&redact_lock);
+	for (int i = 0; i < REDACT_MAX_SECRETS; i++) {
+		if (!strcmp(secrets[i], value)) {
+			pthread_mutex_unlock(&redact_lock);
+			return;
+		}
+	}
+	strncpy(secrets[next_secret], value, PASSWORD_LENGTH - 1);
+	next_secret = (next_secret + 1) % REDACT_MAX_SECRETS;
+	pthread_mutex_unlock(&redact_lock);
+}
+
+bool redact_is_secret_option(const char *option)
+{
+	if (!option || strncmp(option, "--", 2))
+		return false;
+
+	for (const char *p = option + 2; *p; p++) {
+		for (int k = 0; secret_words[k]; k++) {
+			if (!strncasecmp(p, secret_words[k], strlen(secret_words[k])))
+				return true;
+		}
+	}
+	return false;
+}
+
+void redact_known_secrets(char *dst, size_t dst_size, const char *text)
+{
+	size_t i = 0, j = 0;
+
+	if (!dst || !dst_size)
+		return;
+
+	pthread_mutex_lock(&redact_lock);
+	while (text[i] && j + 1 < dst_size) {
+		size_t len = 0;
+
+		for (int k = 0; k < REDACT_MAX_SECRETS; k++) {
+			size_t n = strlen(secrets[k]);
+
+			if (n && !strncmp(text + i, secrets[k], n) && n > len)
+				len = n;
+		}
+		if (!len) {
+			dst[j++] = text[i++];
+			continue;
+		}
+		i += len;
+		j += snprintf(dst + j, dst_size - j, "%s", REDACT_MASK);
+		if (j >= dst_size)
+			j = dst_size - 1;
+	}
+	pthread_mutex_unlock(&redact_lock);
+	dst[j] = '\0';
+}
+
+static bool is_word_char(char c)
+{
+	return isalnum((unsigned char)c) || c == '_';
+}
+
+static size_t secret_word_at(const char *line, size_t i)
+{
+	if (i > 0 && is_word_char(line[i - 1]))
+		return 0;
+
+	for (int k = 0; secret_words[k]; k++) {
+		size_t len = strlen(secret_words[k]);
+
+		if (!strncasecmp(line + i, secret_words[k], len) &&
+		    !is_word_char(line[i + len]))
+			return len;
+	}
+	return 0;
+}
+
+static void redact_secret_words(char *dst, size_t dst_size, const char *line)
+{
+	size_t i = 0, j = 0;
+
+	while (line[i] && j + 1 < dst_size) {
+		size_t len = secret_word_at(line, i);
+		size_t k = 0;
+
+		if (!len) {
+			dst[j++] = line[i++];
+			continue;
+		}
+
+		/* Keep the word and separators, mask the value after them */
+		k = i + len;
+		k += strspn(line + k, SECRET_SEPARATORS);
+		while (i < k && j + 1 < dst_size)
+			dst[j++] = line[i++];
+		if (line[i] && !strchr(VALUE_TERMINATORS, line[i])) {
+			i += strcspn(line + i, VALUE_TERMINATORS);
+			j += snprintf(dst + j, dst_size - j, "%s", REDACT_MASK);
+			if (j >= dst_size)
+				j = dst_size - 1;
+		}
+	}
+	dst[j] = '\0';
+}
+
+void redact_line(char *dst, size_t dst_size, const char *line)
+{
+	char *known = NULL;
+
+	if (!dst || !dst_size)
+		return;
+
+	known = malloc(dst_size);
+	if (!known) {
+		/* Better lose the line than leak it */
+		snprintf(dst, dst_size, "%s\n", REDACT_MASK);
+		return;
+	}
+	redact_known_secrets(known, dst_size, line);
+	redact_secret_words(dst, dst_size, known);
+	free(known);
+}
@@ -0,0 +1,44 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef REDACT_H
+#define REDACT_H
+
+#include <stdbool.h>
+#include <stddef.h>
+
+#define REDACT_MASK                   "***"
+#define REDACT_MAX_SECRETS            8
+/* Shorter values are too likely to match unrelated text */
+#define REDACT_MIN_SECRET_LEN         4
+
+/**
+ * @brief Remember a secret, e.g. password given to connect_ap, so it is
+ * masked wherever it shows up verbatim, with or without a keyword before
+ * it. Up to REDACT_MAX_SECRETS are kept, oldest dropped first
+ *
+ * @param value Secret, NULL or shorter than REDACT_MIN_SECRET_LEN is ignored
+ */
+void redact_add_secret(const char *value);
+
+/**
+ * @brief Tell if command line option takes a secret value, i.e. it
+ * starts with "--" and has a word like "password", "psk" or "secret"
+ */
+bool redact_is_secret_option(const char *option);
+
+/**
+ * @brief Copy text to dst, masking secrets remembered by
+ * redact_add_secret() with REDACT_MASK
+ */
+void redact_known_secrets(char *dst, size_t dst_size, const char *text);
+
+/**
+ * @brief Copy line to dst, masking remembered secrets and also value
+ * following words like "password", "pwd", "psk" or "secret"
+ *
+ * Meant for free form text, e.g. logs and command output. Masking may
+ * hide more than the secret itself
+ */
+void redact_line(char *dst, size_t dst_size, const char *line);
+
+#endif
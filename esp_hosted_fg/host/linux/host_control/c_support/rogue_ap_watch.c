@@ -0,0 +1,225 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <strings.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+
+#include "test.h"
+#include "rogue_ap_watch.h"
+
+#define MAX_TRACKED_APS      64
+/* "xx:xx:xx" part of BSSID string */
+#define OUI_STR_LEN          8
+
+typedef struct {
+	char bssid[BSSID_STR_SIZE];
+	int channel;
+	int encryption_mode;
+	bool trusted;
+} tracked_ap_t;
+
+static tracked_ap_t tracked_aps[MAX_TRACKED_APS];
+static int num_tracked_aps;
+static bool baseline_done;
+
+static char watch_ssid[SSID_LENGTH];
+static int watch_interval_sec;
+static rogue_ap_event_cb_t watch_cb;
+
+static pthread_t watch_thread;
+static bool watch_running;
+static bool rpc_ready;
+static pthread_mutex_t watch_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t watch_cond = PTHREAD_COND_INITIALIZER;
+
+static tracked_ap_t *find_tracked(const char *bssid)
+{
+	for (int i = 0; i < num_tracked_aps; i++) {
+		if (!strcasecmp(tracked_aps[i].bssid, bssid))
+			return &tracked_aps[i];
+	}
+	return NULL;
+}
+
+/* Returns bitmap of fingerprint fields not matching any trusted AP */
+static int get_mismatch_reasons(const wifi_scanlist_t *ap)
+{
+	bool auth_seen = false, oui_seen = false, channel_seen = false;
+
+	for (int i = 0; i < num_tracked_aps; i++) {
+		const tracked_ap_t *t = &tracked_aps[i];
+
+		if (!t->trusted)
+			continue;
+		if (t->encryption_mode == ap->encryption_mode)
+			auth_seen = true;
+		if (!strncasecmp(t->bssid, (const char *)ap->bssid, OUI_STR_LEN))
+			oui_seen = true;
+		if (t->channel == ap->channel)
+			channel_seen = true;
+	}
+
+	return (auth_seen ? 0 : ROGUE_AP_REASON_AUTH_MODE) |
+		(oui_seen ? 0 : ROGUE_AP_REASON_OUI) |
+		(channel_seen ? 0 : ROGUE_AP_REASON_CHANNEL);
+}
+
+static void report(const wifi_scanlist_t *ap, int reasons)
+{
+	rogue_ap_event_t event = {0};
+
+	strncpy(event.ssid, (const char *)ap->ssid, sizeof(event.ssid) - 1);
+	strncpy(event.bssid, (const char *)ap->bssid, sizeof(event.bssid) - 1);
+	event.rssi = ap->rssi;
+	event.channel = ap->channel;
+	event.encryption_mode = ap->encryption_mode;
+	event.reasons = reasons;
+
+	if (watch_cb)
+		watch_cb(&event);
+}
+
+static void process_scan(const wifi_scanlist_t *list, int count)
+{
+	for (int i = 0; i < count; i++) {
+		const wifi_scanlist_t *ap = &list[i];
+		tracked_ap_t *t = NULL;
+		int reasons = 0;
+
+		if (strncmp((const char *)ap->ssid, watch_ssid, SSID_LENGTH))
+			continue;
+
+		t = find_tracked((const char *)ap->bssid);
+		if (t) {
+			/* Known BSSID suddenly advertising different security */
+			if (t->trusted && t->encryption_mode != ap->encryption_mode) {
+				report(ap, ROGUE_AP_REASON_AUTH_MODE);
+				t->encryption_mode = ap->encryption_mode;
+			}
+			continue;
+		}
+
+		if (baseline_done)
+			reasons = get_mismatch_reasons(ap);
+
+		if (num_tracked_aps >= MAX_TRACKED_APS) {
+			printf("rogue AP watch: tracked AP table full\n");
+		} else {
+			t = &tracked_aps[num_tracked_aps++];
+			strncpy(t->bssid, (const char *)ap->bssid, sizeof(t->bssid) - 1);
+			t->channel = ap->channel;
+			t->encryption_mode = ap->encryption_mode;
+			/* Only APs matching fingerprint of trusted ones become trusted */
+			t->trusted = !reasons;
+		}
+
+		if (reasons)
+			report(ap, reasons);
+	}
+
+	if (!baseline_done) {
+		printf("rogue AP watch: %d trusted AP(s) for SSID '%s'\n",
+				num_tracked_aps, watch_ssid);
+		baseline_done = true;
+	}
+}
+
+static void *watch_thread_handler(void *arg)
+{
+	wifi_scanlist_t *list = NULL;
+	int count = 0;
+	struct timespec deadline = {0};
+
+	bool scan = false;
+
+	pthread_mutex_lock(&watch_lock);
+	while (watch_running) {
+		/* Paused while control path is reinitialised */
+		scan = rpc_ready;
+		pthread_mutex_unlock(&watch_lock);
+
+		if (scan) {
+			if (test_get_available_wifi_list(&list, &count) == SUCCESS)
+				process_scan(list, count);
+			else
+				printf("rogue AP watch: scan failed, retry in %d sec\n", watch_interval_sec);
+			free(list);
+			list = NULL;
+		}
+
+		pthread_mutex_lock(&watch_lock);
+		clock_gettime(CLOCK_REALTIME, &deadline);
+		deadline.tv_sec += watch_interval_sec;
+		while (watch_running &&
+		       pthread_cond_timedwait(&watch_cond, &watch_lock, &deadline) != ETIMEDOUT)
+			;
+	}
+	pthread_mutex_unlock(&watch_lock);
+
+	return NULL;
+}
+
+int rogue_ap_watch_start(const char *ssid, int interval_sec, rogue_ap_event_cb_t cb)
+{
+	if (!ssid || !*ssid || strlen(ssid) >= SSID_LENGTH) {
+		printf("Invalid SSID\n");
+		return FAILURE;
+	}
+
+	if (interval_sec < ROGUE_AP_WATCH_MIN_INTERVAL_SEC) {
+		printf("Interval must be at least %d seconds\n", ROGUE_AP_WATCH_MIN_INTERVAL_SEC);
+		return FAILURE;
+	}
+
+	rogue_ap_watch_stop();
+
+	memset(tracked_aps, 0, sizeof(tracked_aps));
+	num_tracked_aps = 0;
+	baseline_done = false;
+	memset(watch_ssid, 0, sizeof(watch_ssid));
+	strncpy(watch_ssid, ssid, sizeof(watch_ssid) - 1);
+	watch_interval_sec = interval_sec;
+	watch_cb = cb;
+	watch_running = true;
+
+	if (pthread_create(&watch_thread, NULL, watch_thread_handler, NULL) != 0) {
+		printf("Failed to create rogue AP watch thread\n");
+		watch_running = false;
+		return FAILURE;
+	}
+
+	return SUCCESS;
+}
+
+void rogue_ap_watch_stop(void)
+{
+	pthread_mutex_lock(&watch_lock);
+	if (!watch_running) {
+		pthread_mutex_unlock(&watch_lock);
+		return;
+	}
+	watch_running = false;
+	pthread_cond_signal(&watch_cond);
+	pthread_mutex_unlock(&watch_lock);
+
+	pthread_join(watch_thread, NULL);
+}
+
+void rogue_ap_watch_rpc_ready(void)
+{
+	pthread_mutex_lock(&watch_lock);
+	rpc_ready = true;
+	pthread_mutex_unlock(&watch_lock);
+}
+
+void rogue_ap_watch_rpc_down(void)
+{
+	pthread_mutex_lock(&watch_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&watch_lock);
+}
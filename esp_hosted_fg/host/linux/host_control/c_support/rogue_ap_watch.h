@@ -0,0 +1,57 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef ROGUE_AP_WATCH_H
+#define ROGUE_AP_WATCH_H
+
+#include "ctrl_api.h"
+
+#define ROGUE_AP_WATCH_MIN_INTERVAL_SEC  30
+
+/* Bitmap of fingerprint fields which differ from trusted APs */
+#define ROGUE_AP_REASON_AUTH_MODE        (1 << 0)
+#define ROGUE_AP_REASON_OUI              (1 << 1)
+#define ROGUE_AP_REASON_CHANNEL          (1 << 2)
+
+typedef struct {
+	char ssid[SSID_LENGTH];
+	char bssid[BSSID_STR_SIZE];
+	int rssi;
+	int channel;
+	int encryption_mode;
+	int reasons;
+} rogue_ap_event_t;
+
+typedef void (*rogue_ap_event_cb_t)(const rogue_ap_event_t *event);
+
+/**
+ * @brief Start background watcher for APs impersonating given SSID
+ *
+ * First scan learns BSSIDs currently broadcasting the SSID as trusted.
+ * Later scans flag new BSSIDs whose auth mode, OUI or channel was not
+ * seen among trusted ones, and trusted BSSIDs changing auth mode
+ *
+ * @param ssid SSID to watch
+ * @param interval_sec Seconds between scans, at least ROGUE_AP_WATCH_MIN_INTERVAL_SEC
+ * @param cb Called from watcher thread for every flagged AP
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int rogue_ap_watch_start(const char *ssid, int interval_sec, rogue_ap_event_cb_t cb);
+
+/**
+ * @brief Stop background watcher and forget trusted APs
+ */
+void rogue_ap_watch_stop(void);
+
+/**
+ * @brief Tell watcher that control path is usable, scans resume
+ */
+void rogue_ap_watch_rpc_ready(void);
+
+/**
+ * @brief Tell watcher that control path is down. Scans are skipped,
+ * trusted APs are kept
+ */
+void rogue_ap_watch_rpc_down(void);
+
+#endif
@@ -0,0 +1,165 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <strings.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+
+#include "test.h"
+#include "scan_cache.h"
+
+static scan_cache_entry_t cache[SCAN_CACHE_MAX_ENTRIES];
+static int num_cache_entries;
+static int cache_interval_sec;
+
+static pthread_t cache_thread;
+static bool cache_running;
+/* Protects cache and cache_running */
+static pthread_mutex_t cache_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t cache_cond = PTHREAD_COND_INITIALIZER;
+
+static scan_cache_entry_t *find_entry(const char *bssid)
+{
+	for (int i = 0; i < num_cache_entries; i++) {
+		if (!strcasecmp(cache[i].bssid, bssid))
+			return &cache[i];
+	}
+	return NULL;
+}
+
+static void expire_entries(time_t now)
+{
+	int i = 0;
+
+	while (i < num_cache_entries) {
+		if (now - cache[i].last_seen > SCAN_CACHE_EXPIRE_SEC) {
+			cache[i] = cache[--num_cache_entries];
+			continue;
+		}
+		i++;
+	}
+}
+
+/* Called with cache_lock held */
+static void update_cache(const wifi_scanlist_t *list, int count, time_t now)
+{
+	for (int i = 0; i < count; i++) {
+		const wifi_scanlist_t *ap = &list[i];
+		scan_cache_entry_t *e = find_entry((const char *)ap->bssid);
+
+		if (!e) {
+			if (num_cache_entries >= SCAN_CACHE_MAX_ENTRIES)
+				continue;
+			e = &cache[num_cache_entries++];
+			memset(e, 0, sizeof(*e));
+			strncpy(e->bssid, (const char *)ap->bssid, sizeof(e->bssid) - 1);
+			e->first_seen = now;
+			e->best_rssi = ap->rssi;
+		}
+
+		/* SSID, channel and auth mode may change, keep latest */
+		strncpy(e->ssid, (const char *)ap->ssid, sizeof(e->ssid) - 1);
+		e->channel = ap->channel;
+		e->encryption_mode = ap->encryption_mode;
+		e->last_rssi = ap->rssi;
+		if (ap->rssi > e->best_rssi)
+			e->best_rssi = ap->rssi;
+		e->last_seen = now;
+	}
+
+	expire_entries(now);
+}
+
+static void *cache_thread_handler(void *arg)
+{
+	wifi_scanlist_t *list = NULL;
+	int count = 0;
+	struct timespec deadline = {0};
+
+	pthread_mutex_lock(&cache_lock);
+	while (cache_running) {
+		pthread_mutex_unlock(&cache_lock);
+
+		if (test_get_available_wifi_list(&list, &count) != SUCCESS) {
+			printf("scan cache: scan failed, retry in %d sec\n", cache_interval_sec);
+			count = 0;
+		}
+
+		pthread_mutex_lock(&cache_lock);
+		if (count)
+			update_cache(list, count, time(NULL));
+		free(list);
+		list = NULL;
+
+		clock_gettime(CLOCK_REALTIME, &deadline);
+		deadline.tv_sec += cache_interval_sec;
+		while (cache_running &&
+		       pthread_cond_timedwait(&cache_cond, &cache_lock, &deadline) != ETIMEDOUT)
+			;
+	}
+	pthread_mutex_unlock(&cache_lock);
+
+	return NULL;
+}
+
+int scan_cache_start(int interval_sec)
+{
+	if (interval_sec < SCAN_CACHE_MIN_INTERVAL_SEC) {
+		printf("Interval must be at least %d seconds\n", SCAN_CACHE_MIN_INTERVAL_SEC);
+		return FAILURE;
+	}
+
+	scan_cache_stop();
+
+	cache_interval_sec = interval_sec;
+	cache_running = true;
+
+	if (pthread_create(&cache_thread, NULL, cache_thread_handler, NULL) != 0) {
+		printf("Failed to create scan cache thread\n");
+		cache_running = false;
+		return FAILURE;
+	}
+
+	return SUCCESS;
+}
+
+void scan_cache_stop(void)
+{
+	pthread_mutex_lock(&cache_lock);
+	if (!cache_running) {
+		pthread_mutex_unlock(&cache_lock);
+		return;
+	}
+	cache_running = false;
+	pthread_cond_signal(&cache_cond);
+	pthread_mutex_unlock(&cache_lock);
+
+	pthread_join(cache_thread, NULL);
+}
+
+static int cmp_best_rssi(const void *a, const void *b)
+{
+	return ((const scan_cache_entry_t *)b)->best_rssi -
+		((const scan_cache_entry_t *)a)->best_rssi;
+}
+
+int scan_cache_get(scan_cache_entry_t *entries, int max)
+{
+	int num = 0;
+
+	if (!entries || max <= 0)
+		return 0;
+
+	pthread_mutex_lock(&cache_lock);
+	expire_entries(time(NULL));
+	qsort(cache, num_cache_entries, sizeof(scan_cache_entry_t), cmp_best_rssi);
+	num = num_cache_entries < max ? num_cache_entries : max;
+	memcpy(entries, cache, num * sizeof(scan_cache_entry_t));
+	pthread_mutex_unlock(&cache_lock);
+
+	return num;
+}
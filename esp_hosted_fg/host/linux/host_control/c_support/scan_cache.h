@@ -0,0 +1,51 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef SCAN_CACHE_H
+#define SCAN_CACHE_H
+
+#include <time.h>
+#include "ctrl_api.h"
+
+#define SCAN_CACHE_MIN_INTERVAL_SEC      30
+#define SCAN_CACHE_MAX_ENTRIES           128
+/* Entries not seen for this long are dropped */
+#define SCAN_CACHE_EXPIRE_SEC            600
+
+typedef struct {
+	char ssid[SSID_LENGTH];
+	char bssid[BSSID_STR_SIZE];
+	int channel;
+	int encryption_mode;
+	int best_rssi;
+	int last_rssi;
+	time_t first_seen;
+	time_t last_seen;
+} scan_cache_entry_t;
+
+/**
+ * @brief Start background scans feeding a cache of visible APs
+ *
+ * Entries are keyed by BSSID and keep first/last seen time and best RSSI
+ *
+ * @param interval_sec Seconds between scans, at least SCAN_CACHE_MIN_INTERVAL_SEC
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int scan_cache_start(int interval_sec);
+
+/**
+ * @brief Stop background scans. Cache content is kept
+ */
+void scan_cache_stop(void);
+
+/**
+ * @brief Copy cached APs, strongest best RSSI first
+ *
+ * @param entries Output array
+ * @param max Size of entries
+ *
+ * @return Number of entries copied
+ */
+int scan_cache_get(scan_cache_entry_t *entries, int max);
+
+#endif
@@ -0,0 +1,198 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <time.h>
+#include <sys/time.h>
+#include <sys/stat.h>
+
+#include "test.h"
+#include "scan_export.h"
+
+/* APs opting out of location services append this to SSID */
+#define NOMAP_SUFFIX        "_nomap"
+#define SURVEY_MAX_SAMPLES  100
+
+static bool is_excluded(const wifi_scanlist_t *ap)
+{
+	const char *ssid = (const char *)ap->ssid;
+	size_t len = strnlen(ssid, SSID_LENGTH);
+	size_t suffix_len = strlen(NOMAP_SUFFIX);
+
+	if (!len)
+		return true;
+	return len >= suffix_len && !strcmp(ssid + len - suffix_len, NOMAP_SUFFIX);
+}
+
+static int channel_to_freq(int channel)
+{
+	if (channel == 14)
+		return 2484;
+	if (channel >= 1 && channel <= 13)
+		return 2407 + 5 * channel;
+	if (channel >= 32)
+		return 5000 + 5 * channel;
+	return 0;
+}
+
+/* Returns number of APs written */
+static int write_access_points(FILE *f, const wifi_scanlist_t *list, int count)
+{
+	int written = 0;
+
+	fprintf(f, "\"wifiAccessPoints\":[");
+	for (int i = 0; i < count; i++) {
+		const wifi_scanlist_t *ap = &list[i];
+
+		if (is_excluded(ap))
+			continue;
+
+		fprintf(f, "%s{\"macAddress\":\"%s\",\"signalStrength\":%d,\"channel\":%d",
+				written ? "," : "", (const char *)ap->bssid, ap->rssi, ap->channel);
+		if (channel_to_freq(ap->channel))
+			fprintf(f, ",\"frequency\":%d", channel_to_freq(ap->channel));
+		fprintf(f, ",\"age\":0}");
+		written++;
+	}
+	fprintf(f, "]");
+
+	return written;
+}
+
+int scan_export_geolocation(const char *path, const scan_export_position_t *pos)
+{
+	wifi_scanlist_t *list = NULL;
+	struct timeval tv = {0};
+	FILE *f = NULL;
+	int count = 0;
+	int written = 0;
+
+	if (!path) {
+		printf("Output path missing\n");
+		return FAILURE;
+	}
+
+	if (test_get_available_wifi_list(&list, &count) != SUCCESS) {
+		printf("Scan failed\n");
+		return FAILURE;
+	}
+	gettimeofday(&tv, NULL);
+
+	f = strcmp(path, "-") ? fopen(path, "w") : stdout;
+	if (!f) {
+		printf("Failed to open %s\n", path);
+		free(list);
+		return FAILURE;
+	}
+
+	if (pos) {
+		fprintf(f, "{\"items\":[{\"timestamp\":%lld,\"position\":{\"latitude\":%.7f,\"longitude\":%.7f",
+				(long long)tv.tv_sec * 1000 + tv.tv_usec / 1000,
+				pos->latitude, pos->longitude);
+		if (pos->accuracy > 0)
+			fprintf(f, ",\"accuracy\":%.1f", pos->accuracy);
+		fprintf(f, "},");
+		written = write_access_points(f, list, count);
+		fprintf(f, "}]}\n");
+	} else {
+		fprintf(f, "{\"considerIp\":false,");
+		written = write_access_points(f, list, count);
+		fprintf(f, "}\n");
+	}
+
+	if (f != stdout) {
+		fclose(f);
+		printf("Exported %d of %d scanned AP(s) to %s\n", written, count, path);
+	}
+	free(list);
+	return SUCCESS;
+}
+
+/* Writes CSV field, quoted when needed */
+static void write_csv_field(FILE *f, const char *str)
+{
+	if (!strpbrk(str, ",\"\r\n")) {
+		fputs(str, f);
+		return;
+	}
+
+	fputc('"', f);
+	for (; *str; str++) {
+		if (*str == '"')
+			fputc('"', f);
+		fputc(*str, f);
+	}
+	fputc('"', f);
+}
+
+int scan_export_survey_csv(const char *path, const char *location,
+		const scan_export_position_t *pos, const char *ssid, int samples)
+{
+	wifi_scanlist_t *list = NULL;
+	struct stat st = {0};
+	char ts[32] = {0};
+	struct tm tm_now = {0};
+	time_t now = 0;
+	FILE *f = NULL;
+	int count = 0;
+	int rows = 0;
+	bool new_file = false;
+
+	if (!path || !location || !*location) {
+		printf("Output path and location are required\n");
+		return FAILURE;
+	}
+
+	if (samples <= 0 || samples > SURVEY_MAX_SAMPLES) {
+		printf("Samples must be 1-%d\n", SURVEY_MAX_SAMPLES);
+		return FAILURE;
+	}
+
+	new_file = stat(path, &st) != 0 || st.st_size == 0;
+	f = fopen(path, "a");
+	if (!f) {
+		printf("Failed to open %s\n", path);
+		return FAILURE;
+	}
+
+	if (new_file)
+		fprintf(f, "timestamp,location,latitude,longitude,sample,ssid,bssid,channel,rssi\n");
+
+	for (int s = 1; s <= samples; s++) {
+		if (test_get_available_wifi_list(&list, &count) != SUCCESS) {
+			printf("Scan %d of %d failed, skipped\n", s, samples);
+			continue;
+		}
+
+		now = time(NULL);
+		gmtime_r(&now, &tm_now);
+		strftime(ts, sizeof(ts), "%Y-%m-%dT%H:%M:%SZ", &tm_now);
+
+		for (int i = 0; i < count; i++) {
+			const wifi_scanlist_t *ap = &list[i];
+
+			if (ssid && strncmp((const char *)ap->ssid, ssid, SSID_LENGTH))
+				continue;
+
+			fprintf(f, "%s,", ts);
+			write_csv_field(f, location);
+			if (pos)
+				fprintf(f, ",%.7f,%.7f,", pos->latitude, pos->longitude);
+			else
+				fprintf(f, ",,,");
+			fprintf(f, "%d,", s);
+			write_csv_field(f, (const char *)ap->ssid);
+			fprintf(f, ",%s,%d,%d\n", (const char *)ap->bssid, ap->channel, ap->rssi);
+			rows++;
+		}
+		free(list);
+		list = NULL;
+		printf("Sample %d of %d: %d AP(s)\n", s, samples, count);
+	}
+
+	fclose(f);
+	printf("Appended %d row(s) for location '%s' to %s\n", rows, location, path);
+	return SUCCESS;
+}
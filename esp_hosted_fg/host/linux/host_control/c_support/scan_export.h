@@ -0,0 +1,49 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef SCAN_EXPORT_H
+#define SCAN_EXPORT_H
+
+#include <stdbool.h>
+
+/* Optional position to tag scan with, e.g. from GPS */
+typedef struct {
+	double latitude;
+	double longitude;
+	/* Radius in meters, 0 if unknown */
+	double accuracy;
+} scan_export_position_t;
+
+/**
+ * @brief Scan and write visible APs as Wi-Fi geolocation JSON
+ *
+ * Without position, writes geolocate request body
+ * {"considerIp":false,"wifiAccessPoints":[...]}, usable to look up own
+ * position. With position, writes geosubmit body {"items":[...]} for
+ * coverage mapping. Hidden SSIDs and SSIDs ending in "_nomap" are skipped
+ *
+ * @param path Output file, "-" for stdout
+ * @param pos Position of this scan, NULL if unknown
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int scan_export_geolocation(const char *path, const scan_export_position_t *pos);
+
+/**
+ * @brief Take repeated scans at one location and append RSSI samples as CSV
+ *
+ * Each row is "timestamp,location,latitude,longitude,sample,ssid,bssid,
+ * channel,rssi". Header row is written when file is new. Repeating this at
+ * several locations builds a coverage/heatmap dataset
+ *
+ * @param path CSV file to append to
+ * @param location Label of location, e.g. "hall-east"
+ * @param pos Position of location, NULL if unknown
+ * @param ssid Only sample APs of this SSID, NULL for all
+ * @param samples Number of scans
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int scan_export_survey_csv(const char *path, const char *location,
+		const scan_export_position_t *pos, const char *ssid, int samples);
+
+#endif
@@ -0,0 +1,304 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <strings.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+#include <arpa/inet.h>
+
+#include "test.h"
+#include "nw_helper_func.h"
+#include "status_led.h"
+
+#define LED_SYSFS_DIR                    "/sys/class/leds"
+#define LED_TRIGGER_LEN                  64
+
+static const char *pattern_names[STATUS_LED_MAX_PATTERN] = {
+	"off", "on", "slow_blink", "fast_blink", "double_blink"
+};
+
+static const char *state_names[STATUS_LED_MAX_STATE] = {
+	"connected", "no_ip", "wifi_down", "esp_down"
+};
+
+static char led_name[STATUS_LED_NAME_LEN];
+static char saved_trigger[LED_TRIGGER_LEN];
+static int led_patterns[STATUS_LED_MAX_STATE];
+static int max_brightness;
+static int led_state = -1;
+static bool rpc_ready;
+
+static pthread_t led_thread;
+static bool led_running;
+static pthread_mutex_t led_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t led_cond = PTHREAD_COND_INITIALIZER;
+
+static int led_read(const char *attr, char *buf, size_t size)
+{
+	char path[128] = {0};
+	FILE *f = NULL;
+
+	snprintf(path, sizeof(path), LED_SYSFS_DIR "/%s/%s", led_name, attr);
+	f = fopen(path, "r");
+	if (!f) {
+		printf("Failed to open %s\n", path);
+		return FAILURE;
+	}
+	if (!fgets(buf, size, f)) {
+		fclose(f);
+		printf("Failed to read %s\n", path);
+		return FAILURE;
+	}
+	fclose(f);
+	buf[strcspn(buf, "\n")] = '\0';
+	return SUCCESS;
+}
+
+static int led_write(const char *attr, const char *val)
+{
+	char path[128] = {0};
+	FILE *f = NULL;
+	int ret = SUCCESS;
+
+	snprintf(path, sizeof(path), LED_SYSFS_DIR "/%s/%s", led_name, attr);
+	f = fopen(path, "w");
+	if (!f) {
+		printf("Failed to open %s\n", path);
+		return FAILURE;
+	}
+	if (fputs(val, f) < 0)
+		ret = FAILURE;
+	/* sysfs reports rejected value on close */
+	if (fclose(f) != 0)
+		ret = FAILURE;
+	if (ret != SUCCESS)
+		printf("Failed to write '%s' to %s\n", val, path);
+	return ret;
+}
+
+static int led_set(bool on)
+{
+	char val[16] = {0};
+
+	snprintf(val, sizeof(val), "%d", on ? max_brightness : 0);
+	return led_write("brightness", val);
+}
+
+/* Trigger attribute lists all triggers, with current one in brackets */
+static int read_current_trigger(char *trigger, size_t size)
+{
+	char buf[1024] = {0};
+	char *start = NULL;
+	char *end = NULL;
+
+	if (led_read("trigger", buf, sizeof(buf)) != SUCCESS)
+		return FAILURE;
+
+	start = strchr(buf, '[');
+	end = start ? strchr(start, ']') : NULL;
+	if (!start || !end || (size_t)(end - start - 1) >= size)
+		return FAILURE;
+
+	memcpy(trigger, start + 1, end - start - 1);
+	trigger[end - start - 1] = '\0';
+	return SUCCESS;
+}
+
+static bool pattern_level(int pattern, uint64_t now_ms)
+{
+	uint64_t phase = now_ms % 2000;
+
+	switch (pattern) {
+		case STATUS_LED_ON:
+			return true;
+		case STATUS_LED_SLOW_BLINK:
+			return phase < 1000;
+		case STATUS_LED_FAST_BLINK:
+			return phase % 400 < 200;
+		case STATUS_LED_DOUBLE_BLINK:
+			return phase < 200 || (phase >= 400 && phase < 600);
+		default:
+			return false;
+	}
+}
+
+/* Called without led_lock held, as it checks interface address */
+static int read_link_state(bool esp_rpc_ready)
+{
+	ctrl_link_status_t st = {0};
+	char ip[INET_ADDRSTRLEN] = {0};
+
+	if (!esp_rpc_ready || get_ctrl_link_status(&st) != SUCCESS ||
+	    st.health == CTRL_LINK_HEALTH_ESP_UNRESPONSIVE)
+		return STATUS_LED_STATE_ESP_DOWN;
+	if (st.health == CTRL_LINK_HEALTH_WIFI_DOWN)
+		return STATUS_LED_STATE_WIFI_DOWN;
+	if (get_ipv4_addr(STA_INTERFACE, ip, sizeof(ip)) != SUCCESS || !ip[0])
+		return STATUS_LED_STATE_NO_IP;
+	return STATUS_LED_STATE_CONNECTED;
+}
+
+static void wait_ms(int ms)
+{
+	struct timespec deadline = {0};
+
+	clock_gettime(CLOCK_REALTIME, &deadline);
+	deadline.tv_sec += ms / 1000;
+	deadline.tv_nsec += (ms % 1000) * 1000000L;
+	if (deadline.tv_nsec >= 1000000000L) {
+		deadline.tv_sec++;
+		deadline.tv_nsec -= 1000000000L;
+	}
+	while (led_running &&
+	       pthread_cond_timedwait(&led_cond, &led_lock, &deadline) != ETIMEDOUT)
+		;
+}
+
+static void *led_thread_handler(void *arg)
+{
+	uint64_t next_check = 0;
+	uint64_t now = 0;
+	bool level = false;
+	bool cur_level = false;
+	bool esp_rpc_ready = false;
+	int state = STATUS_LED_STATE_ESP_DOWN;
+
+	/* Start from known level, as trigger may have left LED on */
+	led_set(false);
+
+	pthread_mutex_lock(&led_lock);
+	while (led_running) {
+		now = get_mono_ms();
+		if (now >= next_check) {
+			esp_rpc_ready = rpc_ready;
+			pthread_mutex_unlock(&led_lock);
+			state = read_link_state(esp_rpc_ready);
+			pthread_mutex_lock(&led_lock);
+			if (state != led_state)
+				printf("Status LED: %s\n", state_names[state]);
+			led_state = state;
+			next_check = now + STATUS_LED_CHECK_MS;
+		}
+
+		level = pattern_level(led_patterns[state], now);
+		if (level != cur_level) {
+			pthread_mutex_unlock(&led_lock);
+			led_set(level);
+			pthread_mutex_lock(&led_lock);
+			cur_level = level;
+		}
+
+		wait_ms(STATUS_LED_TICK_MS);
+	}
+	pthread_mutex_unlock(&led_lock);
+
+	return NULL;
+}
+
+int status_led_start(const char *led, const int patterns[STATUS_LED_MAX_STATE])
+{
+	char buf[16] = {0};
+
+	if (!led || !*led || strchr(led, '/') || strlen(led) >= STATUS_LED_NAME_LEN || !patterns) {
+		printf("Invalid status LED config\n");
+		return FAILURE;
+	}
+
+	for (int i = 0; i < STATUS_LED_MAX_STATE; i++) {
+		if (patterns[i] < 0 || patterns[i] >= STATUS_LED_MAX_PATTERN) {
+			printf("Invalid pattern for %s\n", state_names[i]);
+			return FAILURE;
+		}
+	}
+
+	status_led_stop();
+
+	memset(led_name, 0, sizeof(led_name));
+	strncpy(led_name, led, sizeof(led_name) - 1);
+
+	if (led_read("max_brightness", buf, sizeof(buf)) != SUCCESS)
+		return FAILURE;
+	max_brightness = atoi(buf);
+	if (max_brightness <= 0)
+		max_brightness = 1;
+
+	/* Kernel trigger would fight with our writes */
+	if (read_current_trigger(saved_trigger, sizeof(saved_trigger)) != SUCCESS)
+		strcpy(saved_trigger, "none");
+	if (led_write("trigger", "none") != SUCCESS)
+		return FAILURE;
+
+	memcpy(led_patterns, patterns, sizeof(led_patterns));
+	led_state = -1;
+	led_running = true;
+
+	if (pthread_create(&led_thread, NULL, led_thread_handler, NULL) != 0) {
+		printf("Failed to create status LED thread\n");
+		led_running = false;
+		led_write("trigger", saved_trigger);
+		return FAILURE;
+	}
+
+	return SUCCESS;
+}
+
+const char *status_led_pattern_str(int pattern)
+{
+	if (pattern < 0 || pattern >= STATUS_LED_MAX_PATTERN)
+		return "unknown";
+	return pattern_names[pattern];
+}
+
+const char *status_led_state_str(int state)
+{
+	if (state < 0 || state >= STATUS_LED_MAX_STATE)
+		return "unknown";
+	return state_names[state];
+}
+
+int status_led_pattern_from_str(const char *str)
+{
+	if (!str)
+		return -1;
+
+	for (int i = 0; i < STATUS_LED_MAX_PATTERN; i++) {
+		if (strcasecmp(str, pattern_names[i]) == 0)
+			return i;
+	}
+	return -1;
+}
+
+void status_led_rpc_ready(void)
+{
+	pthread_mutex_lock(&led_lock);
+	rpc_ready = true;
+	pthread_mutex_unlock(&led_lock);
+}
+
+void status_led_rpc_down(void)
+{
+	pthread_mutex_lock(&led_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&led_lock);
+}
+
+void status_led_stop(void)
+{
+	pthread_mutex_lock(&led_lock);
+	if (!led_running) {
+		pthread_mutex_unlock(&led_lock);
+		return;
+	}
+	led_running = false;
+	pthread_cond_signal(&led_cond);
+	pthread_mutex_unlock(&led_lock);
+
+	pthread_join(led_thread, NULL);
+
+	led_set(false);
+	led_write("trigger", saved_trigger);
+}
@@ -0,0 +1,65 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef STATUS_LED_H
+#define STATUS_LED_H
+
+#define STATUS_LED_NAME_LEN              64
+/* Pattern resolution */
+#define STATUS_LED_TICK_MS               100
+#define STATUS_LED_CHECK_MS              1000
+
+typedef enum {
+	STATUS_LED_OFF,
+	STATUS_LED_ON,
+	STATUS_LED_SLOW_BLINK,   /* 1s on, 1s off */
+	STATUS_LED_FAST_BLINK,   /* 200ms on, 200ms off */
+	STATUS_LED_DOUBLE_BLINK, /* Two short blinks every 2s */
+	STATUS_LED_MAX_PATTERN,
+} status_led_pattern_e;
+
+typedef enum {
+	STATUS_LED_STATE_CONNECTED,  /* Station connected, with IPv4 address */
+	STATUS_LED_STATE_NO_IP,      /* Station connected, no IPv4 address yet */
+	STATUS_LED_STATE_WIFI_DOWN,  /* ESP responds, station not connected */
+	STATUS_LED_STATE_ESP_DOWN,   /* ESP not responding, or RPC lost */
+	STATUS_LED_MAX_STATE,
+} status_led_state_e;
+
+/**
+ * @brief Start driving host LED from link state
+ *
+ * LED is a Linux LED class device, /sys/class/leds/<led>. Its trigger is
+ * set to "none" while driven, and restored on stop. Writing needs root
+ *
+ * @param led LED name under /sys/class/leds
+ * @param patterns Pattern per state, indexed by status_led_state_e
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int status_led_start(const char *led, const int patterns[STATUS_LED_MAX_STATE]);
+
+/**
+ * @brief Name of pattern or state, e.g. "slow_blink" or "wifi_down"
+ */
+const char *status_led_pattern_str(int pattern);
+const char *status_led_state_str(int state);
+
+/**
+ * @brief Parse pattern name as given by status_led_pattern_str
+ *
+ * @return Pattern, or -1 if not known
+ */
+int status_led_pattern_from_str(const char *str);
+
+/**
+ * @brief Link state is only read from ESP while RPC is up, else ESP down is shown
+ */
+void status_led_rpc_ready(void);
+void status_led_rpc_down(void);
+
+/**
+ * @brief Stop driving LED, turn it off and restore its trigger
+ */
+void status_led_stop(void);
+
+#endif
@@ -89,6 +89,11 @@ int main(int argc, char *argv[])
 	/* Print FW Version by Default */
 	printf("------ ESP-Hosted FW [%s] ------\n", test_get_fw_version(version, sizeof(version)));
 
+	if (test_check_min_fw_version(MIN_FIRMWARE_VERSION, MIN_FIRMWARE_VERSION_ENFORCE)) {
+		/* Skip tests, only clean up */
+		stress_test_count = 0;
+	}
+
 	for (int test_count=0; test_count<stress_test_count; test_count++) {
 		printf("\n\nIteration %u:\n",test_count+1);
 		for (int i=str_args_start; i<argc; i++) {
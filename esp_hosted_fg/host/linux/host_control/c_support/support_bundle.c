@@ -0,0 +1,133 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <unistd.h>
+#include <fcntl.h>
+#include <time.h>
+#include <sys/stat.h>
+#include <sys/utsname.h>
+#include <sys/wait.h>
+
+#include "test.h"
+#include "redact.h"
+#include "support_bundle.h"
+
+static void copy_redacted(FILE *out, FILE *in)
+{
+	char line[SUPPORT_BUNDLE_LINE_LEN];
+	char redacted[SUPPORT_BUNDLE_LINE_LEN + sizeof(REDACT_MASK)];
+
+	while (fgets(line, sizeof(line), in)) {
+		redact_line(redacted, sizeof(redacted), line);
+		fputs(redacted, out);
+	}
+}
+
+FILE *support_bundle_open(const char *path)
+{
+	struct utsname uts = {0};
+	char ts[32] = {0};
+	time_t now = time(NULL);
+	FILE *out = NULL;
+	int fd = -1;
+
+	if (!path || !*path) {
+		printf("Support bundle path missing\n");
+		return NULL;
+	}
+
+	fd = open(path, O_WRONLY | O_CREAT | O_TRUNC, 0600);
+	if (fd < 0) {
+		printf("Failed to create %s\n", path);
+		return NULL;
+	}
+	/* Already existing file keeps its mode otherwise */
+	fchmod(fd, 0600);
+
+	out = fdopen(fd, "w");
+	if (!out) {
+		close(fd);
+		printf("Failed to open %s\n", path);
+		return NULL;
+	}
+
+	strftime(ts, sizeof(ts), "%Y-%m-%dT%H:%M:%SZ", gmtime(&now));
+	uname(&uts);
+	fprintf(out, "ESP-Hosted support bundle\ncreated %s\nhost %s\nkernel %s %s %s\n",
+			ts, uts.nodename, uts.sysname, uts.release, uts.machine);
+	return out;
+}
+
+void support_bundle_section(FILE *out, const char *title)
+{
+	fprintf(out, "\n===== %s =====\n", title);
+	fflush(out);
+}
+
+int support_bundle_add_cmd(FILE *out, const char *cmd)
+{
+	char full_cmd[SUPPORT_BUNDLE_LINE_LEN];
+	FILE *p = NULL;
+	int status = 0;
+
+	snprintf(full_cmd, sizeof(full_cmd), "%s 2>&1", cmd);
+	p = popen(full_cmd, "r");
+	if (!p) {
+		fprintf(out, "failed to run: %s\n", cmd);
+		return FAILURE;
+	}
+	copy_redacted(out, p);
+	status = pclose(p);
+	if (status)
+		fprintf(out, "(exit status %d)\n", WEXITSTATUS(status));
+	return SUCCESS;
+}
+
+int support_bundle_capture(FILE *out, int (*fn)(int argc, char **argv),
+		int argc, char **argv)
+{
+	FILE *tmp = tmpfile();
+	int saved = -1;
+	int ret = FAILURE;
+
+	if (!tmp) {
+		fprintf(out, "failed to capture output\n");
+		return FAILURE;
+	}
+
+	fflush(stdout);
+	saved = dup(STDOUT_FILENO);
+	if (saved < 0 || dup2(fileno(tmp), STDOUT_FILENO) < 0) {
+		if (saved >= 0)
+			close(saved);
+		fclose(tmp);
+		fprintf(out, "failed to capture output\n");
+		return FAILURE;
+	}
+
+	ret = fn(argc, argv);
+
+	fflush(stdout);
+	dup2(saved, STDOUT_FILENO);
+	close(saved);
+
+	rewind(tmp);
+	copy_redacted(out, tmp);
+	fclose(tmp);
+	return ret;
+}
+
+int support_bundle_close(FILE *out)
+{
+	int ret = SUCCESS;
+
+	if (!out)
+		return FAILURE;
+	if (ferror(out))
+		ret = FAILURE;
+	if (fclose(out))
+		ret = FAILURE;
+	return ret;
+}
@@ -0,0 +1,52 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef SUPPORT_BUNDLE_H
+#define SUPPORT_BUNDLE_H
+
+#include <stdio.h>
+#include <stddef.h>
+
+#define SUPPORT_BUNDLE_LINE_LEN       1024
+
+/**
+ * @brief Create support bundle file, readable by owner only, and write
+ * header with time, host and kernel
+ *
+ * @param path Bundle file, overwritten if it exists
+ *
+ * @return Open file, NULL on failure
+ */
+FILE *support_bundle_open(const char *path);
+
+/**
+ * @brief Start new titled section in bundle
+ */
+void support_bundle_section(FILE *out, const char *title);
+
+/**
+ * @brief Append output of shell command, stdout and stderr, redacted
+ * with redact_line()
+ *
+ * @return SUCCESS if command could be run, FAILURE otherwise
+ */
+int support_bundle_add_cmd(FILE *out, const char *cmd);
+
+/**
+ * @brief Run fn with stdout redirected into bundle, redacted
+ *
+ * Meant for hosted_shell command handlers printing their result.
+ * Anything printed by other threads meanwhile lands in bundle too
+ *
+ * @return Return value of fn, FAILURE if stdout could not be redirected
+ */
+int support_bundle_capture(FILE *out, int (*fn)(int argc, char **argv),
+		int argc, char **argv);
+
+/**
+ * @brief Finish and close bundle
+ *
+ * @return SUCCESS if everything was written, FAILURE otherwise
+ */
+int support_bundle_close(FILE *out);
+
+#endif
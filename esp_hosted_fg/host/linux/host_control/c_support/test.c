@@ -53,6 +53,7 @@ static void inline usage(char *argv[])
 		SOFTAP_STOP, SET_WIFI_POWERSAVE_MODE, GET_WIFI_POWERSAVE_MODE, SET_WIFI_MAX_TX_POWER, GET_WIFI_CURR_TX_POWER,
 		OTA, ENABLE_WIFI, DISABLE_WIFI, ENABLE_BT, DISABLE_BT, GET_FW_VERSION, SET_COUNTRY_CODE, SET_COUNTRY_CODE_ENABLED,
 		GET_COUNTRY_CODE,  CUSTOM_RPC_DEMO1, CUSTOM_RPC_DEMO2, CUSTOM_RPC_DEMO3);
+	printf("||\n %s [--json]\n", EVENTS);
 	printf("\n\nFor example, \nsudo %s %s\n",
 		argv[0], SET_STA_MAC_ADDR);
 }
@@ -60,6 +61,7 @@ static void inline usage(char *argv[])
 static int demo1_custom_rpc_unserialised_request_only_ack(void);
 static int demo2_custom_rpc_unserialised_request_and_slave_echo_back_as_response(void);
 static int demo3_custom_rpc_unserialised_request_and_slave_echo_back_as_event(void);
+static int wait_for_events(void);
 
 static int parse_cli_cmd(char *in_cmd, char *args[])
 {
@@ -100,6 +102,7 @@ static int parse_cli_cmd(char *in_cmd, char *args[])
 	EXEC_IF_CMD_EQUALS(CUSTOM_RPC_DEMO1, demo1_custom_rpc_unserialised_request_only_ack());
 	EXEC_IF_CMD_EQUALS(CUSTOM_RPC_DEMO2, demo2_custom_rpc_unserialised_request_and_slave_echo_back_as_response());
 	EXEC_IF_CMD_EQUALS(CUSTOM_RPC_DEMO3, demo3_custom_rpc_unserialised_request_and_slave_echo_back_as_event());
+	EXEC_IF_CMD_EQUALS(EVENTS, wait_for_events());
 
 	if (cmd_executed)
 		return SUCCESS;
@@ -139,9 +142,18 @@ static void cleanup_app(void)
 	exit(1);
 }
 
+/* Events are printed by event callbacks, till SIGINT/SIGTERM cleans up */
+static int wait_for_events(void)
+{
+	while (1) {
+		sleep(1);
+	}
+	return SUCCESS;
+}
+
 static void sig_handler(int signum)
 {
-	printf("\nClean-up and exit\n");
+	fprintf(stderr, "\nClean-up and exit\n");
 	cleanup_app();
 }
 
@@ -172,6 +184,14 @@ int main(int argc, char *argv[])
 
 	/* Register Sig handler */
 	signal(SIGINT,sig_handler);
+	signal(SIGTERM,sig_handler);
+
+	/* Keep stdout to JSON lines only, so it can be piped to a consumer.
+	 * Set before init, so all other output goes to stderr */
+	if ((0 == strncasecmp(EVENTS, argv[1], sizeof(EVENTS))) &&
+	    argv[2] && (0 == strcmp("--json", argv[2]))) {
+		test_set_event_output_json(true);
+	}
 
 	if (init_app()) {
 		printf("Err Exit\n");
@@ -181,6 +201,12 @@ int main(int argc, char *argv[])
 	/* Print FW Version by Default */
 	printf("------ ESP-Hosted FW [%s] ------\n", test_get_fw_version(version, sizeof(version)));
 
+	if (test_check_min_fw_version(MIN_FIRMWARE_VERSION, MIN_FIRMWARE_VERSION_ENFORCE)) {
+		cleanup_app();
+		printf("Err Exit\n");
+		return -1;
+	}
+
 	cli_cmd = argv[1];
 	if (SUCCESS == parse_cli_cmd(cli_cmd, &argv[2])) {
 
@@ -50,7 +50,14 @@ int test_softap_mode_get_mac_addr(char *mac_str);
 int test_station_mode_connect(void);
 int test_async_station_mode_connect(void);
 int test_station_mode_get_info(void);
+int test_station_mode_get_rssi(int *rssi);
+const char *wifi_auth_mode_to_str(int auth_mode);
+const char *wifi_channel_to_band(int channel);
+uint64_t get_mono_ms(void);
+void json_escape(char *dst, size_t dst_size, const char *src);
+void test_set_event_output_json(bool enable);
 int test_get_available_wifi(void);
+int test_get_available_wifi_list(wifi_scanlist_t **list, int *count);
 int test_station_mode_disconnect(void);
 int test_softap_mode_start(void);
 int test_softap_mode_get_info(void);
@@ -79,6 +86,7 @@ int test_disable_wifi(void);
 int test_enable_wifi(void);
 char * test_get_fw_version(char *, uint16_t);
 int test_print_fw_version(void);
+int test_check_min_fw_version(const char *min_version, bool enforce);
 int test_set_country_code_with_ieee80211d_on();
 int test_set_country_code();
 int test_set_country_code_with_params(const char *code);
@@ -96,7 +104,7 @@ int test_get_fw_version_with_params(char *version, uint16_t version_size);
 int test_ota_update_with_params(const char *url);
 int test_heartbeat_with_params(bool enable, int duration);
 int test_set_mac_addr_with_params(int mode, const char *mac);
-int test_set_vendor_specific_ie_with_params(bool enable, const char *data);
+int test_set_vendor_specific_ie_with_params(bool enable, int type, int idx, const char *data);
 int test_set_wifi_power_save_mode_with_params(int psmode);
 int test_get_fw_version_with_params(char *version, uint16_t version_size);
 int test_subscribe_event(const char *event);
@@ -27,12 +27,15 @@
 #include <sys/ioctl.h>
 #include <netdb.h>
 #include <errno.h>
+#include <inttypes.h>
 #include "ctrl_api.h"
 #include "ctrl_config.h"
 #include <time.h>
 #include "test.h"
 #include "nw_helper_func.h"
 #include "esp_hosted_custom_rpc.h"
+#include "webhook_notify.h"
+#include "conn_history.h"
 
 /***** Please Read *****/
 /* Before use : User must enter user configuration parameter in "ctrl_config.h" file */
@@ -49,6 +52,8 @@ static bool interface_down_printed = false;
 static bool interface_up_printed = false;
 static bool connected_printed = false;
 static bool disconnected_printed = false;
+static bool event_output_json = false;
+static FILE *json_out;
 
 #define PRINT_IF(cond, ...) \
     do { \
@@ -57,6 +62,9 @@ static bool disconnected_printed = false;
         } \
     } while (0)
 
+/* Human readable event lines, suppressed when events are printed as JSON */
+#define EVENT_PRINTF(...) PRINT_IF(!event_output_json, __VA_ARGS__)
+
 #define WIFI_VENDOR_IE_ELEMENT_ID                         0xDD
 #define OFFSET                                            4
 #define VENDOR_OUI_0                                      1
@@ -90,6 +98,28 @@ static ctrl_cmd_t * CTRL_CMD_DEFAULT_REQ(void)
 }
 
 
+const char *wifi_auth_mode_to_str(int auth_mode)
+{
+	switch (auth_mode) {
+		case WIFI_AUTH_OPEN:            return "open";
+		case WIFI_AUTH_WEP:             return "wep";
+		case WIFI_AUTH_WPA_PSK:         return "wpa_psk";
+		case WIFI_AUTH_WPA2_PSK:        return "wpa2_psk";
+		case WIFI_AUTH_WPA_WPA2_PSK:    return "wpa_wpa2_psk";
+		case WIFI_AUTH_WPA2_ENTERPRISE: return "wpa2_enterprise";
+		case WIFI_AUTH_WPA3_PSK:        return "wpa3_sae";
+		case WIFI_AUTH_WPA2_WPA3_PSK:   return "wpa2_wpa3_psk";
+		/* ESP reports newer modes (WAPI, OWE, WPA3 enterprise) as is */
+		default:                        return "other";
+	}
+}
+
+/* ESP reports channel only. 2.4 GHz band has channels 1 to 14 */
+const char *wifi_channel_to_band(int channel)
+{
+	return (channel >= 1 && channel <= 14) ? "2.4G" : "5G";
+}
+
 static char * get_timestamp(char *str, uint16_t str_size)
 {
 	if (str && str_size>=MIN_TIMESTAMP_STR_SIZE) {
@@ -101,6 +131,133 @@ static char * get_timestamp(char *str, uint16_t str_size)
 	return NULL;
 }
 
+/* Milliseconds since host boot, including suspend. Unlike time(NULL),
+ * it does not jump when host clock is set, e.g. on first SNTP sync */
+uint64_t get_mono_ms(void)
+{
+	struct timespec ts = {0};
+
+	clock_gettime(CLOCK_BOOTTIME, &ts);
+	return (uint64_t)ts.tv_sec * 1000 + ts.tv_nsec / 1000000;
+}
+
+/* Copies src into dst escaping characters not allowed in JSON string */
+void json_escape(char *dst, size_t dst_size, const char *src)
+{
+	size_t j = 0;
+
+	for (size_t i = 0; src && src[i] && j + 7 < dst_size; i++) {
+		unsigned char c = src[i];
+
+		if (c == '"' || c == '\\') {
+			dst[j++] = '\\';
+			dst[j++] = c;
+		} else if (c < 0x20) {
+			j += snprintf(dst + j, dst_size - j, "\\u%04x", c);
+		} else {
+			dst[j++] = c;
+		}
+	}
+	dst[j] = '\0';
+}
+
+static const char *get_event_name(int event_id)
+{
+	switch (event_id) {
+		case CTRL_EVENT_ESP_INIT:                           return "esp_init";
+		case CTRL_EVENT_HEARTBEAT:                          return "heartbeat";
+		case CTRL_EVENT_STATION_CONNECTED_TO_AP:            return "sta_connected";
+		case CTRL_EVENT_STATION_DISCONNECT_FROM_AP:         return "sta_disconnected";
+		case CTRL_EVENT_STATION_CONNECTED_TO_ESP_SOFTAP:    return "softap_sta_connected";
+		case CTRL_EVENT_STATION_DISCONNECT_FROM_ESP_SOFTAP: return "softap_sta_disconnected";
+		case CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG:        return "custom_rpc_event";
+		default:                                            return "unknown";
+	}
+}
+
+/* Prints event as one JSON object per line (NDJSON). Flushed per event, as
+ * json_out is block buffered when piped to another process */
+static void print_event_json(ctrl_cmd_t *app_event)
+{
+	char ts[32] = {0};
+	char ssid[6 * SSID_LENGTH + 1] = {0};
+	char raw_ssid[SSID_LENGTH + 1] = {0};
+	time_t now = time(NULL);
+	struct tm tm_now = {0};
+
+	gmtime_r(&now, &tm_now);
+	strftime(ts, sizeof(ts), "%Y-%m-%dT%H:%M:%SZ", &tm_now);
+	fprintf(json_out, "{\"time\":\"%s\",\"mono_ms\":%" PRIu64 ",\"event\":\"%s\"",
+			ts, get_mono_ms(), get_event_name(app_event->msg_id));
+
+	switch (app_event->msg_id) {
+		case CTRL_EVENT_HEARTBEAT: {
+			fprintf(json_out, ",\"hb_num\":%" PRIu32, app_event->u.e_heartbeat.hb_num);
+			break;
+		} case CTRL_EVENT_STATION_CONNECTED_TO_AP: {
+			event_sta_conn_t *p_e = &app_event->u.e_sta_conn;
+
+			memcpy(raw_ssid, p_e->ssid, SSID_LENGTH);
+			json_escape(ssid, sizeof(ssid), raw_ssid);
+			fprintf(json_out, ",\"ssid\":\"%s\",\"bssid\":\"%s\",\"channel\":%d,\"auth\":\"%s\",\"aid\":%d",
+					ssid, (char *)p_e->bssid, p_e->channel,
+					wifi_auth_mode_to_str(p_e->authmode), p_e->aid);
+			break;
+		} case CTRL_EVENT_STATION_DISCONNECT_FROM_AP: {
+			event_sta_disconn_t *p_e = &app_event->u.e_sta_disconn;
+
+			memcpy(raw_ssid, p_e->ssid, SSID_LENGTH);
+			json_escape(ssid, sizeof(ssid), raw_ssid);
+			fprintf(json_out, ",\"ssid\":\"%s\",\"bssid\":\"%s\",\"reason\":%" PRIu32 ",\"rssi\":%" PRId32,
+					ssid, (char *)p_e->bssid, p_e->reason, p_e->rssi);
+			break;
+		} case CTRL_EVENT_STATION_CONNECTED_TO_ESP_SOFTAP: {
+			event_softap_sta_conn_t *p_e = &app_event->u.e_softap_sta_conn;
+
+			fprintf(json_out, ",\"mac\":\"%s\",\"aid\":%" PRId32 ",\"is_mesh_child\":%s",
+					(char *)p_e->mac, p_e->aid, p_e->is_mesh_child ? "true" : "false");
+			break;
+		} case CTRL_EVENT_STATION_DISCONNECT_FROM_ESP_SOFTAP: {
+			event_softap_sta_disconn_t *p_e = &app_event->u.e_softap_sta_disconn;
+
+			fprintf(json_out, ",\"mac\":\"%s\",\"aid\":%" PRId32 ",\"is_mesh_child\":%s,\"reason\":%" PRIu32,
+					(char *)p_e->mac, p_e->aid, p_e->is_mesh_child ? "true" : "false", p_e->reason);
+			break;
+		} case CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG: {
+			custom_rpc_unserialised_data_t *p_e = &app_event->u.custom_rpc_unserialised_data;
+
+			fprintf(json_out, ",\"id\":%" PRIu32 ",\"data_len\":%" PRIu32,
+					(uint32_t)p_e->custom_msg_id, (uint32_t)p_e->data_len);
+			break;
+		} default:
+			break;
+	}
+	fprintf(json_out, "}\n");
+	fflush(json_out);
+}
+
+/* JSON events keep original stdout. Everything else printed, by app or
+ * control library, goes to stderr instead, so stdout has JSON lines only */
+void test_set_event_output_json(bool enable)
+{
+	int fd = -1;
+
+	event_output_json = enable;
+	if (!enable || json_out)
+		return;
+
+	fflush(stdout);
+	fd = dup(STDOUT_FILENO);
+	if (fd < 0 || !(json_out = fdopen(fd, "w"))) {
+		perror("Keep stdout for JSON events");
+		if (fd >= 0)
+			close(fd);
+		json_out = stdout;
+		return;
+	}
+	dup2(STDERR_FILENO, STDOUT_FILENO);
+}
+
 int test_validate_ctrl_event(ctrl_cmd_t *app_event) {
 	if (!app_event || (app_event->msg_type != CTRL_EVENT)) {
 		if (app_event)
@@ -143,6 +300,8 @@ int ctrl_app_resp_callback(ctrl_cmd_t *app_resp);
 
 static int ctrl_app_event_callback(ctrl_cmd_t *app_event) {
 	char ts[MIN_TIMESTAMP_STR_SIZE] = {'\0'};
+	char detail[128] = {'\0'};
+	static bool esp_init_seen = false;
 
 	if (test_validate_ctrl_event(app_event)) {
 		printf("%s invalid event[%u]\n", __func__, app_event->msg_id);
@@ -150,41 +309,70 @@ static int ctrl_app_event_callback(ctrl_cmd_t *app_event) {
 		return FAILURE;
 	}
 
+	if (event_output_json) {
+		print_event_json(app_event);
+	}
+
 	switch(app_event->msg_id) {
 		case CTRL_EVENT_ESP_INIT: {
-			printf("%s App EVENT: ESP INIT\n",
+			EVENT_PRINTF("%s App EVENT: ESP INIT\n",
 				get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE));
+			/* First init is expected, later ones mean ESP restarted */
+			if (esp_init_seen) {
+				webhook_notify(WEBHOOK_EVENT_FIRMWARE_RESTARTED,
+						"ESP restarted (crash, watchdog, reset or power loss)");
+			}
+			esp_init_seen = true;
 			    connected_printed = false;
 				disconnected_printed = false;
 				interface_up_printed = false;
 				interface_down_printed = false;
 			break;
 		} case CTRL_EVENT_HEARTBEAT: {
-			printf("%s App EVENT: Heartbeat event [%d]\n",
+			EVENT_PRINTF("%s App EVENT: Heartbeat event [%d]\n",
 				get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE),
 					app_event->u.e_heartbeat.hb_num);
 			break;
 		} case CTRL_EVENT_STATION_CONNECTED_TO_AP: {
 			event_sta_conn_t *p_e = &app_event->u.e_sta_conn;
-			PRINT_IF(!connected_printed, "Station interface is up/connected\n");
-			PRINT_IF(!connected_printed, "%s App EVENT: STA-Connected ssid[%s] bssid[%s] channel[%d] auth[%d] aid[%d]\n",
+			PRINT_IF(!connected_printed && !event_output_json, "Station interface is up/connected\n");
+			PRINT_IF(!connected_printed && !event_output_json, "%s App EVENT: STA-Connected ssid[%s] bssid[%s] channel[%d] auth[%d] aid[%d]\n",
 				get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE), p_e->ssid,
 				p_e->bssid, p_e->channel, p_e->authmode, p_e->aid);
+			if (!connected_printed) {
+				snprintf(detail, sizeof(detail), "ssid[%s] bssid[%s] channel[%d]",
+						p_e->ssid, p_e->bssid, p_e->channel);
+				webhook_notify(WEBHOOK_EVENT_CONNECTED, detail);
+			}
 			disconnected_printed = false;
 			connected_printed = true;
 			if (sta_network.mac_addr[0] != '\0') {
 				up_sta_netdev(&sta_network);
 			} else {
-				printf("Interface ethsta0 not made up, as MAC is not set\n");
-				printf("You may consider calling 'test_station_mode_get_mac_addr(sta_network.mac_addr);' to set the STA MAC before\n");
+				EVENT_PRINTF("Interface ethsta0 not made up, as MAC is not set\n");
+				EVENT_PRINTF("You may consider calling 'test_station_mode_get_mac_addr(sta_network.mac_addr);' to set the STA MAC before\n");
 			}
 			break;
 		} case CTRL_EVENT_STATION_DISCONNECT_FROM_AP: {
 			event_sta_disconn_t *p_e =  &app_event->u.e_sta_disconn;
-			PRINT_IF(!disconnected_printed, "Station interface is down/disconnected\n");
-			PRINT_IF(!disconnected_printed, "%s App EVENT: STA-Disconnected reason[%d] ssid[%s] bssid[%s] rssi[%d]\n",
+			PRINT_IF(!disconnected_printed && !event_output_json, "Station interface is down/disconnected\n");
+			PRINT_IF(!disconnected_printed && !event_output_json, "%s App EVENT: STA-Disconnected reason[%d] ssid[%s] bssid[%s] rssi[%d]\n",
 				get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE), p_e->reason, p_e->ssid,
 				p_e->bssid, p_e->rssi);
+			if (!disconnected_printed) {
+				conn_history_entry_t entry = {0};
+
+				snprintf(detail, sizeof(detail), "ssid[%s] bssid[%s] reason[%d]",
+						p_e->ssid, p_e->bssid, p_e->reason);
+				webhook_notify(WEBHOOK_EVENT_CONNECTION_LOST, detail);
+
+				entry.type = CONN_HISTORY_DISCONNECT;
+				entry.status = p_e->reason;
+				entry.rssi = p_e->rssi;
+				strncpy(entry.ssid, (char *)p_e->ssid, sizeof(entry.ssid) - 1);
+				strncpy(entry.bssid, (char *)p_e->bssid, sizeof(entry.bssid) - 1);
+				conn_history_add(&entry);
+			}
 			disconnected_printed = true;
 			connected_printed = false;
 			down_sta_netdev(&sta_network);
@@ -193,7 +381,7 @@ static int ctrl_app_event_callback(ctrl_cmd_t *app_event) {
 			event_softap_sta_conn_t *p_e = &app_event->u.e_softap_sta_conn;
 			char *p = (char *)p_e->mac;
 			if (p && strlen(p)) {
-				printf("%s App EVENT: SoftAP mode: Connected MAC[%s] aid[%d] is_mesh_child[%d]\n",
+				EVENT_PRINTF("%s App EVENT: SoftAP mode: Connected MAC[%s] aid[%d] is_mesh_child[%d]\n",
 					get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE),
 					p, p_e->aid, p_e->is_mesh_child);
 			}
@@ -202,28 +390,28 @@ static int ctrl_app_event_callback(ctrl_cmd_t *app_event) {
 			event_softap_sta_disconn_t *p_e = &app_event->u.e_softap_sta_disconn;
 			char *p = (char *)p_e->mac;
 			if (p && strlen(p)) {
-				printf("%s App EVENT: SoftAP mode: Disconnect MAC[%s] reason[%d] aid[%d] is_mesh_child[%d]\n",
+				EVENT_PRINTF("%s App EVENT: SoftAP mode: Disconnect MAC[%s] reason[%d] aid[%d] is_mesh_child[%d]\n",
 					get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE),
 					p, p_e->reason, p_e->aid, p_e->is_mesh_child);
 			}
 			break;
 		} case CTRL_EVENT_CUSTOM_RPC_UNSERIALISED_MSG: {
-			printf("%s App EVENT: Custom RPC unserialised message (Default handler)\n",
+			EVENT_PRINTF("%s App EVENT: Custom RPC unserialised message (Default handler)\n",
 				get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE));
 			custom_rpc_unserialised_data_t *p_e = &app_event->u.custom_rpc_unserialised_data;
-			printf("Received custom RPC event id[%u] data len[%u] data: \n",
+			EVENT_PRINTF("Received custom RPC event id[%u] data len[%u] data: \n",
 				p_e->custom_msg_id, p_e->data_len);
 			for (size_t i = 0; i < p_e->data_len && i < 32; i++) {
-				printf("%02X ", p_e->data[i]);
+				EVENT_PRINTF("%02X ", p_e->data[i]);
 			}
 			if (p_e->data_len > 32) {
-				printf(" ... (%u more bytes)", p_e->data_len - 32);
+				EVENT_PRINTF(" ... (%u more bytes)", p_e->data_len - 32);
 			}
-			printf("\n");
-			printf("Note: You can set your own event callback instead of this default handler\n");
+			EVENT_PRINTF("\n");
+			EVENT_PRINTF("Note: You can set your own event callback instead of this default handler\n");
 			break;
 		} default: {
-			printf("%s Invalid event[%u] to parse\n",
+			EVENT_PRINTF("%s Invalid event[%u] to parse\n",
 				get_timestamp(ts, MIN_TIMESTAMP_STR_SIZE), app_event->msg_id);
 			break;
 		}
@@ -252,6 +440,12 @@ static void process_failed_responses(ctrl_cmd_t *app_msg)
 		case CTRL_ERR_REQUEST_TIMEOUT:
 			printf("Error reported: Response Timeout\n");
 			break;
+		case CTRL_ERR_RATE_LIMITED:
+			printf("Error reported: Request rate limit exceeded\n");
+			break;
+		case CTRL_ERR_FRAME_TOO_LARGE:
+			printf("Error reported: Response from ESP too large, dropped\n");
+			break;
 		case CTRL_ERR_MEMORY_FAILURE:
 			printf("Error reported: Memory allocation failed\n");
 			break;
@@ -297,6 +491,9 @@ static void process_failed_responses(ctrl_cmd_t *app_msg)
 			} else if (app_msg->resp_event_status ==
 					CTRL_ERR_INVALID_PASSWORD) {
 				printf("Invalid password for SSID\n");
+			} else if (app_msg->resp_event_status ==
+					CTRL_ERR_ESP_NOT_SUPPORTED) {
+				printf("WPA3 (SAE) not supported by ESP firmware, enable CONFIG_ESP_WIFI_ENABLE_WPA3_SAE or connect without WPA3\n");
 			} else {
 				printf("Failed to connect with AP \n");
 			}
@@ -447,9 +644,10 @@ int ctrl_app_resp_callback(ctrl_cmd_t * app_resp)
 
 				printf("Number of available APs is %d \n", w_scan_p->count);
 				for (i=0; i<w_scan_p->count; i++) {
-					printf("%d) ssid \"%s\" bssid \"%s\" rssi \"%d\" channel \"%d\" auth mode \"%d\" \n",\
+					printf("%d) ssid \"%s\" bssid \"%s\" rssi \"%d\" channel \"%d\" band \"%s\" auth mode \"%s\" \n",\
 							i, list[i].ssid, list[i].bssid, list[i].rssi,
-							list[i].channel, list[i].encryption_mode);
+							list[i].channel, wifi_channel_to_band(list[i].channel),
+							wifi_auth_mode_to_str(list[i].encryption_mode));
 				}
 			}
 			break;
@@ -460,7 +658,7 @@ int ctrl_app_resp_callback(ctrl_cmd_t * app_resp)
 				printf("AP's bssid i.e. MAC address %s\n", p->bssid);
 				printf("AP's channel number %d\n", p->channel);
 				printf("AP's rssi %d\n", p->rssi);
-				printf("AP's encryption mode %d\n", p->encryption_mode);
+				printf("AP's encryption mode %s\n", wifi_auth_mode_to_str(p->encryption_mode));
 				printf("AP's band mode %d\n", p->band_mode);
 			} else {
 				printf("Station mode status: %s\n",p->status);
@@ -783,6 +981,39 @@ int test_async_station_mode_connect(void)
 	return SUCCESS;
 }
 
+/* Adds connect attempt to connection history. On success, BSSID and
+ * RSSI of AP actually joined are read back from ESP */
+static void record_connect_attempt(const char *ssid, const char *bssid,
+		const struct timespec *start, ctrl_cmd_t *resp)
+{
+	conn_history_entry_t entry = {0};
+	struct timespec now = {0};
+
+	clock_gettime(CLOCK_MONOTONIC, &now);
+	entry.type = CONN_HISTORY_CONNECT;
+	entry.duration_ms = (now.tv_sec - start->tv_sec) * 1000 +
+		(now.tv_nsec - start->tv_nsec) / 1000000;
+	entry.status = resp ? resp->resp_event_status : CTRL_ERR_REQUEST_TIMEOUT;
+	entry.rssi = CONN_HISTORY_RSSI_UNKNOWN;
+	strncpy(entry.ssid, ssid, sizeof(entry.ssid) - 1);
+	strncpy(entry.bssid, bssid, sizeof(entry.bssid) - 1);
+
+	if (entry.status == SUCCESS) {
+		ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
+		ctrl_cmd_t *ap = wifi_get_ap_config(req);
+
+		CLEANUP_CTRL_MSG(req);
+		if (successful_response(ap) &&
+		    !strncmp(SUCCESS_STR, ap->u.wifi_ap_config.status, strlen(SUCCESS_STR))) {
+			strncpy(entry.bssid, (char *)ap->u.wifi_ap_config.bssid, sizeof(entry.bssid) - 1);
+			entry.rssi = ap->u.wifi_ap_config.rssi;
+		}
+		CLEANUP_CTRL_MSG(ap);
+	}
+
+	conn_history_add(&entry);
+}
+
 int test_station_mode_connect(void)
 {
 	if (sta_network.mac_addr[0] == '\0') {
@@ -795,6 +1026,7 @@ int test_station_mode_connect(void)
 	/* implemented synchronous */
 	ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
 	ctrl_cmd_t *resp = NULL;
+	struct timespec start = {0};
 
 	printf("Connect to AP[%s]", STATION_MODE_SSID);
 
@@ -810,9 +1042,11 @@ int test_station_mode_connect(void)
 	interface_up_printed = false;
 	interface_down_printed = false;
 
+	clock_gettime(CLOCK_MONOTONIC, &start);
 	resp = wifi_connect_ap(req);
 
 	CLEANUP_CTRL_MSG(req);
+	record_connect_attempt(STATION_MODE_SSID, STATION_MODE_BSSID, &start, resp);
 	return ctrl_app_resp_callback(resp);
 }
 
@@ -828,6 +1062,25 @@ int test_station_mode_get_info(void)
 	return ctrl_app_resp_callback(resp);
 }
 
+/* Quiet variant for monitoring, FAILURE if station is not connected */
+int test_station_mode_get_rssi(int *rssi)
+{
+	ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
+	ctrl_cmd_t *resp = NULL;
+	int ret = FAILURE;
+
+	resp = wifi_get_ap_config(req);
+	CLEANUP_CTRL_MSG(req);
+
+	if (successful_response(resp) &&
+	    !strncmp(SUCCESS_STR, resp->u.wifi_ap_config.status, strlen(SUCCESS_STR))) {
+		*rssi = resp->u.wifi_ap_config.rssi;
+		ret = SUCCESS;
+	}
+	CLEANUP_CTRL_MSG(resp);
+	return ret;
+}
+
 int test_get_available_wifi(void)
 {
 	/* implemented synchronous */
@@ -840,6 +1093,44 @@ int test_get_available_wifi(void)
 	return ctrl_app_resp_callback(resp);
 }
 
+/* Scans and returns copy of AP list in *list, caller frees */
+int test_get_available_wifi_list(wifi_scanlist_t **list, int *count)
+{
+	/* implemented synchronous */
+	ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
+	ctrl_cmd_t *resp = NULL;
+	int ret = FAILURE;
+
+	*list = NULL;
+	*count = 0;
+
+	resp = wifi_ap_scan_list(req);
+	CLEANUP_CTRL_MSG(req);
+
+	if (!resp || resp->resp_event_status != SUCCESS) {
+		goto done;
+	}
+
+	ret = SUCCESS;
+	if (!resp->u.wifi_ap_scan.count || !resp->u.wifi_ap_scan.out_list) {
+		goto done;
+	}
+
+	*list = calloc(resp->u.wifi_ap_scan.count, sizeof(wifi_scanlist_t));
+	if (!*list) {
+		printf("Failed to allocate memory\n");
+		ret = FAILURE;
+		goto done;
+	}
+	memcpy(*list, resp->u.wifi_ap_scan.out_list,
+			resp->u.wifi_ap_scan.count * sizeof(wifi_scanlist_t));
+	*count = resp->u.wifi_ap_scan.count;
+
+done:
+	CLEANUP_CTRL_MSG(resp);
+	return ret;
+}
+
 int test_station_mode_disconnect(void)
 {
 	/* implemented synchronous */
@@ -1393,51 +1684,58 @@ char * test_get_fw_version(char *version, uint16_t version_size)
 	return version;
 }
 
-int test_print_fw_version(void)
+int test_check_min_fw_version(const char *min_version, bool enforce)
 {
-	char vers[30] = {'\0'};
-
-	printf("Hosted Slave FW Version [%s]\n", test_get_fw_version(vers, sizeof(vers)));
-
-	return 0;
-}
-
-int test_softap_mode_set_vendor_ie(bool enable, const char *data) {
-	/* implemented synchronous */
 	ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
 	ctrl_cmd_t *resp = NULL;
-	char *v_data = NULL;
+	int min_m1 = 0, min_m2 = 0, min_minor = 0;
+	int ret = SUCCESS;
 
-	if (data && strlen(data) > 0) {
-		v_data = (char*)calloc(1, strlen(data));
-		if (!v_data) {
-			printf("Failed to allocate memory\n");
-			return FAILURE;
+	if (!min_version ||
+	    sscanf(min_version, "%d.%d.%d", &min_m1, &min_m2, &min_minor) != 3) {
+		printf("Invalid minimum firmware version [%s], expected major_1.major_2.minor\n",
+				min_version ? min_version : "");
+		CLEANUP_CTRL_MSG(req);
+		return FAILURE;
+	}
+
+	resp = get_fw_version(req);
+	CLEANUP_CTRL_MSG(req);
+
+	if (!successful_response(resp)) {
+		printf("%s: could not get ESP firmware version to compare with required %s\n",
+				enforce ? "Error" : "Warning", min_version);
+		ret = enforce ? FAILURE : SUCCESS;
+	} else {
+		fw_version_t *v = &resp->u.fw_version;
+		int cmp = (v->major_1 != min_m1) ? (v->major_1 - min_m1) :
+			(v->major_2 != min_m2) ? (v->major_2 - min_m2) :
+			(v->minor - min_minor);
+
+		if (cmp < 0) {
+			printf("%s: ESP firmware %s-%d.%d.%d is older than required %s, please update ESP firmware\n",
+					enforce ? "Error" : "Warning", v->project_name,
+					v->major_1, v->major_2, v->minor, min_version);
+			ret = enforce ? FAILURE : SUCCESS;
 		}
-		memcpy(v_data, data, strlen(data));
 	}
 
-	req->u.wifi_softap_vendor_ie.enable = enable;
-	req->u.wifi_softap_vendor_ie.type   = WIFI_VND_IE_TYPE_BEACON;
-	req->u.wifi_softap_vendor_ie.idx	= WIFI_VND_IE_ID_0;
-	req->u.wifi_softap_vendor_ie.vnd_ie.element_id = WIFI_VENDOR_IE_ELEMENT_ID;
+	CLEANUP_CTRL_MSG(resp);
+	return ret;
+}
 
-	if (v_data) {
-		req->u.wifi_softap_vendor_ie.vnd_ie.length = strlen(data) + OFFSET;
-		req->u.wifi_softap_vendor_ie.vnd_ie.vendor_oui[0] = VENDOR_OUI_0;
-		req->u.wifi_softap_vendor_ie.vnd_ie.vendor_oui[1] = VENDOR_OUI_1;
-		req->u.wifi_softap_vendor_ie.vnd_ie.vendor_oui[2] = VENDOR_OUI_2;
-		req->u.wifi_softap_vendor_ie.vnd_ie.vendor_oui_type = VENDOR_OUI_TYPE;
-		req->u.wifi_softap_vendor_ie.vnd_ie.payload = (uint8_t *)v_data;
-		req->u.wifi_softap_vendor_ie.vnd_ie.payload_len = strlen(data);
+int test_print_fw_version(void)
+{
+	char vers[30] = {'\0'};
 
-		req->free_buffer_func = free;
-		req->free_buffer_handle = v_data;
-	}
+	printf("Hosted Slave FW Version [%s]\n", test_get_fw_version(vers, sizeof(vers)));
 
-	resp = wifi_set_vendor_specific_ie(req);
-	CLEANUP_CTRL_MSG(req);
-	return ctrl_app_resp_callback(resp);
+	return 0;
+}
+
+int test_softap_mode_set_vendor_ie(bool enable, const char *data) {
+	return test_set_vendor_specific_ie_with_params(enable, WIFI_VND_IE_TYPE_BEACON,
+			WIFI_VND_IE_ID_0, data);
 }
 
 /* Updated connect function with parameters */
@@ -1446,6 +1744,7 @@ int test_station_mode_connect_with_params(const char *ssid, const char *pwd, con
 {
 	ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
 	ctrl_cmd_t *resp = NULL;
+	struct timespec start = {0};
 
 	/*printf("Connect to AP[%s] with password[%s] and BSSID[%s] use_wpa3[%d] listen_interval[%d] band_mode[%d]\n",
 		   ssid ? ssid : STATION_MODE_SSID,
@@ -1469,8 +1768,11 @@ int test_station_mode_connect_with_params(const char *ssid, const char *pwd, con
     interface_down_printed = false;
 
 
+	clock_gettime(CLOCK_MONOTONIC, &start);
 	resp = wifi_connect_ap(req);
 	CLEANUP_CTRL_MSG(req);
+	record_connect_attempt(ssid ? ssid : STATION_MODE_SSID,
+			bssid ? bssid : STATION_MODE_BSSID, &start, resp);
 	return ctrl_app_resp_callback(resp);
 }
 
@@ -1646,7 +1948,7 @@ int test_set_mac_addr_with_params(int mode, const char *mac) {
 	return ctrl_app_resp_callback(resp);
 }
 
-int test_set_vendor_specific_ie_with_params(bool enable, const char *data) {
+int test_set_vendor_specific_ie_with_params(bool enable, int type, int idx, const char *data) {
 	/* implemented synchronous */
 	ctrl_cmd_t *req = CTRL_CMD_DEFAULT_REQ();
 	ctrl_cmd_t *resp = NULL;
@@ -1662,8 +1964,8 @@ int test_set_vendor_specific_ie_with_params(bool enable, const char *data) {
 	}
 
 	req->u.wifi_softap_vendor_ie.enable = enable;
-	req->u.wifi_softap_vendor_ie.type   = WIFI_VND_IE_TYPE_BEACON;
-	req->u.wifi_softap_vendor_ie.idx    = WIFI_VND_IE_ID_0;
+	req->u.wifi_softap_vendor_ie.type   = type;
+	req->u.wifi_softap_vendor_ie.idx    = idx;
 	req->u.wifi_softap_vendor_ie.vnd_ie.element_id = WIFI_VENDOR_IE_ELEMENT_ID;
 
 	if (v_data) {
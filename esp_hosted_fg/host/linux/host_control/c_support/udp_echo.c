@@ -0,0 +1,251 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <unistd.h>
+#include <pthread.h>
+#include <time.h>
+#include <poll.h>
+#include <endian.h>
+#include <sys/socket.h>
+#include <netinet/in.h>
+#include <arpa/inet.h>
+
+#include "test.h"
+#include "udp_echo.h"
+
+#define UDP_ECHO_MAGIC                   0x45535045 /* "ESPE" */
+#define UDP_ECHO_POLL_MS                 500
+
+/* Probe on wire, in network byte order */
+typedef struct __attribute__((packed)) {
+	uint32_t magic;
+	uint32_t seq;
+	/* Sender wall clock at send, us since epoch */
+	uint64_t tx_us;
+	/* Responder wall clock at echo, 0 in probe */
+	uint64_t echo_us;
+} udp_echo_pkt_t;
+
+static int echo_sock = -1;
+static pthread_t echo_thread;
+static bool echo_running;
+static pthread_mutex_t echo_lock = PTHREAD_MUTEX_INITIALIZER;
+
+static uint64_t clock_us(clockid_t clock)
+{
+	struct timespec ts = {0};
+
+	clock_gettime(clock, &ts);
+	return (uint64_t)ts.tv_sec * 1000000ULL + ts.tv_nsec / 1000;
+}
+
+static bool is_running(void)
+{
+	bool running = false;
+
+	pthread_mutex_lock(&echo_lock);
+	running = echo_running;
+	pthread_mutex_unlock(&echo_lock);
+	return running;
+}
+
+static void *echo_thread_handler(void *arg)
+{
+	udp_echo_pkt_t pkt = {0};
+	struct sockaddr_in from = {0};
+	socklen_t from_len = 0;
+	struct pollfd pfd = {.fd = echo_sock, .events = POLLIN};
+
+	while (is_running()) {
+		if (poll(&pfd, 1, UDP_ECHO_POLL_MS) <= 0)
+			continue;
+
+		from_len = sizeof(from);
+		/* Anything else is dropped, not to reflect arbitrary traffic */
+		if (recvfrom(echo_sock, &pkt, sizeof(pkt), 0, (struct sockaddr *)&from, &from_len) != sizeof(pkt) ||
+		    ntohl(pkt.magic) != UDP_ECHO_MAGIC || pkt.echo_us)
+			continue;
+
+		pkt.echo_us = htobe64(clock_us(CLOCK_REALTIME));
+		sendto(echo_sock, &pkt, sizeof(pkt), 0, (struct sockaddr *)&from, from_len);
+	}
+
+	return NULL;
+}
+
+int udp_echo_start(int port)
+{
+	struct sockaddr_in addr = {0};
+
+	if (port <= 0 || port > 65535) {
+		printf("Invalid port %d\n", port);
+		return FAILURE;
+	}
+
+	udp_echo_stop();
+
+	echo_sock = socket(AF_INET, SOCK_DGRAM, 0);
+	if (echo_sock < 0) {
+		perror("UDP echo socket:");
+		return FAILURE;
+	}
+
+	addr.sin_family = AF_INET;
+	addr.sin_port = htons(port);
+	addr.sin_addr.s_addr = htonl(INADDR_ANY);
+	if (bind(echo_sock, (struct sockaddr *)&addr, sizeof(addr)) < 0) {
+		perror("UDP echo bind:");
+		goto close_sock;
+	}
+
+	echo_running = true;
+	if (pthread_create(&echo_thread, NULL, echo_thread_handler, NULL) != 0) {
+		printf("Failed to create UDP echo thread\n");
+		echo_running = false;
+		goto close_sock;
+	}
+
+	return SUCCESS;
+
+close_sock:
+	close(echo_sock);
+	echo_sock = -1;
+	return FAILURE;
+}
+
+void udp_echo_stop(void)
+{
+	pthread_mutex_lock(&echo_lock);
+	if (!echo_running) {
+		pthread_mutex_unlock(&echo_lock);
+		return;
+	}
+	echo_running = false;
+	pthread_mutex_unlock(&echo_lock);
+
+	pthread_join(echo_thread, NULL);
+	close(echo_sock);
+	echo_sock = -1;
+}
+
+int udp_probe_run(const char *host, int port, int count, int interval_ms,
+		int timeout_ms, udp_probe_result_t *r)
+{
+	struct sockaddr_in addr = {0};
+	struct pollfd pfd = {0};
+	udp_echo_pkt_t pkt = {0};
+	uint64_t *sent_mono = NULL;
+	bool *seen = NULL;
+	uint64_t rtt_sum = 0;
+	uint64_t jitter_sum = 0;
+	int64_t out_sum = 0;
+	int64_t back_sum = 0;
+	int64_t last_rtt = -1;
+	uint64_t next_send = 0;
+	uint64_t deadline = 0;
+	uint64_t now = 0;
+	int sock = -1;
+	int ret = FAILURE;
+
+	if (!host || !r || port <= 0 || port > 65535 ||
+	    count <= 0 || count > UDP_ECHO_MAX_PROBES ||
+	    interval_ms < UDP_ECHO_MIN_INTERVAL_MS || timeout_ms <= 0 ||
+	    inet_pton(AF_INET, host, &addr.sin_addr) != 1) {
+		printf("Invalid parameter\n");
+		return FAILURE;
+	}
+	addr.sin_family = AF_INET;
+	addr.sin_port = htons(port);
+	memset(r, 0, sizeof(*r));
+
+	sent_mono = calloc(count, sizeof(uint64_t));
+	seen = calloc(count, sizeof(bool));
+	if (!sent_mono || !seen) {
+		printf("Failed to allocate probe state\n");
+		goto free_state;
+	}
+
+	sock = socket(AF_INET, SOCK_DGRAM, 0);
+	if (sock < 0) {
+		perror("UDP probe socket:");
+		goto free_state;
+	}
+	/* Replies only from responder */
+	if (connect(sock, (struct sockaddr *)&addr, sizeof(addr)) < 0) {
+		perror("UDP probe connect:");
+		goto close_sock;
+	}
+
+	pfd.fd = sock;
+	pfd.events = POLLIN;
+	next_send = clock_us(CLOCK_MONOTONIC);
+	deadline = next_send + (uint64_t)(count - 1) * interval_ms * 1000 + timeout_ms * 1000ULL;
+
+	while ((now = clock_us(CLOCK_MONOTONIC)) < deadline && r->received < count) {
+		uint64_t wake = deadline;
+		int64_t rtt = 0;
+		uint32_t seq = 0;
+
+		if (r->sent < count && now >= next_send) {
+			pkt.magic = htonl(UDP_ECHO_MAGIC);
+			pkt.seq = htonl(r->sent);
+			pkt.tx_us = htobe64(clock_us(CLOCK_REALTIME));
+			pkt.echo_us = 0;
+			sent_mono[r->sent] = now;
+			if (send(sock, &pkt, sizeof(pkt), 0) != sizeof(pkt))
+				perror("UDP probe send:");
+			r->sent++;
+			next_send += interval_ms * 1000ULL;
+			continue;
+		}
+
+		if (r->sent < count && next_send < wake)
+			wake = next_send;
+		if (poll(&pfd, 1, (int)((wake - now + 999) / 1000)) <= 0)
+			continue;
+
+		/* ICMP port unreachable shows up as error here, keep waiting */
+		if (recv(sock, &pkt, sizeof(pkt), 0) != sizeof(pkt) ||
+		    ntohl(pkt.magic) != UDP_ECHO_MAGIC || !pkt.echo_us)
+			continue;
+
+		now = clock_us(CLOCK_MONOTONIC);
+		seq = ntohl(pkt.seq);
+		if (seq >= (uint32_t)r->sent || seen[seq])
+			continue;
+		seen[seq] = true;
+		r->received++;
+
+		rtt = now - sent_mono[seq];
+		if (r->received == 1 || rtt < r->rtt_min_us)
+			r->rtt_min_us = rtt;
+		if (rtt > r->rtt_max_us)
+			r->rtt_max_us = rtt;
+		rtt_sum += rtt;
+		if (last_rtt >= 0)
+			jitter_sum += rtt > last_rtt ? rtt - last_rtt : last_rtt - rtt;
+		last_rtt = rtt;
+
+		out_sum += (int64_t)(be64toh(pkt.echo_us) - be64toh(pkt.tx_us));
+		back_sum += (int64_t)(clock_us(CLOCK_REALTIME) - be64toh(pkt.echo_us));
+	}
+
+	if (r->received) {
+		r->rtt_avg_us = rtt_sum / r->received;
+		r->out_avg_us = out_sum / r->received;
+		r->back_avg_us = back_sum / r->received;
+	}
+	if (r->received > 1)
+		r->jitter_us = jitter_sum / (r->received - 1);
+	ret = SUCCESS;
+
+close_sock:
+	close(sock);
+free_state:
+	free(sent_mono);
+	free(seen);
+	return ret;
+}
@@ -0,0 +1,62 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef UDP_ECHO_H
+#define UDP_ECHO_H
+
+#include <stdint.h>
+
+#define UDP_ECHO_DEFAULT_PORT            7007
+#define UDP_ECHO_MAX_PROBES              1000
+#define UDP_ECHO_MIN_INTERVAL_MS         10
+
+typedef struct {
+	int sent;
+	int received;
+	/* Round trip, over received probes */
+	uint32_t rtt_min_us;
+	uint32_t rtt_avg_us;
+	uint32_t rtt_max_us;
+	/* Mean change of round trip between consecutive replies */
+	uint32_t jitter_us;
+	/* One way delays from sender and responder wall clocks. Only
+	 * meaningful if both clocks are synced, e.g. by NTP or PTP */
+	int64_t out_avg_us;
+	int64_t back_avg_us;
+} udp_probe_result_t;
+
+/**
+ * @brief Start answering UDP probes on given port, on all interfaces
+ *
+ * Only packets in probe format are answered, stamped with wall clock
+ * time of this host
+ *
+ * @param port UDP port
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int udp_echo_start(int port);
+
+/**
+ * @brief Stop answering UDP probes
+ */
+void udp_echo_stop(void);
+
+/**
+ * @brief Send probes to udp_echo responder and measure latency and jitter
+ *
+ * Blocks for about count * interval_ms + timeout_ms
+ *
+ * @param host IPv4 address of responder
+ * @param port UDP port of responder
+ * @param count Probes to send, 1 to UDP_ECHO_MAX_PROBES
+ * @param interval_ms Gap between probes, at least UDP_ECHO_MIN_INTERVAL_MS
+ * @param timeout_ms Wait for replies after last probe
+ * @param r Filled with results
+ *
+ * @return SUCCESS if probes were sent, even if none was answered,
+ * FAILURE otherwise
+ */
+int udp_probe_run(const char *host, int port, int count, int interval_ms,
+		int timeout_ms, udp_probe_result_t *r);
+
+#endif
@@ -0,0 +1,121 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <unistd.h>
+#include <time.h>
+#include <pthread.h>
+#include <sys/types.h>
+#include <sys/wait.h>
+#include <net/if.h>
+#include <arpa/inet.h>
+
+#include "test.h"
+#include "nw_helper_func.h"
+#include "webhook_notify.h"
+
+#define WEBHOOK_URL_LEN          256
+#define WEBHOOK_BODY_LEN         1024
+#define WEBHOOK_TIMEOUT_SEC      "10"
+
+static char webhook_url[WEBHOOK_URL_LEN];
+static char webhook_iface[IFNAMSIZ];
+static char last_ip[INET_ADDRSTRLEN];
+static pthread_mutex_t webhook_lock = PTHREAD_MUTEX_INITIALIZER;
+
+int webhook_notify_configure(const char *url, const char *iface)
+{
+	if (url && strlen(url) >= sizeof(webhook_url)) {
+		printf("Webhook URL too long\n");
+		return FAILURE;
+	}
+	if (iface && strlen(iface) >= sizeof(webhook_iface)) {
+		printf("Invalid interface name\n");
+		return FAILURE;
+	}
+
+	pthread_mutex_lock(&webhook_lock);
+	memset(webhook_url, 0, sizeof(webhook_url));
+	memset(webhook_iface, 0, sizeof(webhook_iface));
+	if (url)
+		strncpy(webhook_url, url, sizeof(webhook_url) - 1);
+	if (iface)
+		strncpy(webhook_iface, iface, sizeof(webhook_iface) - 1);
+	pthread_mutex_unlock(&webhook_lock);
+
+	return SUCCESS;
+}
+
+void webhook_notify(const char *event, const char *detail)
+{
+	char url[WEBHOOK_URL_LEN] = {0};
+	char iface[IFNAMSIZ] = {0};
+	char body[WEBHOOK_BODY_LEN] = {0};
+	char esc_detail[WEBHOOK_BODY_LEN / 2] = {0};
+	char host[64] = {0};
+	char ts[32] = {0};
+	time_t now = time(NULL);
+	struct tm tm_now = {0};
+	pid_t pid = 0;
+
+	pthread_mutex_lock(&webhook_lock);
+	strncpy(url, webhook_url, sizeof(url) - 1);
+	strncpy(iface, webhook_iface, sizeof(iface) - 1);
+	pthread_mutex_unlock(&webhook_lock);
+
+	if (!url[0] || !event)
+		return;
+
+	gethostname(host, sizeof(host) - 1);
+	gmtime_r(&now, &tm_now);
+	strftime(ts, sizeof(ts), "%Y-%m-%dT%H:%M:%SZ", &tm_now);
+	json_escape(esc_detail, sizeof(esc_detail), detail ? detail : "");
+	snprintf(body, sizeof(body),
+			"{\"event\":\"%s\",\"detail\":\"%s\",\"host\":\"%s\",\"time\":\"%s\"}",
+			event, esc_detail, host, ts);
+
+	/* Double fork, so curl is reparented to init and never blocks caller
+	 * (event callbacks) nor leaves zombies behind */
+	pid = fork();
+	if (pid < 0) {
+		printf("webhook: fork failed\n");
+		return;
+	}
+	if (pid == 0) {
+		if (fork() == 0) {
+			if (iface[0]) {
+				execlp("curl", "curl", "-s", "-o", "/dev/null", "-m", WEBHOOK_TIMEOUT_SEC,
+						"--interface", iface,
+						"-H", "Content-Type: application/json",
+						"-d", body, url, (char *)NULL);
+			} else {
+				execlp("curl", "curl", "-s", "-o", "/dev/null", "-m", WEBHOOK_TIMEOUT_SEC,
+						"-H", "Content-Type: application/json",
+						"-d", body, url, (char *)NULL);
+			}
+			_exit(127);
+		}
+		_exit(0);
+	}
+	waitpid(pid, NULL, 0);
+}
+
+void webhook_notify_check_ip(const char *iface)
+{
+	char ip[INET_ADDRSTRLEN] = {0};
+	char detail[64] = {0};
+
+	if (!iface || !webhook_url[0])
+		return;
+
+	get_ipv4_addr(iface, ip, sizeof(ip));
+
+	/* Address removed (link down) is reported by connection_lost */
+	if (!ip[0] || !strcmp(ip, last_ip))
+		return;
+
+	snprintf(detail, sizeof(detail), "%s %s -> %s", iface, last_ip[0] ? last_ip : "none", ip);
+	strncpy(last_ip, ip, sizeof(last_ip) - 1);
+	webhook_notify(WEBHOOK_EVENT_IP_CHANGED, detail);
+}
@@ -0,0 +1,39 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef WEBHOOK_NOTIFY_H
+#define WEBHOOK_NOTIFY_H
+
+#define WEBHOOK_EVENT_CONNECTED          "connected"
+#define WEBHOOK_EVENT_CONNECTION_LOST    "connection_lost"
+#define WEBHOOK_EVENT_IP_CHANGED         "ip_changed"
+#define WEBHOOK_EVENT_FIRMWARE_RESTARTED "firmware_restarted"
+#define WEBHOOK_EVENT_ESP_UNRESPONSIVE   "esp_unresponsive"
+#define WEBHOOK_EVENT_IP_CONFLICT        "ip_conflict"
+#define WEBHOOK_EVENT_RECONNECT_TEST     "reconnect_test"
+
+/**
+ * @brief Configure webhook target
+ *
+ * @param url URL to POST JSON to, NULL or empty disables notifications
+ * @param iface Network interface to send through (e.g. "eth0"), NULL for
+ *              default route. Useful when ethsta0 itself is down
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int webhook_notify_configure(const char *url, const char *iface);
+
+/**
+ * @brief POST {"event", "detail", "host", "time"} to configured URL
+ *
+ * Returns immediately, request is sent by detached curl process.
+ * No-op when no URL is configured
+ */
+void webhook_notify(const char *event, const char *detail);
+
+/**
+ * @brief Notify WEBHOOK_EVENT_IP_CHANGED if IPv4 address of iface changed
+ * since last call. Meant to be polled
+ */
+void webhook_notify_check_ip(const char *iface);
+
+#endif
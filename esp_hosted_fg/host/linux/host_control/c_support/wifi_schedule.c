@@ -0,0 +1,224 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#include <stdio.h>
+#include <string.h>
+#include <stdlib.h>
+#include <stdbool.h>
+#include <pthread.h>
+#include <time.h>
+#include <errno.h>
+
+#include "test.h"
+#include "wifi_schedule.h"
+
+#define WIFI_SCHEDULE_CHECK_SEC          10
+
+static wifi_window_t sched_windows[WIFI_SCHEDULE_MAX_WINDOWS];
+static int num_sched_windows;
+static char sched_ssid[SSID_LENGTH];
+static char sched_pwd[PASSWORD_LENGTH];
+static time_t wake_until;
+static bool wifi_on;
+
+static pthread_t sched_thread;
+static bool sched_running;
+static bool rpc_ready;
+static pthread_mutex_t sched_lock = PTHREAD_MUTEX_INITIALIZER;
+static pthread_cond_t sched_cond = PTHREAD_COND_INITIALIZER;
+
+static int parse_hh_mm(const char *str, int *minutes)
+{
+	int hh = 0, mm = 0;
+	char *end = NULL;
+
+	hh = strtol(str, &end, 10);
+	if (end == str || *end != ':')
+		return FAILURE;
+	str = end + 1;
+	mm = strtol(str, &end, 10);
+	if (end == str || hh < 0 || hh > 23 || mm < 0 || mm > 59)
+		return FAILURE;
+
+	*minutes = hh * 60 + mm;
+	return SUCCESS;
+}
+
+int wifi_schedule_parse_windows(const char *str, wifi_window_t *windows)
+{
+	char buf[256] = {0};
+	char *saveptr = NULL;
+	char *tok = NULL;
+	int num = 0;
+
+	if (!str || !windows || strlen(str) >= sizeof(buf))
+		return -1;
+	strncpy(buf, str, sizeof(buf) - 1);
+
+	for (tok = strtok_r(buf, ",", &saveptr); tok; tok = strtok_r(NULL, ",", &saveptr)) {
+		char *dash = strchr(tok, '-');
+
+		if (num >= WIFI_SCHEDULE_MAX_WINDOWS || !dash)
+			return -1;
+		*dash = '\0';
+		if (parse_hh_mm(tok, &windows[num].start_min) ||
+		    parse_hh_mm(dash + 1, &windows[num].end_min) ||
+		    windows[num].start_min == windows[num].end_min)
+			return -1;
+		num++;
+	}
+
+	return num ? num : -1;
+}
+
+bool wifi_window_contains(const wifi_window_t *windows, int num, const struct tm *now)
+{
+	int min = now->tm_hour * 60 + now->tm_min;
+
+	for (int i = 0; i < num; i++) {
+		const wifi_window_t *w = &windows[i];
+
+		if (w->start_min < w->end_min) {
+			if (min >= w->start_min && min < w->end_min)
+				return true;
+		} else if (min >= w->start_min || min < w->end_min) {
+			return true;
+		}
+	}
+	return false;
+}
+
+static void set_wifi(bool on)
+{
+	if (on) {
+		printf("Wi-Fi schedule: window open, enabling Wi-Fi\n");
+		if (test_enable_wifi() != SUCCESS)
+			return;
+		if (test_station_mode_connect_with_params(sched_ssid, sched_pwd,
+					STATION_MODE_BSSID, STATION_MODE_IS_WPA3_SUPPORTED,
+					STATION_MODE_LISTEN_INTERVAL, STATION_BAND_MODE) != SUCCESS)
+			printf("Wi-Fi schedule: connect to '%s' failed\n", sched_ssid);
+	} else {
+		printf("Wi-Fi schedule: window closed, disabling Wi-Fi\n");
+		if (test_disable_wifi() != SUCCESS)
+			return;
+	}
+	wifi_on = on;
+}
+
+static void *sched_thread_handler(void *arg)
+{
+	struct timespec deadline = {0};
+	struct tm tm_now = {0};
+	time_t now = 0;
+	bool want_on = false;
+	bool ready = false;
+
+	pthread_mutex_lock(&sched_lock);
+	while (sched_running) {
+		now = time(NULL);
+		localtime_r(&now, &tm_now);
+		want_on = wifi_window_contains(sched_windows, num_sched_windows, &tm_now) ||
+			now < wake_until;
+		ready = rpc_ready;
+		pthread_mutex_unlock(&sched_lock);
+
+		/* Retried on next check if enable/disable failed, or while
+		 * control path is reinitialised */
+		if (ready && want_on != wifi_on)
+			set_wifi(want_on);
+
+		pthread_mutex_lock(&sched_lock);
+		clock_gettime(CLOCK_REALTIME, &deadline);
+		deadline.tv_sec += WIFI_SCHEDULE_CHECK_SEC;
+		while (sched_running &&
+		       pthread_cond_timedwait(&sched_cond, &sched_lock, &deadline) != ETIMEDOUT)
+			;
+	}
+	pthread_mutex_unlock(&sched_lock);
+
+	return NULL;
+}
+
+int wifi_schedule_start(const wifi_window_t *windows, int num, const char *ssid, const char *pwd)
+{
+	if (!windows || num <= 0 || num > WIFI_SCHEDULE_MAX_WINDOWS) {
+		printf("Invalid Wi-Fi windows\n");
+		return FAILURE;
+	}
+
+	if (!ssid || !*ssid || strlen(ssid) >= SSID_LENGTH) {
+		printf("Invalid SSID\n");
+		return FAILURE;
+	}
+
+	if (!pwd)
+		pwd = STATION_MODE_PWD;
+	if (strlen(pwd) >= PASSWORD_LENGTH) {
+		printf("Invalid password\n");
+		return FAILURE;
+	}
+
+	wifi_schedule_stop();
+
+	memcpy(sched_windows, windows, num * sizeof(wifi_window_t));
+	num_sched_windows = num;
+	memset(sched_ssid, 0, sizeof(sched_ssid));
+	strncpy(sched_ssid, ssid, sizeof(sched_ssid) - 1);
+	memset(sched_pwd, 0, sizeof(sched_pwd));
+	strncpy(sched_pwd, pwd, sizeof(sched_pwd) - 1);
+	wake_until = 0;
+	/* Assume on, so first check turns Wi-Fi off if outside window */
+	wifi_on = true;
+	sched_running = true;
+
+	if (pthread_create(&sched_thread, NULL, sched_thread_handler, NULL) != 0) {
+		printf("Failed to create Wi-Fi schedule thread\n");
+		sched_running = false;
+		return FAILURE;
+	}
+
+	return SUCCESS;
+}
+
+int wifi_schedule_wake(int duration_sec)
+{
+	pthread_mutex_lock(&sched_lock);
+	if (!sched_running) {
+		pthread_mutex_unlock(&sched_lock);
+		printf("Wi-Fi schedule not running\n");
+		return FAILURE;
+	}
+	wake_until = time(NULL) + duration_sec;
+	pthread_cond_signal(&sched_cond);
+	pthread_mutex_unlock(&sched_lock);
+
+	return SUCCESS;
+}
+
+void wifi_schedule_stop(void)
+{
+	pthread_mutex_lock(&sched_lock);
+	if (!sched_running) {
+		pthread_mutex_unlock(&sched_lock);
+		return;
+	}
+	sched_running = false;
+	pthread_cond_signal(&sched_cond);
+	pthread_mutex_unlock(&sched_lock);
+
+	pthread_join(sched_thread, NULL);
+}
+
+void wifi_schedule_rpc_ready(void)
+{
+	pthread_mutex_lock(&sched_lock);
+	rpc_ready = true;
+	pthread_mutex_unlock(&sched_lock);
+}
+
+void wifi_schedule_rpc_down(void)
+{
+	pthread_mutex_lock(&sched_lock);
+	rpc_ready = false;
+	pthread_mutex_unlock(&sched_lock);
+}
@@ -0,0 +1,72 @@
+/* SPDX-License-Identifier: GPL-2.0 */
+
+#ifndef WIFI_SCHEDULE_H
+#define WIFI_SCHEDULE_H
+
+#include <stdbool.h>
+#include <time.h>
+
+#define WIFI_SCHEDULE_MAX_WINDOWS        8
+
+/* Daily window in local time, as minutes since midnight.
+ * end_min < start_min means window crosses midnight */
+typedef struct {
+	int start_min;
+	int end_min;
+} wifi_window_t;
+
+/**
+ * @brief Parse "HH:MM-HH:MM[,HH:MM-HH:MM...]" into windows
+ *
+ * @param str Window list
+ * @param windows Output array of WIFI_SCHEDULE_MAX_WINDOWS
+ *
+ * @return Number of windows parsed, -1 if str is invalid
+ */
+int wifi_schedule_parse_windows(const char *str, wifi_window_t *windows);
+
+/**
+ * @brief Tell if local time now falls in any of windows
+ */
+bool wifi_window_contains(const wifi_window_t *windows, int num, const struct tm *now);
+
+/**
+ * @brief Start background scheduler keeping ESP Wi-Fi off outside windows
+ *
+ * On entering a window (or wake), Wi-Fi is enabled and station connects
+ * to ssid. On leaving it, Wi-Fi is disabled on ESP, which powers down
+ * the radio. ESP itself keeps running, as host talks to it over SPI/SDIO
+ *
+ * @param windows Daily windows
+ * @param num Number of windows
+ * @param ssid AP to connect in window
+ * @param pwd Password of AP, NULL for ctrl_config.h default
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int wifi_schedule_start(const wifi_window_t *windows, int num, const char *ssid, const char *pwd);
+
+/**
+ * @brief Keep Wi-Fi on for duration_sec from now, even outside windows
+ *
+ * @return SUCCESS if the operation was successful, FAILURE otherwise
+ */
+int wifi_schedule_wake(int duration_sec);
+
+/**
+ * @brief Stop scheduler. Wi-Fi is left in its current state
+ */
+void wifi_schedule_stop(void);
+
+/**
+ * @brief Tell scheduler that control path is usable, checks resume
+ */
+void wifi_schedule_rpc_ready(void);
+
+/**
+ * @brief Tell scheduler that control path is down. Windows and wake are
+ * kept, Wi-Fi is switched on next check once control path is back
+ */
+void wifi_schedule_rpc_down(void);
+
+#endif
@@ -249,6 +249,10 @@ def process_failed_responses(app_msg):
 		print("Err: Failed to set aync callback")
 	elif (app_msg.contents.resp_event_status == CTRL_ERR.CTRL_ERR_TRANSPORT_SEND.value):
 		print("Err: Problem while serial driver write")
+	elif (app_msg.contents.resp_event_status == CTRL_ERR.CTRL_ERR_RATE_LIMITED.value):
+		print("Err: Request rate limit exceeded")
+	elif (app_msg.contents.resp_event_status == CTRL_ERR.CTRL_ERR_FRAME_TOO_LARGE.value):
+		print("Err: Response from ESP too large, dropped")
 	else:
 		request_failed_flag = False
 
@@ -263,7 +267,10 @@ def process_failed_responses(app_msg):
 	elif (app_msg.contents.msg_id == CTRL_MSGID.CTRL_RESP_OTA_END.value):
 		print("OTA failed in OTA end")
 	elif (app_msg.contents.msg_id == CTRL_MSGID.CTRL_RESP_CONNECT_AP.value):
-		print("Failed to connect with AP, reason [" + str(app_msg.contents.resp_event_status) + "]")
+		if (app_msg.contents.resp_event_status == CTRL_ERR_ESP_NOT_SUPPORTED):
+			print("WPA3 (SAE) not supported by ESP firmware, enable CONFIG_ESP_WIFI_ENABLE_WPA3_SAE or connect without WPA3")
+		else:
+			print("Failed to connect with AP, reason [" + str(app_msg.contents.resp_event_status) + "]")
 	elif (app_msg.contents.msg_id == CTRL_MSGID.CTRL_RESP_START_SOFTAP.value):
 		print("Failed to start SoftAP")
 	elif (app_msg.contents.msg_id == CTRL_MSGID.CTRL_RESP_STOP_SOFTAP.value):
@@ -303,6 +310,14 @@ def CTRL_CMD_DEFAULT_REQ():
 
 	return new_req
 
+def auth_mode_to_str(auth_mode):
+	try:
+		return WIFI_AUTH_MODE(auth_mode).name[len("WIFI_AUTH_"):].lower()
+	except ValueError:
+		# ESP reports newer modes (WAPI, OWE, WPA3 enterprise) as is
+		return "other(" + str(auth_mode) + ")"
+
+
 def fail_resp(app_resp) :
 	cleanup_ctrl_msg(app_resp)
 	return FAILURE
@@ -382,7 +397,7 @@ def ctrl_app_resp_callback(app_resp):
 						" bssid \""+get_str(list[i].bssid)+"\""+
 						" rssi \""+str(list[i].rssi)+"\""+
 						" channel \""+str(list[i].channel)+"\""+
-						" auth mode \""+str(list[i].encryption_mode)+"\"")
+						" auth mode \""+auth_mode_to_str(list[i].encryption_mode)+"\"")
 
 	elif (app_resp.contents.msg_id == CTRL_MSGID.CTRL_RESP_CONNECT_AP.value) :
 		if not successful_response(app_resp):
@@ -401,7 +416,7 @@ def ctrl_app_resp_callback(app_resp):
 			print("AP's bssid \""+get_str(ap_config_p.contents.bssid)+"\"")
 			print("AP's channel number \""+str(ap_config_p.contents.channel)+"\"")
 			print("AP's rssi \""+str(ap_config_p.contents.rssi)+"\"")
-			print("AP's encryption mode \""+str(ap_config_p.contents.encryption_mode)+"\"")
+			print("AP's encryption mode \""+auth_mode_to_str(ap_config_p.contents.encryption_mode)+"\"")
 			print("AP's band mode \""+str(ap_config_p.contents.band_mode)+"\"")
 		else:
 			print("Station mode status: "+get_str(ap_config_p.contents.status))
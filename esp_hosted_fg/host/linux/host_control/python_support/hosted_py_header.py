@@ -127,7 +127,13 @@ class CTRL_ERR(Enum):
 	CTRL_ERR_TRANSPORT_SEND = 12
 	CTRL_ERR_REQUEST_TIMEOUT = 13
 	CTRL_ERR_REQ_IN_PROG = 14
-	OUT_OF_RANGE = 15
+	CTRL_ERR_RATE_LIMITED = 15
+	CTRL_ERR_FRAME_TOO_LARGE = 16
+	OUT_OF_RANGE = 17
+
+# ESP-IDF error passed as is by ESP in connect response, when requested
+# security (e.g. WPA3-SAE) is not enabled in ESP firmware
+CTRL_ERR_ESP_NOT_SUPPORTED = 0x106
 
 
 class CTRL_MSGTYPE(Enum):
@@ -241,6 +241,38 @@ class ctrl_cmd(object):
 		return self
 
 
+	def softap_dhcp_config(self, ip : str = "192.168.4.5", netmask : str = "255.255.255.0", range_start : str = "192.168.4.1", range_end : str = "192.168.4.20", lease_time : str = "1h", router : str = "", dns : str = ""):
+		"""Configure DHCP server run for ESP softAP network
+
+		Args:
+			ip(str, optional): O | IP address of ethap0 | Default: '192.168.4.5'
+			netmask(str, optional): O | Netmask of softAP subnet | Default: '255.255.255.0'
+			range_start(str, optional): O | First address of DHCP pool | Default: '192.168.4.1'
+			range_end(str, optional): O | Last address of DHCP pool | Default: '192.168.4.20'
+			lease_time(str, optional): O | Lease time e.g. 45m, 1h, 2d, infinite | Default: '1h'
+			router(str, optional): O | Gateway offered to stations | Default: ethap0 address
+			dns(str, optional): O | DNS server offered to stations | Default: ethap0 address
+
+		Returns:
+			ctrl_cmd: ctrl_cmd object
+		"""
+		self.out = process_softap_dhcp_config(ip, netmask, range_start, range_end, lease_time, router, dns)
+		return self
+
+
+	def softap_nat(self, enable : bool = True):
+		"""Route softAP clients to internet through ESP station uplink (NAT on host)
+
+		Args:
+			enable(bool, optional): O | Enable or disable NAT [ True | False ] | Default: True
+
+		Returns:
+			ctrl_cmd: ctrl_cmd object
+		"""
+		self.out = process_softap_nat(enable)
+		return self
+
+
 	def set_wifi_power_save(self, mode : str = "max"):
 		"""Set Wi-Fi power save
 
@@ -69,6 +69,14 @@ os_softap_static_ip = "192.168.4.5"
 os_softap_netmask = "255.255.255.0"
 os_softap_gateway = "192.168.4.1"
 
+# DHCP pool offered to stations connected to ESP softAP
+# Empty router/dns means dnsmasq offers its own interface address
+os_softap_dhcp_range_start = "192.168.4.1"
+os_softap_dhcp_range_end = "192.168.4.20"
+os_softap_dhcp_lease_time = "1h"
+os_softap_dhcp_router = ""
+os_softap_dhcp_dns = ""
+
 g_run_dhcp_on_station_connected = True
 g_stop_dhclient_on_disconnected = True
 g_run_dhcp_server_after_softap_up = True
@@ -404,6 +412,26 @@ def stop_dhclient_on_disconnected():
     return SUCCESS
 
 
+def set_softap_dhcp_config(ip_addr, netmask, range_start, range_end, lease_time, router, dns):
+    global os_softap_static_ip, os_softap_netmask
+    global os_softap_dhcp_range_start, os_softap_dhcp_range_end, os_softap_dhcp_lease_time
+    global os_softap_dhcp_router, os_softap_dhcp_dns
+
+    os_softap_static_ip = ip_addr
+    os_softap_netmask = netmask
+    os_softap_dhcp_range_start = range_start
+    os_softap_dhcp_range_end = range_end
+    os_softap_dhcp_lease_time = lease_time
+    os_softap_dhcp_router = router
+    os_softap_dhcp_dns = dns
+
+    # Apply right away if softAP network is already up
+    if g_ap_network_info and g_ap_network_info.network_up:
+        if up_softap_netdev() != SUCCESS:
+            return FAILURE
+        return run_dhcp_server()
+    return SUCCESS
+
 def run_dhcp_server():
     if not g_run_dhcp_server_after_softap_up:
         print("Not running DHCP server, as requested in nw_helper_func.py")
@@ -417,7 +445,8 @@ def run_dhcp_server():
         print("Network is not up" + g_ap_network_info.network_up)
         return FAILURE
 
-    ret = os.system("sudo bash ./run_dhcp_server.sh")
+    ret = os.system(f"sudo bash ./run_dhcp_server.sh {os_softap_dhcp_range_start} {os_softap_dhcp_range_end} "
+                    f"{os_softap_netmask} {os_softap_dhcp_lease_time} '{os_softap_dhcp_router}' '{os_softap_dhcp_dns}'")
     if ret != 0:
         print("DHCP server (dnsmasq) not configured/running")
         print("\033[91m Please review/edit and run 'bash -x run_dhcp_server.sh for your platform' \033[0m")
@@ -435,6 +464,40 @@ def stop_dhcp_server():
     ret = os.system("sudo bash ./stop_dhcp_server.sh")
     return SUCCESS if ret == 0 else FAILURE
 
+# NAT rules routing softAP clients through station uplink
+nat_rules = [
+    f"-t nat POSTROUTING -o {STA_INTERFACE} -j MASQUERADE",
+    f"FORWARD -i {AP_INTERFACE} -o {STA_INTERFACE} -j ACCEPT",
+    f"FORWARD -i {STA_INTERFACE} -o {AP_INTERFACE} -m state --state RELATED,ESTABLISHED -j ACCEPT",
+]
+
+def _iptables_rule(action, rule):
+    # '-t nat' table selector has to precede the action
+    if rule.startswith("-t "):
+        table, chain_rule = rule[:len("-t nat")], rule[len("-t nat "):]
+        return f"iptables {table} {action} {chain_rule}"
+    return f"iptables {action} {rule}"
+
+def set_softap_nat(enable):
+    if enable:
+        # ip_forward is left enabled on disable, as other users may rely on it
+        if os.system("sysctl -w net.ipv4.ip_forward=1 > /dev/null 2>&1") != 0:
+            print("Failed to enable IPv4 forwarding")
+            return FAILURE
+
+    for rule in nat_rules:
+        present = os.system(_iptables_rule("-C", rule) + " > /dev/null 2>&1") == 0
+        if enable and not present:
+            ret = os.system(_iptables_rule("-A", rule))
+        elif not enable and present:
+            ret = os.system(_iptables_rule("-D", rule))
+        else:
+            ret = 0
+        if ret != 0:
+            print("Failed to update iptables rule: " + rule)
+            return FAILURE
+    return SUCCESS
+
 def down_hci_instance():
     os.system(down_hci_instance_cmd)
     return SUCCESS
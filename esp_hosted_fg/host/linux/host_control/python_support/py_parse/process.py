@@ -17,6 +17,7 @@ from commands_lib import *
 from hosted_py_header import *
 import re
 import subprocess
+import ipaddress
 #from ctypes import byref
 from py_parse import nw_helper_func
 import traceback
@@ -201,6 +202,39 @@ def process_start_softap(ssid, pwd, channel, sec_prot, max_conn, hide_ssid, bw,
 	return ret_str
 
 
+def process_softap_dhcp_config(ip, netmask, range_start, range_end, lease_time, router, dns):
+	try:
+		net = ipaddress.IPv4Network(ip + "/" + netmask, strict=False)
+		start = ipaddress.IPv4Address(range_start)
+		end = ipaddress.IPv4Address(range_end)
+		for addr in [router, dns]:
+			if addr:
+				ipaddress.IPv4Address(addr)
+	except ValueError as e:
+		return "Invalid DHCP config: " + str(e)
+
+	if start not in net or end not in net:
+		return "DHCP range should be within " + str(net)
+	if start > end:
+		return "DHCP range start should not be after range end"
+	if not re.match("^[0-9]+[mhd]?$|^infinite$", str(lease_time)):
+		return "Invalid lease time " + str(lease_time) + ", use e.g. 45m, 1h, 2d or infinite"
+
+	if nw_helper_func.set_softap_dhcp_config(ip, netmask, range_start, range_end,
+			str(lease_time), router, dns) != SUCCESS:
+		return "Failed to apply softAP DHCP config"
+	return "softAP DHCP config set"
+
+
+def process_softap_nat(enable):
+	enable = _get_bool(enable)
+	if enable is None:
+		return "Invalid value for --enable, use True or False"
+	if nw_helper_func.set_softap_nat(enable) != SUCCESS:
+		return "Failed to " + ("enable" if enable else "disable") + " softAP NAT"
+	return "softAP NAT " + ("enabled" if enable else "disabled")
+
+
 def process_get_softap_info():
 	test_sync_softap_mode_get_info()
 	return ""
@@ -5,6 +5,24 @@
 # If your platform doesn't work with dnsmasq, or you use some other DHCP server
 # software, you can skip running this script.
 
+# Usage: run_dhcp_server.sh [range_start] [range_end] [netmask] [lease_time] [router] [dns]
+# Empty router or dns lets dnsmasq offer its own interface address.
+RANGE_START=${1:-192.168.4.1}
+RANGE_END=${2:-192.168.4.20}
+NETMASK=${3:-255.255.255.0}
+LEASE_TIME=${4:-1h}
+ROUTER=$5
+DNS=$6
+
+DHCP_RANGE="--dhcp-range=${RANGE_START},${RANGE_END},${NETMASK},${LEASE_TIME}"
+DHCP_OPTIONS=""
+if [ -n "$ROUTER" ]; then
+    DHCP_OPTIONS="$DHCP_OPTIONS --dhcp-option=3,${ROUTER}"
+fi
+if [ -n "$DNS" ]; then
+    DHCP_OPTIONS="$DHCP_OPTIONS --dhcp-option=6,${DNS}"
+fi
+
 echo "[run_dhcp_server.sh] Starting DHCP server setup..."
 
 # Check if the script is run as root
@@ -41,7 +59,7 @@ install_dnsmasq()
 is_dnsmasq_running_in_custom_way()
 {
     echo "[run_dhcp_server.sh] Checking if dnsmasq is running in custom way (port 55000)..."
-    if ps -eaf | grep -i "dnsmasq" | grep -- "--port=55000" &>/dev/null ; then
+    if ps -eaf | grep -i "dnsmasq" | grep -- "--port=55000" | grep -- "${DHCP_RANGE}${DHCP_OPTIONS}" &>/dev/null ; then
         echo "[run_dhcp_server.sh] dnsmasq is running in custom way"
         return 0
     else
@@ -59,7 +77,7 @@ run_dnsmasq_in_custom_way()
     fi
     sudo killall dnsmasq
     echo "[run_dhcp_server.sh] > Running dnsmasq in custom way"
-    nohup sudo dnsmasq --port=55000 --no-daemon --no-resolv --no-poll --dhcp-script=/system/bin/dhcp_announce --dhcp-range=192.168.4.1,192.168.4.20,1h &> /dev/null &
+    nohup sudo dnsmasq --port=55000 --no-daemon --no-resolv --no-poll --dhcp-script=/system/bin/dhcp_announce ${DHCP_RANGE}${DHCP_OPTIONS} &> /dev/null &
     echo "[run_dhcp_server.sh] dnsmasq started in background with custom options"
 }
 
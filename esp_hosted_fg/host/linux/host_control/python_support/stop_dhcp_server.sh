@@ -10,8 +10,8 @@ if [ "$EUID" -ne 0 ]; then
   exit 1
 fi
 
-echo "[stop_dhcp_server.sh] Looking for custom dnsmasq instance(s) running on port 40000..."
-PIDS=$(ps -eaf | grep -i "dnsmasq" | grep -- "--port=40000" | grep -v grep | awk '{print $2}')
+echo "[stop_dhcp_server.sh] Looking for custom dnsmasq instance(s) running on port 55000..."
+PIDS=$(ps -eaf | grep -i "dnsmasq" | grep -- "--port=55000" | grep -v grep | awk '{print $2}')
 if [ -n "$PIDS" ]; then
   echo "[stop_dhcp_server.sh] > Stopping custom dnsmasq instance(s) with PID(s): $PIDS"
   sudo kill $PIDS
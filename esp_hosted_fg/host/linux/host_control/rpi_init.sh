@@ -27,6 +27,14 @@ spi_handshake="534"
 # Module param, spi_dataready: spi dataready GPIO to use
 spi_dataready="539"
 
+# Module param, serial_rb_size: bytes from ESP buffered for control apps - Optional
+# Default: 32768. Raise if driver logs 'RB full' on large scan results
+serial_rb_size=""
+
+# Module param, serial_max_frame: largest message from ESP, bigger ones dropped whole - Optional
+# Default: 16384, can not be more than serial_rb_size - 1
+serial_max_frame=""
+
 ##################  Local params for host ######################
 cpu_perf="on"
 
@@ -410,6 +418,14 @@ parse_arguments() {
 				clockspeed=${arg#*=}
 				log "Clock freq: $clockspeed MHz"
 				;;
+			serial-rb-size=*)
+				serial_rb_size=${arg#*=}
+				log "Serial RB size: $serial_rb_size"
+				;;
+			serial-max-frame=*)
+				serial_max_frame=${arg#*=}
+				log "Serial max frame: $serial_max_frame"
+				;;
 			spi-bus=*)
 				spi_bus=${arg#*=}
 				log "SPI bus: $spi_bus"
@@ -570,6 +586,10 @@ usage() {
 	echo "  resetpin=<gpio>              Reset pin GPIO number"
 	echo "  clockspeed=<freq_mhz>        Clock frequency in MHz (default: per Device Tree, max: 50MHz)"
 	echo ""
+	echo "Control Path Configuration:"
+	echo "  serial-rb-size=<bytes>       Driver buffer for messages from ESP (default: 32768)"
+	echo "  serial-max-frame=<bytes>     Largest message from ESP, bigger ones dropped (default: 16384)"
+	echo ""
 	echo "UART Configuration (when using bt=uart-2pins or bt=uart-4pins):"
 	echo "  Note: UART pins must be configured in device tree"
 	echo "        No additional GPIO parameters required"
@@ -632,6 +652,12 @@ populate_module_params()
 	# Populate module params
 	add_module_param "resetpin"
 	add_module_param "clockspeed"
+	if [ -n "$serial_rb_size" ]; then
+		add_module_param "serial_rb_size"
+	fi
+	if [ -n "$serial_max_frame" ]; then
+		add_module_param "serial_max_frame"
+	fi
 
 	if [ "$IF_TYPE" = "spi" ]; then
 		add_module_param "spi_bus"
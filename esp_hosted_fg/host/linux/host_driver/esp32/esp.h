@@ -79,6 +79,8 @@ struct module_params {
 	int spi_cs;
 	int spi_handshake;
 	int spi_dataready;
+	int serial_rb_size;
+	int serial_max_frame;
 };
 
 struct esp_adapter {
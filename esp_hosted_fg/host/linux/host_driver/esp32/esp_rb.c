@@ -30,6 +30,7 @@ int esp_rb_init(esp_rb_t *rb, size_t sz)
 	rb->end = rb->buf + sz;
 	rb->rp = rb->wp = rb->buf;
 	rb->size = sz;
+	rb->dropped = false;
 
 	sema_init(&(rb->sem), 1);
 	esp_verbose("\n");
@@ -46,12 +47,18 @@ int esp_rb_read_by_user(esp_rb_t *rb, const char __user *buf, size_t sz, int blo
 	}
 
 	while (rb->rp == rb->wp) {
+		/* Reported once reader is past frames queued before the drop */
+		if (rb->dropped) {
+			rb->dropped = false;
+			up(&rb->sem);
+			return -EMSGSIZE;
+		}
 		up(&rb->sem);
 		if (block == 0) {
 			esp_verbose("%u EAGAIN\n", __LINE__);
 			return -EAGAIN;
 		}
-		if (wait_event_interruptible(rb->wq, (rb->rp != rb->wp))) {
+		if (wait_event_interruptible(rb->wq, (rb->rp != rb->wp || rb->dropped))) {
 			esp_verbose("%u Interrupted2 by signal\n", __LINE__);
 			return -ERESTARTSYS; /* Signal interruption */
 		}
@@ -95,6 +102,19 @@ int esp_rb_read_by_user(esp_rb_t *rb, const char __user *buf, size_t sz, int blo
 	return read_len;
 }
 
+/* Make reader fail with -EMSGSIZE, so app learns a frame was lost */
+void esp_rb_mark_dropped(esp_rb_t *rb)
+{
+	if (down_interruptible(&rb->sem)) {
+		esp_verbose("%u intr by sig\n", __LINE__);
+		return;
+	}
+	rb->dropped = true;
+	up(&rb->sem);
+
+	wake_up_interruptible(&rb->wq);
+}
+
 int get_free_space(esp_rb_t *rb)
 {
 	if (!rb || !rb->rp || !rb->wp) {
@@ -160,6 +180,45 @@ int esp_rb_write_by_kernel(esp_rb_t *rb, const char *buf, size_t sz)
 	return write_len;
 }
 
+/* Write all of buf or nothing, so reader never sees a cut frame */
+int esp_rb_write_all_by_kernel(esp_rb_t *rb, const char *buf, size_t sz)
+{
+	size_t first_len = 0;
+
+	if (!rb || !rb->wp || !rb->rp) {
+		esp_err("%u rb uninitialized\n", __LINE__);
+		return -EFAULT;
+	}
+
+	if (down_interruptible(&rb->sem)) {
+		esp_verbose("%u intr by sig\n", __LINE__);
+		return -ERESTARTSYS;
+	}
+
+	if (get_free_space(rb) < (int)sz) {
+		up(&rb->sem);
+		return -ENOSPC;
+	}
+
+	first_len = min(sz, (size_t)(rb->end - rb->wp));
+	memcpy(rb->wp, buf, first_len);
+	rb->wp += first_len;
+	if (rb->wp == rb->end) {
+		rb->wp = rb->buf;
+	}
+
+	if (first_len < sz) {
+		memcpy(rb->wp, buf+first_len, sz-first_len);
+		rb->wp += sz-first_len;
+	}
+
+	up(&rb->sem);
+
+	wake_up_interruptible(&rb->wq);
+
+	return sz;
+}
+
 void esp_rb_cleanup(esp_rb_t *rb)
 {
 	kfree(rb->buf);
@@ -14,12 +14,15 @@ typedef struct esp_rb {
 	size_t size;
 	unsigned char *rp, *wp;		/* current read/write pointers */
 	struct semaphore sem;		/* Mutex to protect rb */
+	bool dropped;			/* Frame dropped since reader last emptied rb */
 } esp_rb_t;
 
 int esp_rb_init(esp_rb_t *rb, size_t sz);
 void esp_rb_cleanup(esp_rb_t *rb);
 int esp_rb_read_by_user(esp_rb_t *rb, const char __user *buf, size_t sz, int block);
 int esp_rb_write_by_kernel(esp_rb_t *rb, const char *buf, size_t sz);
+int esp_rb_write_all_by_kernel(esp_rb_t *rb, const char *buf, size_t sz);
+void esp_rb_mark_dropped(esp_rb_t *rb);
 int get_free_space(esp_rb_t *rb);
 
 #endif
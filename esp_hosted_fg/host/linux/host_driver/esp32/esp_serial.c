@@ -36,8 +36,14 @@
 
 #define ESP_SERIAL_MAJOR      221
 #define ESP_SERIAL_MINOR_MAX  1
-#define ESP_RX_RB_SIZE        4096
+/* Holds two frames of ESP_SERIAL_MAX_FRAME */
+#define ESP_RX_RB_SIZE        (32 * 1024)
+#define ESP_RX_RB_SIZE_MIN    4096
+#define ESP_RX_RB_SIZE_MAX    (256 * 1024)
 #define ESP_SERIAL_MAX_TX     4096
+#define ESP_SERIAL_MIN_FRAME  256
+/* Same as SERIAL_MAX_FRAME of host control library */
+#define ESP_SERIAL_MAX_FRAME  16384
 
 static struct esp_serial_devs {
 	struct device* dev;
@@ -46,9 +52,15 @@ static struct esp_serial_devs {
 	esp_rb_t rb;
 	void *priv;
 	struct mutex lock;
+	/* Fragments of current frame from ESP, put in rb once last one is in.
+	 * Only touched from rx work, so no lock */
+	u8 *frame;
+	size_t frame_len;
+	bool frame_drop;
 } devs[ESP_SERIAL_MINOR_MAX];
 
 static uint8_t serial_init_done;
+static size_t serial_max_frame;
 static atomic_t ref_count_open;
 
 static ssize_t esp_serial_read(struct file *file, char __user *user_buffer, size_t size, loff_t *offset)
@@ -87,7 +99,7 @@ static ssize_t esp_serial_write(struct file *file, const char __user *user_buffe
 
 	if (size > ESP_SERIAL_MAX_TX) {
 		esp_err("Exceed max tx buffer size [%zu]\n", size);
-		return 0;
+		return -EMSGSIZE;
 	}
 
 	seq_num++;
@@ -149,7 +161,8 @@ static ssize_t esp_serial_write(struct file *file, const char __user *user_buffe
 			esp_err("Error copying buffer to send serial data\n");
 			return (size - left_len);
 		}
-		esp_hex_dump_dbg("esp_serial_tx: ", pos, frag_len);
+		/* Header only, payload may carry Wi-Fi passwords */
+		esp_hex_dump_dbg("esp_serial_tx: ", tx_buf, sizeof(struct esp_payload_header));
 
 		ret = esp_send_packet(dev->priv, tx_skb);
 		if (ret) {
@@ -209,7 +222,7 @@ static unsigned int esp_serial_poll(struct file *file, poll_table *wait)
     mutex_lock(&dev->lock);
     poll_wait(file, &dev->rb.wq,  wait);
 
-    if (dev->rb.rp != dev->rb.wp) {
+    if (dev->rb.rp != dev->rb.wp || dev->rb.dropped) {
         mask |= (POLLIN | POLLRDNORM) ;   /* readable */
     }
     if (get_free_space(&dev->rb)) {
@@ -230,36 +243,60 @@ const struct file_operations esp_serial_fops = {
 	.release = esp_serial_release,
 };
 
-int esp_serial_data_received(int dev_index, const char *data, size_t len)
+int esp_serial_data_received(int dev_index, const char *data, size_t len, u8 flags)
 {
-	int ret = 0, ret_len = 0;
+	struct esp_serial_devs *dev = NULL;
+	int ret = 0;
+
 	if (dev_index >= ESP_SERIAL_MINOR_MAX) {
 		esp_err("%u ERR: serial_dev_idx[%d] >= minor_max[%d]\n",
 				__LINE__, dev_index, ESP_SERIAL_MINOR_MAX);
 		return -EINVAL;
 	}
+	dev = &devs[dev_index];
 
 	if (!atomic_read(&ref_count_open)) {
 		esp_verbose("no user app listening: dropping packet\n");
+		/* App opening mid frame must not get its tail */
+		dev->frame_len = 0;
+		dev->frame_drop = !!(flags & MORE_FRAGMENT);
 		return len;
 	}
 
-	while (ret_len != len) {
-		ret = esp_rb_write_by_kernel(&devs[dev_index].rb,
-				data+ret_len, (len-ret_len));
-		if (ret <= 0) {
-			break;
-		}
-		ret_len += ret;
+	/* Frame is dropped whole, never cut at some fragment */
+	if (!dev->frame_drop && (!dev->frame || dev->frame_len + len > serial_max_frame)) {
+		esp_err("Frame from ESP over serial_max_frame[%zu] bytes, dropping it\n",
+				serial_max_frame);
+		dev->frame_drop = true;
+		esp_rb_mark_dropped(&dev->rb);
 	}
-	if (ret <= 0) {
-		return ret;
+
+	if (!dev->frame_drop) {
+		memcpy(dev->frame + dev->frame_len, data, len);
+		dev->frame_len += len;
 	}
-	if (ret_len != len) {
-		esp_err("RB full, no space to receive. Dropping packet\n");
+
+	if (flags & MORE_FRAGMENT) {
+		return len;
 	}
 
-	return ret_len;
+	ret = len;
+	if (!dev->frame_drop) {
+		ret = esp_rb_write_all_by_kernel(&dev->rb, dev->frame, dev->frame_len);
+		if (ret == -ENOSPC) {
+			esp_err("RB full, dropping %zu byte frame. Host app too slow, or increase serial_rb_size[%zu]\n",
+					dev->frame_len, dev->rb.size);
+			esp_rb_mark_dropped(&dev->rb);
+		}
+		if (ret >= 0) {
+			ret = len;
+		}
+	}
+
+	dev->frame_len = 0;
+	dev->frame_drop = false;
+
+	return ret;
 }
 
 static dev_t dev_first;
@@ -268,6 +305,9 @@ static struct class *cl;
 int esp_serial_init(void *priv)
 {
 	int err = -EINVAL, i = 0;
+	int rb_size = ESP_RX_RB_SIZE;
+	int max_frame = 0;
+	struct esp_adapter *adapter = priv;
 
 	if (!priv) {
 		esp_err("failed. NULL adapter\n");
@@ -278,6 +318,23 @@ int esp_serial_init(void *priv)
 	if (serial_init_done)
 		return 0;
 
+	if (adapter->mod_param.serial_rb_size != MOD_PARAM_UNINITIALISED) {
+		rb_size = clamp(adapter->mod_param.serial_rb_size, ESP_RX_RB_SIZE_MIN, ESP_RX_RB_SIZE_MAX);
+		if (rb_size != adapter->mod_param.serial_rb_size)
+			esp_warn("serial_rb_size[%d] out of range, using %d\n",
+					adapter->mod_param.serial_rb_size, rb_size);
+	}
+
+	/* Frame goes in rb whole, so can not be bigger than rb */
+	max_frame = min(ESP_SERIAL_MAX_FRAME, rb_size - 1);
+	if (adapter->mod_param.serial_max_frame != MOD_PARAM_UNINITIALISED) {
+		max_frame = clamp(adapter->mod_param.serial_max_frame, ESP_SERIAL_MIN_FRAME, rb_size - 1);
+		if (max_frame != adapter->mod_param.serial_max_frame)
+			esp_warn("serial_max_frame[%d] out of range, using %d\n",
+					adapter->mod_param.serial_max_frame, max_frame);
+	}
+	serial_max_frame = max_frame;
+
 	err = alloc_chrdev_region(&dev_first, 0, ESP_SERIAL_MINOR_MAX, "esp_serial_driver");
 	if (err) {
 		esp_err("Error alloc chrdev region %d\n", err);
@@ -297,7 +354,12 @@ int esp_serial_init(void *priv)
 		devs[i].dev = device_create(cl, NULL, dev_num, NULL, "esps%d", i);
 		cdev_init(&devs[i].cdev, &esp_serial_fops);
 		cdev_add(&devs[i].cdev, dev_num, 1);
-		esp_rb_init(&devs[i].rb, ESP_RX_RB_SIZE);
+		esp_rb_init(&devs[i].rb, rb_size);
+		devs[i].frame = kmalloc(serial_max_frame, GFP_KERNEL);
+		if (!devs[i].frame)
+			esp_err("Failed to allocate serial frame buffer\n");
+		devs[i].frame_len = 0;
+		devs[i].frame_drop = false;
 		devs[i].priv = priv;
 		mutex_init(&devs[i].lock);
 	}
@@ -326,6 +388,8 @@ void esp_serial_cleanup(void)
 			cdev_del(&devs[i].cdev);
 
 		esp_rb_cleanup(&devs[i].rb);
+		kfree(devs[i].frame);
+		devs[i].frame = NULL;
 		mutex_destroy(&devs[i].lock);
 	}
 
@@ -25,5 +25,5 @@ int esp_serial_init(void * priv);
 void esp_serial_cleanup(void);
 int esp_serial_reinit(void *priv);
 
-int esp_serial_data_received(int dev_index, const char *data, size_t len);
+int esp_serial_data_received(int dev_index, const char *data, size_t len, u8 flags);
 #endif
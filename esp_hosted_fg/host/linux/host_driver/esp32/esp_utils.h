@@ -57,6 +57,12 @@ static inline void esp_hex_dump_dbg(const char *prefix_str, const void *buf, siz
 #define esp_hex_dump_dbg(...) do {} while (0)
 #endif
 
+/* Length of packet at buf to hex dump. Serial (control path) payload may
+ * carry Wi-Fi passwords, so only its esp_payload_header is dumped */
+#define esp_pkt_dump_len(buf, len) \
+	((((const struct esp_payload_header *)(buf))->if_type == ESP_SERIAL_IF) ? \
+	 min_t(size_t, (len), sizeof(struct esp_payload_header)) : (size_t)(len))
+
 #ifdef CONFIG_VERBOSE_LOGS
 static inline void esp_hex_dump_verbose(const char *prefix_str, const void *buf, size_t len)
 {
@@ -45,6 +45,8 @@ static int spi_cs = MOD_PARAM_UNINITIALISED;
 static int spi_mode = MOD_PARAM_UNINITIALISED; /* 1/2/3 */
 static int spi_handshake = MOD_PARAM_UNINITIALISED;
 static int spi_dataready = MOD_PARAM_UNINITIALISED;
+static int serial_rb_size = MOD_PARAM_UNINITIALISED;
+static int serial_max_frame = MOD_PARAM_UNINITIALISED;
 
 MODULE_LICENSE("GPL");
 MODULE_AUTHOR("Amey Inamdar <amey.inamdar@espressif.com>");
@@ -74,6 +76,12 @@ MODULE_PARM_DESC(spi_handshake, "SPI: Handshake GPIO number");
 module_param(spi_dataready, int, S_IRUSR | S_IWUSR | S_IRGRP | S_IROTH);
 MODULE_PARM_DESC(spi_dataready, "SPI: Data Ready GPIO number");
 
+module_param(serial_rb_size, int, S_IRUSR | S_IWUSR | S_IRGRP | S_IROTH);
+MODULE_PARM_DESC(serial_rb_size, "Bytes from ESP buffered on /dev/esps0 until read by host app");
+
+module_param(serial_max_frame, int, S_IRUSR | S_IWUSR | S_IRGRP | S_IROTH);
+MODULE_PARM_DESC(serial_max_frame, "Largest message from ESP passed on /dev/esps0, bigger ones are dropped whole");
+
 struct esp_adapter adapter;
 volatile u8 stop_data = 0;
 
@@ -471,7 +479,7 @@ static void process_rx_packet(struct sk_buff *skb)
 	struct esp_payload_header *payload_header = NULL;
 	u16 len = 0, offset = 0;
 	u16 rx_checksum = 0, checksum = 0;
-	int ret = 0, ret_len = 0;
+	int ret = 0;
 	struct esp_adapter *adapter = esp_get_adapter();
 
 	if (!skb)
@@ -488,7 +496,7 @@ static void process_rx_packet(struct sk_buff *skb)
 		esp_hex_dump_dbg("Wake up rx: ", skb->data, (len+offset)>64? 64: (len+offset));
 	}
 
-	esp_hex_dump_dbg("rx: ", skb->data , len+offset);
+	esp_hex_dump_dbg("rx: ", skb->data , esp_pkt_dump_len(skb->data, len+offset));
 
 	if (adapter->capabilities & ESP_CHECKSUM_ENABLED) {
 		rx_checksum = le16_to_cpu(payload_header->checksum);
@@ -504,16 +512,12 @@ static void process_rx_packet(struct sk_buff *skb)
 	}
 
 	if (payload_header->if_type == ESP_SERIAL_IF) {
-		do {
-			ret = esp_serial_data_received(payload_header->if_num,
-					(skb->data + offset + ret_len), (len - ret_len));
-			if (ret < 0) {
-				esp_err("Failed to process data for iface type %d\n",
-						payload_header->if_num);
-				break;
-			}
-			ret_len += ret;
-		} while (ret_len < len);
+		ret = esp_serial_data_received(payload_header->if_num,
+				(skb->data + offset), len, payload_header->flags);
+		if (ret < 0) {
+			esp_err("Failed to process data for iface type %d\n",
+					payload_header->if_num);
+		}
 		dev_kfree_skb_any(skb);
 	} else if (payload_header->if_type == ESP_STA_IF ||
 	           payload_header->if_type == ESP_AP_IF) {
@@ -890,6 +894,8 @@ static int update_module_params(struct esp_adapter *adapter)
 	adapter->mod_param.spi_mode = spi_mode;
 	adapter->mod_param.spi_handshake = spi_handshake;
 	adapter->mod_param.spi_dataready = spi_dataready;
+	adapter->mod_param.serial_rb_size = serial_rb_size;
+	adapter->mod_param.serial_max_frame = serial_max_frame;
 	return 0;
 }
 
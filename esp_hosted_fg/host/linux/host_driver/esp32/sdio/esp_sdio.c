@@ -530,7 +530,7 @@ static struct sk_buff * read_packet(struct esp_adapter *adapter)
 			return NULL;
 		}
 
-		esp_hex_dump_dbg("sdio_rx: ", skb->data , min(skb->len, 32));
+		esp_hex_dump_dbg("sdio_rx: ", skb->data , esp_pkt_dump_len(skb->data, min(skb->len, 32)));
 
 		data_left -= len_to_read;
 		pos += len_to_read;
@@ -704,7 +704,7 @@ static int tx_process(void *data)
 		pad = ESP_BLOCK_SIZE - (data_left % ESP_BLOCK_SIZE);
 		data_left += pad;
 
-		esp_hex_dump_dbg("sdio_tx: ", tx_skb->data, 32);
+		esp_hex_dump_dbg("sdio_tx: ", tx_skb->data, esp_pkt_dump_len(tx_skb->data, 32));
 
 		do {
 			block_cnt = data_left / ESP_BLOCK_SIZE;
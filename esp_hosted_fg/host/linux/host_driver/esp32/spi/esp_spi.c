@@ -348,7 +348,7 @@ static int process_rx_buf(struct sk_buff *skb)
 
 	header = (struct esp_payload_header *) skb->data;
 
-	esp_hex_dump_dbg("spi_rx: ", skb->data , min(skb->len, 32));
+	esp_hex_dump_dbg("spi_rx: ", skb->data , esp_pkt_dump_len(skb->data, min(skb->len, 32)));
 
 	if (header->if_type >= ESP_MAX_IF) {
 		return -EINVAL;
@@ -475,7 +475,7 @@ static void esp_spi_transaction(void)
 			offset = le16_to_cpu(h->offset);
 			h->checksum = 0;
 			h->checksum = cpu_to_le16(compute_checksum((u8 *)trans.tx_buf, len + offset));
-			esp_hex_dump_dbg("spi_tx: ", trans.tx_buf, min(len, 64));
+			esp_hex_dump_dbg("spi_tx: ", trans.tx_buf, esp_pkt_dump_len(trans.tx_buf, min(len, 64)));
 		}
 	} else {
 #if ESP_PKT_NUM_DEBUG
@@ -211,6 +211,9 @@ int serial_drv_write (struct serial_drv_handle_t* serial_drv_handle,
  * Returns
  *      buf                         :   Protocol encoded data Buffer
  *                                      caller will decode the protobuf
+ *      NULL                        :   On failure. errno is EMSGSIZE if
+ *                                      frame was over max frame length, or
+ *                                      driver dropped it as too large
  */
 
 uint8_t * serial_drv_read(struct serial_drv_handle_t *serial_drv_handle,
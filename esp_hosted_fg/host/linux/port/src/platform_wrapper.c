@@ -23,6 +23,7 @@
 #define SUCCESS                 0
 #define FAILURE                 -1
 #define DUMMY_READ_BUF_LEN      64
+#define SERIAL_MAX_FRAME        16384
 #define EAGAIN                  11
 
 #define HOSTED_CALLOC(buff,nbytes) do {                           \
@@ -38,6 +39,8 @@
 
 struct serial_drv_handle_t {
 	int file_desc;
+	/* Same as file_desc, except for split transport */
+	int write_desc;
 };
 
 extern int errno;
@@ -494,8 +497,158 @@ void *hosted_timer_start(int duration, int type,
 
 
 /* -------- Serial Drv ---------- */
+#ifdef ESP_HOSTED_FAULT_INJECTION
+/* Transport fault injection, to test recovery of host apps.
+ * Configured from environment on first serial_drv_open():
+ *   ESP_HOSTED_FAULT_SEED         Seed, reuse to reproduce a run (default: time)
+ *   ESP_HOSTED_FAULT_DELAY_MS     Max random delay before each write and read
+ *   ESP_HOSTED_FAULT_DROP_PCT     Percent of messages to ESP not sent, and of
+ *                                 messages from ESP discarded
+ *   ESP_HOSTED_FAULT_CORRUPT_PCT  Percent of messages from ESP with one bit flipped
+ *   ESP_HOSTED_FAULT_RESET_PCT    Percent of messages from ESP replaced by ESP
+ *                                 init event, as if ESP was reset meanwhile
+ */
+static struct {
+	bool initialised;
+	unsigned int seed;
+	int delay_ms;
+	int drop_pct;
+	int corrupt_pct;
+	int reset_pct;
+} fault_cfg;
+static pthread_mutex_t fault_lock = PTHREAD_MUTEX_INITIALIZER;
+
+static int fault_env_int(const char *name, int max)
+{
+	const char *val = getenv(name);
+	int n = val ? atoi(val) : 0;
+
+	if (n < 0)
+		return 0;
+	return n > max ? max : n;
+}
+
+static void fault_init(void)
+{
+	const char *seed = getenv("ESP_HOSTED_FAULT_SEED");
+
+	if (fault_cfg.initialised)
+		return;
+
+	fault_cfg.seed = seed ? strtoul(seed, NULL, 0) : (unsigned int)time(NULL);
+	fault_cfg.delay_ms = fault_env_int("ESP_HOSTED_FAULT_DELAY_MS", 60000);
+	fault_cfg.drop_pct = fault_env_int("ESP_HOSTED_FAULT_DROP_PCT", 100);
+	fault_cfg.corrupt_pct = fault_env_int("ESP_HOSTED_FAULT_CORRUPT_PCT", 100);
+	fault_cfg.reset_pct = fault_env_int("ESP_HOSTED_FAULT_RESET_PCT", 100);
+	fault_cfg.initialised = true;
+
+	printf("Fault injection: seed[%u] delay[%d ms] drop[%d%%] corrupt[%d%%] reset[%d%%]\n",
+			fault_cfg.seed, fault_cfg.delay_ms, fault_cfg.drop_pct, fault_cfg.corrupt_pct,
+			fault_cfg.reset_pct);
+}
+
+/* Next number in [0, max) from seeded sequence. Shared by rx thread and
+ * callers of write, so sequence repeats as long as call order does */
+static int fault_rand(int max)
+{
+	int r = 0;
+
+	pthread_mutex_lock(&fault_lock);
+	r = rand_r(&fault_cfg.seed) % max;
+	pthread_mutex_unlock(&fault_lock);
+	return r;
+}
+
+static bool fault_hit(int pct)
+{
+	return pct && fault_rand(100) < pct;
+}
+
+static void fault_delay(void)
+{
+	if (fault_cfg.delay_ms)
+		usleep(fault_rand(fault_cfg.delay_ms + 1) * 1000);
+}
+
+/* ESP init event, as ESP sends after boot. Message it replaces is lost,
+ * like response to request pending across a real reset */
+static uint8_t * fault_esp_init_msg(uint32_t *out_nbyte)
+{
+	CtrlMsg msg = CTRL_MSG__INIT;
+	CtrlMsgEventESPInit init = CTRL_MSG__EVENT__ESPINIT__INIT;
+	uint8_t *buf = NULL;
+
+	msg.msg_type = CTRL_MSG_TYPE__Event;
+	msg.msg_id = CTRL_MSG_ID__Event_ESPInit;
+	msg.payload_case = CTRL_MSG__PAYLOAD_EVENT_ESP_INIT;
+	msg.event_esp_init = &init;
+
+	*out_nbyte = ctrl_msg__get_packed_size(&msg);
+	buf = (uint8_t *)hosted_calloc(1, *out_nbyte);
+	if (!buf) {
+		*out_nbyte = 0;
+		return NULL;
+	}
+	ctrl_msg__pack(&msg, buf);
+	return buf;
+}
+#endif
+
+/* Transport override, to drive a simulator instead of ESP, e.g. in CI
+ * without driver, ptys or serial devices. ESP_HOSTED_SERIAL_IF is one of:
+ *   <path>              Opened read-write instead of SERIAL_IF_FILE, e.g. pty
+ *   <rx_path>,<tx_path> Named pipes, from ESP and to ESP. Opened in this
+ *                       order, so simulator must open them in same order
+ *   fd:<rx_fd>,<tx_fd>  Descriptors inherited from parent process.
+ *                       stdin/stdout can't be used, as apps print on stdout
+ * Messages keep same TLV framing as on SERIAL_IF_FILE
+ */
+static int serial_drv_open_override(const char *spec,
+		struct serial_drv_handle_t *serial_drv_handle)
+{
+	char rx[256] = {0};
+	const char *tx = strchr(spec, ',');
+	int rx_fd = -1, tx_fd = -1;
+
+	if (!tx) {
+		serial_drv_handle->file_desc = open(spec, O_RDWR);
+		serial_drv_handle->write_desc = serial_drv_handle->file_desc;
+		return serial_drv_handle->file_desc == -1 ? FAILURE : SUCCESS;
+	}
+
+	if (tx - spec >= sizeof(rx)) {
+		printf("ESP_HOSTED_SERIAL_IF too long\n");
+		return FAILURE;
+	}
+	memcpy(rx, spec, tx - spec);
+	tx++;
+
+	if (!strncmp(rx, "fd:", 3)) {
+		/* Duplicated, so close() leaves parent's descriptors alone */
+		rx_fd = dup(atoi(rx + 3));
+		if (rx_fd >= 0)
+			tx_fd = dup(atoi(tx));
+	} else {
+		rx_fd = open(rx, O_RDONLY);
+		if (rx_fd >= 0)
+			tx_fd = open(tx, O_WRONLY);
+	}
+	if (rx_fd < 0 || tx_fd < 0) {
+		perror("ESP_HOSTED_SERIAL_IF:");
+		if (rx_fd >= 0)
+			close(rx_fd);
+		return FAILURE;
+	}
+
+	serial_drv_handle->file_desc = rx_fd;
+	serial_drv_handle->write_desc = tx_fd;
+	return SUCCESS;
+}
+
 struct serial_drv_handle_t* serial_drv_open(const char *transport)
 {
+	const char *override = getenv("ESP_HOSTED_SERIAL_IF");
+
 	if (!transport) {
 		return NULL;
 	}
@@ -507,7 +660,19 @@ struct serial_drv_handle_t* serial_drv_open(const char *transport)
 		return NULL;
 	}
 
+	if (override && *override) {
+		if (serial_drv_open_override(override, serial_drv_handle)) {
+			mem_free(serial_drv_handle);
+			return NULL;
+		}
+#ifdef ESP_HOSTED_FAULT_INJECTION
+		fault_init();
+#endif
+		return serial_drv_handle;
+	}
+
 	serial_drv_handle->file_desc = open(transport, O_RDWR);
+	serial_drv_handle->write_desc = serial_drv_handle->file_desc;
 	if (serial_drv_handle->file_desc == -1) {
 		int errsv = errno;
 		//printf("%s failed: ", __func__);
@@ -527,6 +692,9 @@ struct serial_drv_handle_t* serial_drv_open(const char *transport)
 		return NULL;
 	}
 
+#ifdef ESP_HOSTED_FAULT_INJECTION
+	fault_init();
+#endif
 	return serial_drv_handle;
 }
 
@@ -540,7 +708,16 @@ int serial_drv_write (struct serial_drv_handle_t *serial_drv_handle,
 		return FAILURE;
 	}
 
-	*out_count = write(serial_drv_handle->file_desc, buf, in_count);
+#ifdef ESP_HOSTED_FAULT_INJECTION
+	fault_delay();
+	if (fault_hit(fault_cfg.drop_pct)) {
+		printf("Fault injection: dropped %d bytes to ESP\n", in_count);
+		*out_count = in_count;
+		return SUCCESS;
+	}
+#endif
+
+	*out_count = write(serial_drv_handle->write_desc, buf, in_count);
 	if (*out_count <= 0) {
 		perror("write: ");
 		return FAILURE;
@@ -556,6 +733,9 @@ int serial_drv_close(struct serial_drv_handle_t **serial_drv_handle)
 	    (*serial_drv_handle)->file_desc < 0) {
 		return FAILURE;
 	}
+	if ((*serial_drv_handle)->write_desc != (*serial_drv_handle)->file_desc) {
+		close((*serial_drv_handle)->write_desc);
+	}
 	if(close((*serial_drv_handle)->file_desc) < 0) {
 		perror("close:");
 		mem_free(*serial_drv_handle);
@@ -576,6 +756,37 @@ int serial_drv_close(struct serial_drv_handle_t **serial_drv_handle)
  * serial buffer on transport.
  * To keep it simple, two step parsing TLV buffer is kept in platform specific code
  */
+/* Largest value accepted from ESP, `ESP_HOSTED_SERIAL_MAX_FRAME` or
+ * SERIAL_MAX_FRAME. Bigger one is read off and dropped */
+static uint32_t serial_max_frame(void)
+{
+	static uint32_t max_frame;
+	const char *val = NULL;
+
+	if (!max_frame) {
+		val = getenv("ESP_HOSTED_SERIAL_MAX_FRAME");
+		max_frame = val ? strtoul(val, NULL, 0) : 0;
+		if (!max_frame)
+			max_frame = SERIAL_MAX_FRAME;
+	}
+	return max_frame;
+}
+
+static void serial_drv_discard(struct serial_drv_handle_t *serial_drv_handle,
+		uint32_t nbyte)
+{
+	uint8_t buf[DUMMY_READ_BUF_LEN];
+	int count = 0;
+
+	while (nbyte) {
+		count = read(serial_drv_handle->file_desc, buf,
+				nbyte < DUMMY_READ_BUF_LEN ? nbyte : DUMMY_READ_BUF_LEN);
+		if (count <= 0)
+			break;
+		nbyte -= count;
+	}
+}
+
 uint8_t * serial_drv_read(struct serial_drv_handle_t *serial_drv_handle,
 		uint32_t *out_nbyte)
 {
@@ -614,6 +825,12 @@ uint8_t * serial_drv_read(struct serial_drv_handle_t *serial_drv_handle,
 	do {
 		count = read(serial_drv_handle->file_desc,
 				(init_read_buf+total_read_len), (init_read_len-total_read_len));
+		if (count < 0 && errno == EMSGSIZE) {
+			/* Driver dropped frame, over its serial_max_frame or serial_rb_size */
+			printf("%s: frame from ESP dropped by driver, too large\n", __func__);
+			errno = EMSGSIZE;
+			goto free_bufs;
+		}
 		if (count <= 0) {
 			perror("read fail:");
 			printf("Exp read of %u bytes: ret[%d]\n",
@@ -634,6 +851,14 @@ uint8_t * serial_drv_read(struct serial_drv_handle_t *serial_drv_handle,
 		goto free_bufs;
 	}
 
+	if (buf_len > serial_max_frame()) {
+		printf("%s: frame of %u bytes from ESP over max %u, dropped. Raise ESP_HOSTED_SERIAL_MAX_FRAME if expected\n",
+				__func__, buf_len, serial_max_frame());
+		serial_drv_discard(serial_drv_handle, buf_len);
+		errno = EMSGSIZE;
+		goto free_bufs;
+	}
+
 	/*
 	 * Read variable length of received data.
 	 * Variable length is obtained after
@@ -658,6 +883,29 @@ uint8_t * serial_drv_read(struct serial_drv_handle_t *serial_drv_handle,
 		goto free_bufs;
 	}
 
+#ifdef ESP_HOSTED_FAULT_INJECTION
+	fault_delay();
+	if (fault_hit(fault_cfg.reset_pct)) {
+		printf("Fault injection: ESP reset, %u bytes from ESP replaced by init event\n", buf_len);
+		mem_free(buf);
+		buf = fault_esp_init_msg(&buf_len);
+		if (!buf)
+			goto free_bufs;
+		*out_nbyte = buf_len;
+		return buf;
+	}
+	if (fault_hit(fault_cfg.drop_pct)) {
+		printf("Fault injection: dropped %u bytes from ESP\n", buf_len);
+		goto free_bufs;
+	}
+	if (fault_hit(fault_cfg.corrupt_pct)) {
+		uint32_t idx = fault_rand(buf_len);
+
+		buf[idx] ^= 1 << fault_rand(8);
+		printf("Fault injection: corrupted byte %u of %u from ESP\n", idx, buf_len);
+	}
+#endif
+
 	*out_nbyte = buf_len;
 	return buf;
 
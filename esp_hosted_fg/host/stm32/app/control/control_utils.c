@@ -146,6 +146,12 @@ static void process_failed_responses(ctrl_cmd_t *app_msg)
 		case CTRL_ERR_REQUEST_TIMEOUT:
 			printf("Error reported: Response Timeout\n\r");
 			break;
+		case CTRL_ERR_RATE_LIMITED:
+			printf("Error reported: Request rate limit exceeded\n\r");
+			break;
+		case CTRL_ERR_FRAME_TOO_LARGE:
+			printf("Error reported: Response from ESP too large, dropped\n\r");
+			break;
 		case CTRL_ERR_MEMORY_FAILURE:
 			printf("Error reported: Memory allocation failed\n\r");
 			break;